@@ -0,0 +1,573 @@
+// gen 根据OpenAPI文档生成Go客户端的类型和接口方法
+//
+// 用法：go run ./api/gen -spec api/openapi.yaml -out client/client_gen.go -package client
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// methods 生成方法时的HTTP方法顺序
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+// initialisms 字段名中需要全部大写的缩写
+var initialisms = map[string]bool{
+	"id": true, "url": true, "api": true, "html": true, "http": true, "json": true, "uri": true, "ip": true,
+}
+
+type schema struct {
+	Ref                  string    `yaml:"$ref"`
+	Type                 string    `yaml:"type"`
+	Format               string    `yaml:"format"`
+	Nullable             bool      `yaml:"nullable"`
+	Enum                 []string  `yaml:"enum"`
+	Description          string    `yaml:"description"`
+	Properties           namedList `yaml:"properties"`
+	Items                *schema   `yaml:"items"`
+	AdditionalProperties *schema   `yaml:"additionalProperties"`
+	AllOf                []*schema `yaml:"allOf"`
+}
+
+// named 保持文档中顺序的名字和schema
+type named struct {
+	Name   string
+	Schema *schema
+}
+
+type namedList []named
+
+// UnmarshalYAML 按文档中的顺序解析映射，生成的代码顺序才会稳定
+func (l *namedList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("第%d行: 应为映射", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		s := new(schema)
+		if err := node.Content[i+1].Decode(s); err != nil {
+			return err
+		}
+		*l = append(*l, named{Name: node.Content[i].Value, Schema: s})
+	}
+	return nil
+}
+
+type parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Required    bool    `yaml:"required"`
+	Description string  `yaml:"description"`
+	Schema      *schema `yaml:"schema"`
+}
+
+type mediaType struct {
+	Schema *schema `yaml:"schema"`
+}
+
+type body struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]mediaType `yaml:"content"`
+}
+
+type operation struct {
+	OperationID string           `yaml:"operationId"`
+	Summary     string           `yaml:"summary"`
+	Role        string           `yaml:"x-role"`
+	Parameters  []*parameter     `yaml:"parameters"`
+	RequestBody *body            `yaml:"requestBody"`
+	Responses   map[string]*body `yaml:"responses"`
+}
+
+type pathItem map[string]*operation
+
+type pathList []struct {
+	Path string
+	Item pathItem
+}
+
+// UnmarshalYAML 按文档中的顺序解析路径
+func (l *pathList) UnmarshalYAML(node *yaml.Node) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var item pathItem
+		if err := node.Content[i+1].Decode(&item); err != nil {
+			return err
+		}
+		*l = append(*l, struct {
+			Path string
+			Item pathItem
+		}{node.Content[i].Value, item})
+	}
+	return nil
+}
+
+type document struct {
+	Paths      pathList `yaml:"paths"`
+	Components struct {
+		Schemas    namedList             `yaml:"schemas"`
+		Parameters map[string]*parameter `yaml:"parameters"`
+		Responses  map[string]*body      `yaml:"responses"`
+	} `yaml:"components"`
+}
+
+type generator struct {
+	doc     *document
+	schemas map[string]*schema
+	skip    map[string]bool
+	types   bytes.Buffer // 类型定义
+	funcs   bytes.Buffer // 接口方法
+	emitted map[string]bool
+}
+
+func main() {
+	specFile := flag.String("spec", "api/openapi.yaml", "OpenAPI文档")
+	outFile := flag.String("out", "client/client_gen.go", "输出文件")
+	pkg := flag.String("package", "client", "包名")
+	skip := flag.String("skip", "", "不生成的schema，逗号分隔，用于手写的类型")
+	flag.Parse()
+
+	data, err := os.ReadFile(*specFile)
+	if err != nil {
+		log.Fatalf("读取文档失败: %v", err)
+	}
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		log.Fatalf("解析文档失败: %v", err)
+	}
+
+	g := &generator{
+		doc:     &doc,
+		schemas: make(map[string]*schema),
+		skip:    make(map[string]bool),
+		emitted: make(map[string]bool),
+	}
+	for _, name := range strings.Split(*skip, ",") {
+		if name != "" {
+			g.skip[name] = true
+		}
+	}
+	for _, s := range doc.Components.Schemas {
+		g.schemas[s.Name] = s.Schema
+	}
+
+	src, err := g.generate(*pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*outFile, src, 0644); err != nil {
+		log.Fatalf("写入文件失败: %v", err)
+	}
+}
+
+// generate 生成完整的源文件并格式化
+func (g *generator) generate(pkg string) ([]byte, error) {
+	for _, s := range g.doc.Components.Schemas {
+		if !g.skip[s.Name] {
+			g.namedType(s.Name, s.Schema)
+		}
+	}
+	for _, p := range g.doc.Paths {
+		for _, method := range methods {
+			if op, ok := p.Item[method]; ok {
+				if err := g.operation(p.Path, method, op); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	code := g.types.String() + g.funcs.String()
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by api/gen from api/openapi.yaml. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	for _, imp := range []struct{ path, use string }{
+		{"context", "context."},
+		{"encoding/json", "json."},
+		{"fmt", "fmt."},
+		{"io", "io."},
+		{"net/http", "http."},
+		{"net/url", "url."},
+		{"strconv", "strconv."},
+		{"time", "time."},
+	} {
+		if strings.Contains(code, imp.use) {
+			fmt.Fprintf(&out, "\t%q\n", imp.path)
+		}
+	}
+	out.WriteString(")\n")
+	out.WriteString(code)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("格式化生成的代码失败: %w", err)
+	}
+	return src, nil
+}
+
+// namedType 生成components中的一个类型
+func (g *generator) namedType(name string, s *schema) {
+	if g.emitted[name] {
+		return
+	}
+	g.emitted[name] = true
+
+	// 先生成字段，字段中内联的类型会写在当前类型之前
+	var decl string
+	switch {
+	case s.Type == "string" && len(s.Enum) > 0:
+		var b strings.Builder
+		fmt.Fprintf(&b, "type %s string\n\nconst (\n", name)
+		for _, value := range s.Enum {
+			fmt.Fprintf(&b, "\t%s%s %s = %q\n", name, goName(value), name, value)
+		}
+		b.WriteString(")\n\n")
+		decl = b.String()
+	case isObject(s):
+		decl = fmt.Sprintf("type %s struct {\n%s}\n\n", name, g.fields(name, s))
+	default:
+		decl = fmt.Sprintf("type %s %s\n\n", name, g.goType(name, s))
+	}
+	writeComment(&g.types, name, s.Description)
+	g.types.WriteString(decl)
+}
+
+// fields 生成结构体字段，allOf中引用的类型作为嵌入字段
+func (g *generator) fields(name string, s *schema) string {
+	var b strings.Builder
+	for _, part := range s.AllOf {
+		if part.Ref != "" {
+			fmt.Fprintf(&b, "\t%s\n", refName(part.Ref))
+			continue
+		}
+		b.WriteString(g.fields(name, part))
+	}
+	for _, prop := range s.Properties {
+		field := goName(prop.Name)
+		typ := g.goType(name+field, prop.Schema)
+		tag := prop.Name
+		if omittable(typ) {
+			tag += ",omitempty"
+		}
+		if desc := firstLine(prop.Schema.Description); desc != "" {
+			fmt.Fprintf(&b, "\t%s %s `json:%q` // %s\n", field, typ, tag, desc)
+		} else {
+			fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, typ, tag)
+		}
+	}
+	return b.String()
+}
+
+// goType 返回schema对应的Go类型，内联的对象生成名为hint的类型
+func (g *generator) goType(hint string, s *schema) string {
+	if s.Ref != "" {
+		name := refName(s.Ref)
+		if target := g.schemas[name]; target != nil && isObject(target) {
+			return "*" + name
+		}
+		return name
+	}
+
+	var typ string
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			typ = "time.Time"
+		case "binary":
+			return "[]byte"
+		default:
+			typ = "string"
+		}
+	case "integer":
+		typ = "int64"
+	case "number":
+		typ = "float64"
+	case "boolean":
+		typ = "bool"
+	case "array":
+		return "[]" + strings.TrimPrefix(g.goType(hint+"Item", s.Items), "*")
+	case "", "object":
+		switch {
+		case s.AdditionalProperties != nil:
+			return "map[string]" + strings.TrimPrefix(g.goType(hint+"Value", s.AdditionalProperties), "*")
+		case isObject(s):
+			g.inlineType(hint, s)
+			return "*" + hint
+		case s.Type == "object":
+			return "map[string]interface{}"
+		default:
+			return "json.RawMessage"
+		}
+	default:
+		typ = "interface{}"
+	}
+
+	if s.Nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+// inlineType 把内联的对象生成为独立的类型
+func (g *generator) inlineType(name string, s *schema) {
+	if g.emitted[name] {
+		return
+	}
+	g.emitted[name] = true
+
+	fields := g.fields(name, s)
+	writeComment(&g.types, name, s.Description)
+	fmt.Fprintf(&g.types, "type %s struct {\n%s}\n\n", name, fields)
+}
+
+// operation 生成一个接口方法，查询参数生成为 <operationId>Params
+func (g *generator) operation(path, method string, op *operation) error {
+	if op.OperationID == "" {
+		return fmt.Errorf("%s %s 缺少operationId", strings.ToUpper(method), path)
+	}
+	name := op.OperationID
+	specPath := path
+
+	var args []string
+	var pathArgs []string
+	var query []*parameter
+	for _, p := range op.Parameters {
+		p = g.parameter(p)
+		switch p.In {
+		case "path":
+			arg := lowerName(p.Name)
+			if p.Schema.Type == "integer" {
+				args = append(args, arg+" int64")
+				pathArgs = append(pathArgs, arg)
+				path = strings.Replace(path, "{"+p.Name+"}", "%d", 1)
+			} else {
+				args = append(args, arg+" string")
+				pathArgs = append(pathArgs, "url.PathEscape("+arg+")")
+				path = strings.Replace(path, "{"+p.Name+"}", "%s", 1)
+			}
+		case "query":
+			query = append(query, p)
+		}
+	}
+
+	if len(query) > 0 {
+		g.params(name+"Params", query)
+		args = append(args, "params *"+name+"Params")
+	}
+
+	// 请求体：只有JSON时生成对应的类型，否则由调用方提供内容和Content-Type
+	rawBody := false
+	if op.RequestBody != nil {
+		if s, ok := jsonOnly(op.RequestBody.Content); ok {
+			args = append(args, "body "+g.goType(name+"Request", s))
+		} else {
+			rawBody = true
+			args = append(args, "body io.Reader", "contentType string")
+		}
+	}
+
+	// 返回值：成功响应只有JSON时解析为对应的类型，否则返回原始响应
+	var result string
+	for _, code := range sortedCodes(op.Responses) {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		resp := g.response(op.Responses[code])
+		if s, ok := jsonOnly(resp.Content); ok && s.Ref != "" || ok && isObject(s) {
+			result = g.goType(name+"Response", s)
+		}
+		break
+	}
+
+	f := &g.funcs
+	writeComment(f, name, op.Summary)
+	fmt.Fprintf(f, "//\n// %s %s", strings.ToUpper(method), specPath)
+	if op.Role != "" {
+		fmt.Fprintf(f, "，需要 %s 角色", op.Role)
+	}
+	f.WriteString("。\n")
+	if result == "" {
+		f.WriteString("// 返回原始响应，调用方需要关闭响应体。\n")
+	}
+
+	urlExpr := fmt.Sprintf("%q", path)
+	if len(pathArgs) > 0 {
+		urlExpr = fmt.Sprintf("fmt.Sprintf(%q, %s)", path, strings.Join(pathArgs, ", "))
+	}
+	queryExpr := "nil"
+	if len(query) > 0 {
+		queryExpr = "params.values()"
+	}
+	httpMethod := "http.Method" + strings.ToUpper(method[:1]) + method[1:]
+	ctxArgs := strings.Join(append([]string{"ctx context.Context"}, args...), ", ")
+
+	switch {
+	case result == "":
+		bodyArgs := "nil, \"\""
+		if rawBody {
+			bodyArgs = "body, contentType"
+		}
+		fmt.Fprintf(f, "func (c *Client) %s(%s) (*http.Response, error) {\n", name, ctxArgs)
+		fmt.Fprintf(f, "\treturn c.doRaw(ctx, %s, %s, %s, %s)\n}\n\n", httpMethod, urlExpr, queryExpr, bodyArgs)
+	case rawBody:
+		fmt.Fprintf(f, "func (c *Client) %s(%s) (%s, error) {\n", name, ctxArgs, result)
+		fmt.Fprintf(f, "\tresp, err := c.doRaw(ctx, %s, %s, %s, body, contentType)\n", httpMethod, urlExpr, queryExpr)
+		fmt.Fprintf(f, "\tif err != nil {\n\t\treturn nil, err\n\t}\n\tdefer resp.Body.Close()\n\n")
+		fmt.Fprintf(f, "\tout := new(%s)\n", strings.TrimPrefix(result, "*"))
+		fmt.Fprintf(f, "\tif err := decodeJSON(resp, out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n\n")
+	default:
+		bodyArg := "nil"
+		if op.RequestBody != nil {
+			bodyArg = "body"
+		}
+		fmt.Fprintf(f, "func (c *Client) %s(%s) (%s, error) {\n", name, ctxArgs, result)
+		fmt.Fprintf(f, "\tout := new(%s)\n", strings.TrimPrefix(result, "*"))
+		fmt.Fprintf(f, "\tif err := c.do(ctx, %s, %s, %s, %s, out); err != nil {\n", httpMethod, urlExpr, queryExpr, bodyArg)
+		fmt.Fprintf(f, "\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n\n")
+	}
+	return nil
+}
+
+// params 生成查询参数结构体及其编码方法，未设置的参数不会发送
+func (g *generator) params(name string, query []*parameter) {
+	var fields, encode strings.Builder
+	for _, p := range query {
+		field := goName(p.Name)
+		typ := strings.TrimPrefix(g.goType(name+field, p.Schema), "*")
+		if desc := firstLine(p.Description); desc != "" {
+			fmt.Fprintf(&fields, "\t%s *%s // %s\n", field, typ, desc)
+		} else {
+			fmt.Fprintf(&fields, "\t%s *%s\n", field, typ)
+		}
+
+		var value string
+		switch typ {
+		case "string":
+			value = "*p." + field
+		case "int64":
+			value = "strconv.FormatInt(*p." + field + ", 10)"
+		case "bool":
+			value = "strconv.FormatBool(*p." + field + ")"
+		case "time.Time":
+			value = "p." + field + ".Format(time.RFC3339)"
+		default:
+			value = "string(*p." + field + ")"
+		}
+		fmt.Fprintf(&encode, "\tif p.%s != nil {\n\t\tq.Set(%q, %s)\n\t}\n", field, p.Name, value)
+	}
+
+	fmt.Fprintf(&g.types, "// %s %s的查询参数，为nil的字段不会发送\ntype %s struct {\n%s}\n\n", name, strings.TrimSuffix(name, "Params"), name, fields.String())
+	fmt.Fprintf(&g.types, "func (p *%s) values() url.Values {\n\tq := url.Values{}\n\tif p == nil {\n\t\treturn q\n\t}\n%s\treturn q\n}\n\n", name, encode.String())
+}
+
+// parameter 解析对components中参数的引用
+func (g *generator) parameter(p *parameter) *parameter {
+	if p.Ref == "" {
+		return p
+	}
+	if target, ok := g.doc.Components.Parameters[refName(p.Ref)]; ok {
+		return target
+	}
+	log.Fatalf("未定义的参数: %s", p.Ref)
+	return nil
+}
+
+// response 解析对components中响应的引用
+func (g *generator) response(b *body) *body {
+	if b.Ref == "" {
+		return b
+	}
+	if target, ok := g.doc.Components.Responses[refName(b.Ref)]; ok {
+		return target
+	}
+	log.Fatalf("未定义的响应: %s", b.Ref)
+	return nil
+}
+
+// jsonOnly 内容只有JSON一种格式时返回其schema
+func jsonOnly(content map[string]mediaType) (*schema, bool) {
+	if len(content) != 1 {
+		return nil, false
+	}
+	media, ok := content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil, false
+	}
+	return media.Schema, true
+}
+
+func isObject(s *schema) bool {
+	return len(s.Properties) > 0 || len(s.AllOf) > 0
+}
+
+func omittable(typ string) bool {
+	return strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "json.RawMessage"
+}
+
+func sortedCodes(responses map[string]*body) []string {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// goName 把 gmail_message_id、log.created 这样的名字转换为 GmailMessageID、LogCreated
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	})
+	var b strings.Builder
+	for _, part := range parts {
+		lower := strings.ToLower(part)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// lowerName 用作方法参数名，例如 mailbox_id 转换为 mailboxID
+func lowerName(name string) string {
+	n := goName(name)
+	if strings.ToUpper(n) == n {
+		return strings.ToLower(n)
+	}
+	for i, r := range n {
+		if r < 'A' || r > 'Z' {
+			if i > 1 {
+				i--
+			}
+			return strings.ToLower(n[:i]) + n[i:]
+		}
+	}
+	return n
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+func writeComment(b *bytes.Buffer, name, text string) {
+	if text = firstLine(text); text != "" {
+		fmt.Fprintf(b, "// %s %s\n", name, text)
+	}
+}
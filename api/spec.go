@@ -0,0 +1,114 @@
+// Package api 内嵌接口的OpenAPI文档，并校验文档与实际注册的路由是否一致
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+	specErr  error
+)
+
+// operation 文档中一个接口的路由信息
+type operation struct {
+	OperationID string `yaml:"operationId"`
+}
+
+// document 校验路由时只需要的文档内容
+type document struct {
+	Paths map[string]map[string]operation `yaml:"paths"`
+}
+
+// Spec 返回JSON格式的OpenAPI文档，只在第一次调用时转换
+func Spec() ([]byte, error) {
+	specOnce.Do(func() {
+		var doc interface{}
+		if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+			specErr = fmt.Errorf("解析OpenAPI文档失败: %w", err)
+			return
+		}
+		specJSON, specErr = json.Marshal(doc)
+		if specErr != nil {
+			specErr = fmt.Errorf("转换OpenAPI文档失败: %w", specErr)
+		}
+	})
+	return specJSON, specErr
+}
+
+// CheckRoutes 校验文档与已注册的路由是否一致：/api/v1 下的每个路由都要有文档，
+// 文档中的每个接口都要已注册，且operationId与处理函数的方法名相同
+func CheckRoutes(routes gin.RoutesInfo) error {
+	var doc document
+	if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+		return fmt.Errorf("解析OpenAPI文档失败: %w", err)
+	}
+
+	documented := make(map[string]string)
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			documented[strings.ToUpper(method)+" "+path] = op.OperationID
+		}
+	}
+
+	var problems []string
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		key := route.Method + " " + specPath(route.Path)
+		operationID, ok := documented[key]
+		if !ok {
+			if strings.HasPrefix(route.Path, "/api/v1/") {
+				problems = append(problems, "缺少文档: "+key)
+			}
+			continue
+		}
+		registered[key] = true
+		if handler := handlerName(route.Handler); handler != operationID {
+			problems = append(problems, fmt.Sprintf("operationId与处理函数不一致: %s 文档为 %s，处理函数为 %s", key, operationID, handler))
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			problems = append(problems, "路由未注册: "+key)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("OpenAPI文档与路由不一致:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// specPath 把gin的路径参数 :id 转换为OpenAPI的 {id}
+func specPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// handlerName 从gin记录的处理函数全名中取出方法名，
+// 例如 email-forwarding/handlers.(*EmailHandler).GetEmailLogs-fm 取出 GetEmailLogs
+func handlerName(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
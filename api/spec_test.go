@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSpecIsJSON(t *testing.T) {
+	spec, err := Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string                 `json:"openapi"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI == "" || len(doc.Paths) == 0 {
+		t.Fatalf("文档缺少 openapi 或 paths: %q, %d", doc.OpenAPI, len(doc.Paths))
+	}
+}
+
+type fakeHandler struct{}
+
+func (fakeHandler) Liveness(*gin.Context)     {}
+func (fakeHandler) Undocumented(*gin.Context) {}
+func (fakeHandler) WrongName(*gin.Context)    {}
+
+func TestCheckRoutesReportsMismatches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var h fakeHandler
+	router := gin.New()
+	router.GET("/healthz", h.Liveness)
+	router.GET("/readyz", h.WrongName)
+	router.GET("/api/v1/undocumented/:id", h.Undocumented)
+
+	err := CheckRoutes(router.Routes())
+	if err == nil {
+		t.Fatal("路由与文档不一致时应返回错误")
+	}
+	for _, want := range []string{
+		"缺少文档: GET /api/v1/undocumented/{id}",
+		"operationId与处理函数不一致: GET /readyz 文档为 Readiness，处理函数为 WrongName",
+		"路由未注册: GET /api/v1/stats",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("错误中缺少 %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "/healthz") {
+		t.Errorf("与文档一致的路由不应报告:\n%v", err)
+	}
+}
+
+func TestSpecPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/api/v1/targets/:id":               "/api/v1/targets/{id}",
+		"/api/v1/emails/messages/:a/att/:b": "/api/v1/emails/messages/{a}/att/{b}",
+		"/static/*filepath":                 "/static/{filepath}",
+		"/api/v1/stats":                     "/api/v1/stats",
+	} {
+		if got := specPath(path); got != want {
+			t.Errorf("specPath(%q) = %q，应为 %q", path, got, want)
+		}
+	}
+}
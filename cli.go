@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"email-forwarding/config"
+	"email-forwarding/models"
+	"email-forwarding/services"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runCLI 执行命令行子命令，例如：
+//
+//	go run . apikey create -name ops -role admin -expires 720h
+//	go run . apikey create -name acme-ops -role admin -tenant 2
+//	go run . apikey create -name root -role superadmin
+//	go run . apikey list
+//	go run . apikey revoke -id 3
+//
+// 另有不依赖数据库的 config check 和 auth google 子命令，见 runConfigCommand、runAuthCommand
+func runCLI(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("缺少子命令")
+	}
+
+	switch args[0] {
+	case "apikey":
+		return runAPIKeyCommand(args[1:])
+	default:
+		return fmt.Errorf("未知的子命令: %s", args[0])
+	}
+}
+
+// runAPIKeyCommand 管理API密钥
+func runAPIKeyCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: apikey create|list|revoke")
+	}
+
+	authService := services.NewAuthService("")
+	// 命令行可以管理所有租户的密钥
+	ctx := services.ContextWithPrincipal(context.Background(), &services.Principal{Name: "cli", AllTenants: true})
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := fs.String("name", "", "密钥名称")
+		role := fs.String("role", "viewer", "角色: viewer/operator/admin/superadmin")
+		tenant := fs.Uint("tenant", models.DefaultTenantID, "所属租户ID")
+		expires := fs.Duration("expires", 0, "有效期，例如 720h，0表示永不过期")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		ctx = services.ContextWithPrincipal(context.Background(), &services.Principal{Name: "cli", TenantID: *tenant})
+
+		var expiresAt *time.Time
+		if *expires > 0 {
+			t := time.Now().Add(*expires)
+			expiresAt = &t
+		}
+
+		rawKey, key, err := authService.CreateAPIKey(ctx, *name, *role, expiresAt)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已创建API密钥 [%d] %s (%s，租户 %d)\n", key.ID, key.Name, key.Role, key.TenantID)
+		fmt.Printf("密钥: %s\n", rawKey)
+		fmt.Println("请妥善保存密钥，之后将无法再次查看")
+
+	case "list":
+		keys, err := authService.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			lastUsed := "-"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d\t%s\t%s...\t%s\t租户: %d\t最后使用: %s\n", key.ID, key.Name, key.Prefix, key.Role, key.TenantID, lastUsed)
+		}
+
+	case "revoke":
+		fs := flag.NewFlagSet("apikey revoke", flag.ContinueOnError)
+		id := fs.Uint("id", 0, "密钥ID")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := authService.RevokeAPIKey(ctx, *id); err != nil {
+			return err
+		}
+		fmt.Printf("已吊销API密钥 [%d]\n", *id)
+
+	default:
+		fmt.Fprintf(os.Stderr, "未知的apikey子命令: %s\n", args[0])
+		return fmt.Errorf("用法: apikey create|list|revoke")
+	}
+
+	return nil
+}
+
+// runConfigCommand 校验配置，例如：
+//
+//	go run . config check
+//	go run . config check config.prod.yaml
+//
+// 校验通过时输出合并环境变量后的最终配置（密码和密钥会被隐藏）
+func runConfigCommand(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("用法: config check [配置文件]")
+	}
+
+	var path string
+	if len(args) > 1 {
+		path = args[1]
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return fmt.Errorf("配置无效:\n%v", err)
+	}
+
+	out, err := yaml.Marshal(cfg.Masked())
+	if err != nil {
+		return err
+	}
+	fmt.Println("配置校验通过")
+	fmt.Print(string(out))
+	return nil
+}
+
+// runAuthCommand 在命令行完成Google授权，例如：
+//
+//	go run . auth google
+//	go run . auth google -port 8085
+//
+// 在本机启动临时的回调服务（loopback重定向），在浏览器中打开输出的链接完成授权即可。
+// 服务器上没有浏览器时，可以先用 ssh -L 8085:127.0.0.1:8085 转发端口，再在本地浏览器中打开链接
+func runAuthCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "google" {
+		return fmt.Errorf("用法: auth google [-port 端口] [-timeout 10m]")
+	}
+
+	fs := flag.NewFlagSet("auth google", flag.ContinueOnError)
+	port := fs.Int("port", 0, "本地回调端口，0表示随机端口")
+	timeout := fs.Duration("timeout", 10*time.Minute, "等待授权的最长时间")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if cfg.Gmail.AuthMode == services.GmailAuthServiceAccount {
+		return services.ErrOAuthNotSupported
+	}
+
+	proxy, err := cfg.Proxy.ProxyFunc()
+	if err != nil {
+		return err
+	}
+	gmailService, err := services.NewGmailService(cfg.Gmail.CredentialsFile, cfg.Gmail.TokenFile, cfg.Gmail.UserEmail, proxy)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", *port))
+	if err != nil {
+		return fmt.Errorf("无法监听回调端口: %v", err)
+	}
+	redirectURL := fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	authURL, err := gmailService.StartAuthorization(redirectURL)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	result := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		query := r.URL.Query()
+		var err error
+		if reason := query.Get("error"); reason != "" {
+			err = fmt.Errorf("授权未完成: %s", reason)
+		} else {
+			err = gmailService.CompleteAuthorization(r.Context(), query.Get("state"), query.Get("code"))
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "授权失败: %v\n", err)
+			// state不匹配的请求可能不是本次授权的回调，继续等待
+			if errors.Is(err, services.ErrInvalidOAuthState) {
+				return
+			}
+		} else {
+			fmt.Fprintln(w, "授权成功，可以关闭此页面")
+		}
+
+		select {
+		case result <- err:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Printf("在浏览器中打开以下链接进行授权:\n%s\n\n", authURL)
+	fmt.Printf("等待授权回调 %s ...\n", redirectURL)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return err
+		}
+		fmt.Printf("授权成功，token已保存到 %s\n", cfg.Gmail.TokenFile)
+		return nil
+	case <-time.After(*timeout):
+		return fmt.Errorf("等待授权超时")
+	case <-ctx.Done():
+		return fmt.Errorf("已取消")
+	}
+}
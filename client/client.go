@@ -0,0 +1,143 @@
+// Package client 邮件转发系统的Go客户端
+//
+// 接口方法和类型由 api/gen 根据 api/openapi.yaml 生成（client_gen.go），
+// 修改文档后在本目录执行 go generate 重新生成。
+package client
+
+//go:generate go run ../api/gen -spec ../api/openapi.yaml -out client_gen.go -package client -skip Error
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody 读取错误响应的最大长度
+const maxErrorBody = 64 * 1024
+
+// Client 调用 /api/v1 接口的客户端，可以在多个goroutine中共用
+type Client struct {
+	BaseURL    string       // 服务地址，例如 http://localhost:8080
+	APIKey     string       // API密钥，未开启认证时可以为空
+	TenantID   uint         // 超级管理员要操作的租户，0表示不指定
+	HTTPClient *http.Client // 为空时使用 http.DefaultClient
+}
+
+// New 创建客户端
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+	}
+}
+
+// Error 接口返回的错误
+type Error struct {
+	StatusCode int          `json:"-"`
+	Code       string       `json:"code"`       // 机器可读的错误码
+	Summary    string       `json:"error"`      // 错误摘要
+	Message    string       `json:"message"`    // 错误详情
+	Details    []FieldError `json:"details"`    // 校验失败的字段
+	RequestID  string       `json:"request_id"` // 请求ID，排查问题时对照服务端日志
+}
+
+func (e *Error) Error() string {
+	text := fmt.Sprintf("HTTP %d", e.StatusCode)
+	if e.Code != "" {
+		text += " " + e.Code
+	}
+	text += ": " + e.Summary
+	if e.Message != "" {
+		text += ": " + e.Message
+	}
+	return text
+}
+
+// String 返回字符串指针，用于设置可选参数
+func String(v string) *string { return &v }
+
+// Int64 返回整数指针，用于设置可选参数
+func Int64(v int64) *int64 { return &v }
+
+// Bool 返回布尔值指针，用于设置可选参数
+func Bool(v bool) *bool { return &v }
+
+// Time 返回时间指针，用于设置可选参数
+func Time(v time.Time) *time.Time { return &v }
+
+// do 发送JSON请求并把响应解析到out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("编码请求失败: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.doRaw(ctx, method, path, query, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeJSON(resp, out)
+}
+
+// doRaw 发送请求，非2xx响应转换为 *Error，成功时由调用方关闭响应体
+func (c *Client) doRaw(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	if c.TenantID != 0 {
+		req.Header.Set("X-Tenant-ID", strconv.FormatUint(uint64(c.TenantID), 10))
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Summary == "" {
+		apiErr.Summary = http.StatusText(resp.StatusCode)
+	}
+	return nil, apiErr
+}
+
+// decodeJSON 解析成功响应
+func decodeJSON(resp *http.Response, out interface{}) error {
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,1394 @@
+// Code generated by api/gen from api/openapi.yaml. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type FieldError struct {
+	Field string `json:"field"` // 字段名
+	Rule  string `json:"rule"`  // 未通过的校验规则，例如 required；类型不匹配时为 type
+}
+
+type Message struct {
+	Message string `json:"message"`
+}
+
+type Pagination struct {
+	Page      int64 `json:"page"`
+	PageSize  int64 `json:"page_size"`
+	Total     int64 `json:"total"`
+	TotalPage int64 `json:"total_page"`
+}
+
+type Liveness struct {
+	Status        string `json:"status"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+	Timestamp  int64                      `json:"timestamp"`
+}
+
+type ComponentHealth struct {
+	Status    string          `json:"status"`
+	Message   string          `json:"message"` // 固定的状态说明，不包含内部错误信息，完整错误见服务日志
+	LatencyMs int64           `json:"latency_ms"`
+	Details   json.RawMessage `json:"details,omitempty"`
+}
+
+type ForwardStatus string
+
+const (
+	ForwardStatusPending  ForwardStatus = "pending"
+	ForwardStatusSuccess  ForwardStatus = "success"
+	ForwardStatusFailed   ForwardStatus = "failed"
+	ForwardStatusRetrying ForwardStatus = "retrying"
+)
+
+type EmailLog struct {
+	ID             int64         `json:"id"`
+	TenantID       int64         `json:"tenant_id"`
+	MailboxID      int64         `json:"mailbox_id"` // 收到邮件的邮箱，0为默认邮箱
+	GmailMessageID string        `json:"gmail_message_id"`
+	Subject        string        `json:"subject"`
+	FromEmail      string        `json:"from_email"`
+	ToEmail        string        `json:"to_email"`
+	Content        string        `json:"content"`
+	Keyword        string        `json:"keyword"`
+	ForwardTarget  string        `json:"forward_target"` // 转发目标名字
+	ForwardEmail   string        `json:"forward_email"`
+	TargetType     string        `json:"target_type"`
+	TargetID       int64         `json:"target_id"`
+	ForwardStatus  ForwardStatus `json:"forward_status"`
+	Attempts       int64         `json:"attempts"`
+	NextRetryAt    *time.Time    `json:"next_retry_at,omitempty"`
+	ErrorMessage   string        `json:"error_message"`
+	TraceID        string        `json:"trace_id"`
+	ProcessedAt    *time.Time    `json:"processed_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+type EmailLogDetail struct {
+	EmailLog
+	Mailbox   string          `json:"mailbox"` // 收到邮件的邮箱地址
+	Forwarded *ForwardPreview `json:"forwarded,omitempty"`
+}
+
+// ForwardPreview 还原的转发内容，未匹配到目标或通用webhook目标时为空
+type ForwardPreview struct {
+	Type    string `json:"type"`
+	Format  string `json:"format"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type EmailStats struct {
+	Days           int64            `json:"days"`
+	Total          int64            `json:"total"`
+	ByStatus       map[string]int64 `json:"by_status,omitempty"`
+	ForwardedToday int64            `json:"forwarded_today"`
+	Daily          []DailyStats     `json:"daily,omitempty"`
+	TopTargets     []TargetCount    `json:"top_targets,omitempty"`
+}
+
+type DailyStats struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Total    int64  `json:"total"`
+	Success  int64  `json:"success"`
+	Failed   int64  `json:"failed"`
+	Retrying int64  `json:"retrying"`
+}
+
+type TargetCount struct {
+	Target string `json:"target"`
+	Count  int64  `json:"count"`
+}
+
+type RuleTestRequest struct {
+	Subject   string `json:"subject"`
+	MailboxID int64  `json:"mailbox_id"` // 0表示只匹配适用于所有邮箱的目标
+}
+
+type RuleTestResult struct {
+	Subject    string         `json:"subject"`
+	MailboxID  int64          `json:"mailbox_id"`
+	Keyword    string         `json:"keyword"`
+	TargetName string         `json:"target_name"`
+	Matched    bool           `json:"matched"`
+	Reason     string         `json:"reason"`
+	Target     *ForwardTarget `json:"target,omitempty"`
+}
+
+// LiveEvent 实时事件流中每条事件的data
+type LiveEvent struct {
+	ID       int64           `json:"id"`
+	Type     string          `json:"type"`
+	TenantID int64           `json:"tenant_id"`
+	Time     time.Time       `json:"time"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+type ForwardTarget struct {
+	ID             int64      `json:"id"`
+	TenantID       int64      `json:"tenant_id"`
+	Name           string     `json:"name"`
+	Type           TargetType `json:"type"`
+	Email          string     `json:"email"`
+	WebhookURL     string     `json:"webhook_url"`
+	AttachmentMode string     `json:"attachment_mode"`
+	Keywords       string     `json:"keywords"` // 逗号分隔
+	IsActive       bool       `json:"is_active"`
+	MailboxID      int64      `json:"mailbox_id"` // 0表示适用于所有邮箱
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type TargetType string
+
+const (
+	TargetTypeEmail    TargetType = "email"
+	TargetTypeDingtalk TargetType = "dingtalk"
+	TargetTypeWecom    TargetType = "wecom"
+	TargetTypeFeishu   TargetType = "feishu"
+	TargetTypeSlack    TargetType = "slack"
+	TargetTypeWebhook  TargetType = "webhook"
+)
+
+// ForwardTargetInput PATCH时只修改出现的字段
+type ForwardTargetInput struct {
+	Name           *string `json:"name,omitempty"`
+	Type           *string `json:"type,omitempty"`
+	Email          *string `json:"email,omitempty"`
+	WebhookURL     *string `json:"webhook_url,omitempty"`
+	Secret         *string `json:"secret,omitempty"` // 签名密钥，不会在接口中返回
+	AttachmentMode *string `json:"attachment_mode,omitempty"`
+	Keywords       *string `json:"keywords,omitempty"`
+	IsActive       *bool   `json:"is_active,omitempty"`
+	MailboxID      *int64  `json:"mailbox_id,omitempty"`
+}
+
+type TargetResponse struct {
+	Message string         `json:"message"`
+	Data    *ForwardTarget `json:"data,omitempty"`
+}
+
+type Mailbox struct {
+	ID                 int64     `json:"id"`
+	TenantID           int64     `json:"tenant_id"`
+	Name               string    `json:"name"`
+	Address            string    `json:"address"`
+	Provider           string    `json:"provider"`
+	AuthMode           string    `json:"auth_mode"`
+	CredentialsFile    string    `json:"credentials_file"`
+	TokenFile          string    `json:"token_file"`
+	ServiceAccountFile string    `json:"service_account_file"`
+	Query              string    `json:"query"`
+	CheckInterval      string    `json:"check_interval"` // 例如 30s、5m，不能小于30s，为空时使用全局配置
+	IsActive           bool      `json:"is_active"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type MailboxStatus struct {
+	Mailbox
+	Running    bool             `json:"running"`
+	Authorized bool             `json:"authorized"`
+	Scheduler  *SchedulerStatus `json:"scheduler,omitempty"`
+	Error      string           `json:"error"` // 启动失败的原因
+}
+
+// MailboxInput PATCH时只修改出现的字段
+type MailboxInput struct {
+	Name               *string `json:"name,omitempty"`
+	Address            *string `json:"address,omitempty"`
+	Provider           *string `json:"provider,omitempty"`
+	AuthMode           *string `json:"auth_mode,omitempty"`
+	CredentialsFile    *string `json:"credentials_file,omitempty"`     // 非超级管理员只能使用租户目录 tenants/<租户ID>/ 中的相对路径
+	TokenFile          *string `json:"token_file,omitempty"`           // 非超级管理员只能使用租户目录 tenants/<租户ID>/ 中的相对路径
+	ServiceAccountFile *string `json:"service_account_file,omitempty"` // 非超级管理员只能使用租户目录中的相对路径，且service_account方式必须设置
+	Query              *string `json:"query,omitempty"`
+	CheckInterval      *string `json:"check_interval,omitempty"` // 例如 30s、5m，不能小于30s，为空时使用全局配置
+	IsActive           *bool   `json:"is_active,omitempty"`
+}
+
+type MailboxResponse struct {
+	Message string   `json:"message"`
+	Data    *Mailbox `json:"data,omitempty"`
+}
+
+type SchedulerStatus struct {
+	Running     bool       `json:"running"`
+	Interval    string     `json:"interval"`
+	Paused      bool       `json:"paused"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error"`
+	WaitingAuth bool       `json:"waiting_auth"` // 最近一次检查因Gmail未授权而跳过，等待授权期间不视为停滞
+	Stale       bool       `json:"stale"`
+}
+
+type SchedulerInfo struct {
+	MailboxID int64  `json:"mailbox_id"`
+	Mailbox   string `json:"mailbox"`
+	SchedulerStatus
+}
+
+type SchedulerResponse struct {
+	Message string         `json:"message"`
+	Data    *SchedulerInfo `json:"data,omitempty"`
+}
+
+type ConfigDocument struct {
+	Version int64        `json:"version"`
+	Targets []TargetSpec `json:"targets,omitempty"`
+}
+
+type TargetSpec struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Email          string `json:"email"`
+	WebhookURL     string `json:"webhook_url"`
+	AttachmentMode string `json:"attachment_mode"`
+	Keywords       string `json:"keywords"`
+	IsActive       bool   `json:"is_active"`
+	MailboxID      int64  `json:"mailbox_id"`
+}
+
+type TargetChange struct {
+	ID     int64       `json:"id"`
+	Before *TargetSpec `json:"before,omitempty"`
+	After  *TargetSpec `json:"after,omitempty"`
+}
+
+type TargetRef struct {
+	ID int64 `json:"id"`
+	TargetSpec
+}
+
+type ImportResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Creates []TargetSpec   `json:"creates,omitempty"`
+	Updates []TargetChange `json:"updates,omitempty"`
+	Deletes []TargetRef    `json:"deletes,omitempty"`
+}
+
+type WebhookSubscription struct {
+	ID        int64     `json:"id"`
+	TenantID  int64     `json:"tenant_id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Events    string    `json:"events"` // 逗号分隔，为空表示所有事件
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SubscriptionInput PATCH时只修改出现的字段
+type SubscriptionInput struct {
+	Name     *string `json:"name,omitempty"`
+	URL      *string `json:"url,omitempty"`
+	Secret   *string `json:"secret,omitempty"`
+	Events   *string `json:"events,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+}
+
+type SubscriptionResponse struct {
+	Message string               `json:"message"`
+	Data    *WebhookSubscription `json:"data,omitempty"`
+}
+
+type EventDelivery struct {
+	ID             int64           `json:"id"`
+	TenantID       int64           `json:"tenant_id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	Status         string          `json:"status"`
+	RedeliveryOf   int64           `json:"redelivery_of"`
+	ResponseStatus int64           `json:"response_status"`
+	ResponseBody   string          `json:"response_body"`
+	ErrorMessage   string          `json:"error_message"`
+	DurationMs     int64           `json:"duration_ms"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type APIKey struct {
+	ID         int64      `json:"id"`
+	TenantID   int64      `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Role       Role       `json:"role"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type Role string
+
+const (
+	RoleViewer     Role = "viewer"
+	RoleOperator   Role = "operator"
+	RoleAdmin      Role = "admin"
+	RoleSuperadmin Role = "superadmin"
+)
+
+type APIKeyInput struct {
+	Name      string     `json:"name"`
+	Role      string     `json:"role"` // 默认viewer
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type AuditLog struct {
+	ID         int64           `json:"id"`
+	TenantID   int64           `json:"tenant_id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int64           `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type Tenant struct {
+	ID               int64     `json:"id"`
+	Name             string    `json:"name"`
+	MaxMailboxes     int64     `json:"max_mailboxes"`
+	MaxTargets       int64     `json:"max_targets"`
+	MaxDailyForwards int64     `json:"max_daily_forwards"`
+	IsActive         bool      `json:"is_active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TenantInput PATCH时只修改出现的字段
+type TenantInput struct {
+	Name             *string `json:"name,omitempty"`
+	MaxMailboxes     *int64  `json:"max_mailboxes,omitempty"`
+	MaxTargets       *int64  `json:"max_targets,omitempty"`
+	MaxDailyForwards *int64  `json:"max_daily_forwards,omitempty"`
+	IsActive         *bool   `json:"is_active,omitempty"`
+}
+
+type TenantResponse struct {
+	Message string  `json:"message"`
+	Data    *Tenant `json:"data,omitempty"`
+}
+
+type TenantStats struct {
+	Tenant
+	Mailboxes      int64 `json:"mailboxes"`
+	Targets        int64 `json:"targets"`
+	APIKeys        int64 `json:"api_keys"`
+	TotalEmails    int64 `json:"total_emails"`
+	SuccessEmails  int64 `json:"success_emails"`
+	FailedEmails   int64 `json:"failed_emails"`
+	ForwardedToday int64 `json:"forwarded_today"`
+}
+
+type ReloadResult struct {
+	Targets         int64    `json:"targets"`
+	Applied         []string `json:"applied,omitempty"`
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+type GoogleAuthStatus struct {
+	Mailbox    string `json:"mailbox"`
+	AuthMode   string `json:"auth_mode"`
+	Authorized bool   `json:"authorized"`
+}
+
+type GoogleAuthStart struct {
+	Mailbox     string `json:"mailbox"`
+	AuthURL     string `json:"auth_url"`
+	RedirectURL string `json:"redirect_url"`
+}
+
+// GetEmailLogsParams GetEmailLogs的查询参数，为nil的字段不会发送
+type GetEmailLogsParams struct {
+	Page      *int64         // 页码，默认1
+	PageSize  *int64         // 每页数量，默认20，最大100
+	Status    *ForwardStatus // 转发状态
+	MailboxID *int64         // 邮箱ID，0为默认邮箱
+	Search    *string        // 对主题、发件人和转发目标名字模糊搜索
+	From      *time.Time     // 只包含此时间及之后记录的日志（RFC3339）
+	To        *time.Time     // 只包含此时间之前记录的日志（RFC3339）
+}
+
+func (p *GetEmailLogsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Page != nil {
+		q.Set("page", strconv.FormatInt(*p.Page, 10))
+	}
+	if p.PageSize != nil {
+		q.Set("page_size", strconv.FormatInt(*p.PageSize, 10))
+	}
+	if p.Status != nil {
+		q.Set("status", string(*p.Status))
+	}
+	if p.MailboxID != nil {
+		q.Set("mailbox_id", strconv.FormatInt(*p.MailboxID, 10))
+	}
+	if p.Search != nil {
+		q.Set("search", *p.Search)
+	}
+	if p.From != nil {
+		q.Set("from", p.From.Format(time.RFC3339))
+	}
+	if p.To != nil {
+		q.Set("to", p.To.Format(time.RFC3339))
+	}
+	return q
+}
+
+type GetEmailLogsResponse struct {
+	Data       []EmailLog  `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// ExportEmailLogsParams ExportEmailLogs的查询参数，为nil的字段不会发送
+type ExportEmailLogsParams struct {
+	Format    *string        // 导出格式，默认csv
+	Columns   *string        // 逗号分隔的列名，按给出的顺序导出，可选值见接口说明
+	Excerpt   *int64         // 在最后一列追加去掉HTML标签后的正文摘录的字数，0（默认）为不导出正文
+	Status    *ForwardStatus // 转发状态
+	MailboxID *int64         // 邮箱ID，0为默认邮箱
+	Search    *string        // 对主题、发件人和转发目标名字模糊搜索
+	From      *time.Time     // 只包含此时间及之后记录的日志（RFC3339）
+	To        *time.Time     // 只包含此时间之前记录的日志（RFC3339）
+}
+
+func (p *ExportEmailLogsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	if p.Columns != nil {
+		q.Set("columns", *p.Columns)
+	}
+	if p.Excerpt != nil {
+		q.Set("excerpt", strconv.FormatInt(*p.Excerpt, 10))
+	}
+	if p.Status != nil {
+		q.Set("status", string(*p.Status))
+	}
+	if p.MailboxID != nil {
+		q.Set("mailbox_id", strconv.FormatInt(*p.MailboxID, 10))
+	}
+	if p.Search != nil {
+		q.Set("search", *p.Search)
+	}
+	if p.From != nil {
+		q.Set("from", p.From.Format(time.RFC3339))
+	}
+	if p.To != nil {
+		q.Set("to", p.To.Format(time.RFC3339))
+	}
+	return q
+}
+
+type GetEmailLogResponse struct {
+	Data *EmailLogDetail `json:"data,omitempty"`
+}
+
+// GetStatsParams GetStats的查询参数，为nil的字段不会发送
+type GetStatsParams struct {
+	Days *int64 // 统计天数（含今天），默认14，最多90
+}
+
+func (p *GetStatsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Days != nil {
+		q.Set("days", strconv.FormatInt(*p.Days, 10))
+	}
+	return q
+}
+
+type GetStatsResponse struct {
+	Data *EmailStats `json:"data,omitempty"`
+}
+
+// StreamEventsParams StreamEvents的查询参数，为nil的字段不会发送
+type StreamEventsParams struct {
+	Type        *string // 事件类型，逗号分隔
+	Target      *string // 转发目标名字，逗号分隔
+	Status      *string // 转发状态或运行结果，逗号分隔
+	LastEventID *int64  // 从该事件之后开始补发，也可以使用 Last-Event-ID 请求头
+}
+
+func (p *StreamEventsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Type != nil {
+		q.Set("type", *p.Type)
+	}
+	if p.Target != nil {
+		q.Set("target", *p.Target)
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.LastEventID != nil {
+		q.Set("last_event_id", strconv.FormatInt(*p.LastEventID, 10))
+	}
+	return q
+}
+
+type TestRuleResponse struct {
+	Data *RuleTestResult `json:"data,omitempty"`
+}
+
+type GetSchedulersResponse struct {
+	Data []SchedulerInfo `json:"data,omitempty"`
+}
+
+// GetForwardTargetsParams GetForwardTargets的查询参数，为nil的字段不会发送
+type GetForwardTargetsParams struct {
+	IncludeInactive *bool   // 是否包含已停用的目标
+	Search          *string // 对名称、邮箱和关键字模糊搜索
+	MailboxID       *int64  // 邮箱ID，0为默认邮箱
+}
+
+func (p *GetForwardTargetsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.IncludeInactive != nil {
+		q.Set("include_inactive", strconv.FormatBool(*p.IncludeInactive))
+	}
+	if p.Search != nil {
+		q.Set("search", *p.Search)
+	}
+	if p.MailboxID != nil {
+		q.Set("mailbox_id", strconv.FormatInt(*p.MailboxID, 10))
+	}
+	return q
+}
+
+type GetForwardTargetsResponse struct {
+	Data []ForwardTarget `json:"data,omitempty"`
+}
+
+type GetForwardTargetResponse struct {
+	Data *ForwardTarget `json:"data,omitempty"`
+}
+
+type GetMailboxesResponse struct {
+	Data []MailboxStatus `json:"data,omitempty"`
+}
+
+type GetMailboxResponse struct {
+	Data *MailboxStatus `json:"data,omitempty"`
+}
+
+// ExportConfigParams ExportConfig的查询参数，为nil的字段不会发送
+type ExportConfigParams struct {
+	Format *string // 导出格式，默认yaml
+}
+
+func (p *ExportConfigParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	return q
+}
+
+// ImportConfigParams ImportConfig的查询参数，为nil的字段不会发送
+type ImportConfigParams struct {
+	Format *string // 请求体格式，未指定时根据Content-Type判断
+	DryRun *bool   // 只返回差异，不写入
+	Prune  *bool   // 删除配置中不存在的目标
+}
+
+func (p *ImportConfigParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	if p.DryRun != nil {
+		q.Set("dry_run", strconv.FormatBool(*p.DryRun))
+	}
+	if p.Prune != nil {
+		q.Set("prune", strconv.FormatBool(*p.Prune))
+	}
+	return q
+}
+
+type ImportConfigResponse struct {
+	Message string        `json:"message"`
+	Data    *ImportResult `json:"data,omitempty"`
+}
+
+type GetSubscriptionsResponse struct {
+	Data   []WebhookSubscription `json:"data,omitempty"`
+	Events []string              `json:"events,omitempty"`
+}
+
+type GetSubscriptionResponse struct {
+	Data *WebhookSubscription `json:"data,omitempty"`
+}
+
+// GetDeliveriesParams GetDeliveries的查询参数，为nil的字段不会发送
+type GetDeliveriesParams struct {
+	Page     *int64  // 页码，默认1
+	PageSize *int64  // 每页数量，默认20，最大100
+	Event    *string // 事件类型
+	Status   *string // 推送状态（pending/success/failed）
+}
+
+func (p *GetDeliveriesParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Page != nil {
+		q.Set("page", strconv.FormatInt(*p.Page, 10))
+	}
+	if p.PageSize != nil {
+		q.Set("page_size", strconv.FormatInt(*p.PageSize, 10))
+	}
+	if p.Event != nil {
+		q.Set("event", *p.Event)
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	return q
+}
+
+type GetDeliveriesResponse struct {
+	Data       []EventDelivery `json:"data,omitempty"`
+	Pagination *Pagination     `json:"pagination,omitempty"`
+}
+
+type RedeliverResponse struct {
+	Message string         `json:"message"`
+	Data    *EventDelivery `json:"data,omitempty"`
+}
+
+type GetAPIKeysResponse struct {
+	Data []APIKey `json:"data,omitempty"`
+}
+
+type CreateAPIKeyResponse struct {
+	Message string  `json:"message"`
+	Key     string  `json:"key"` // 完整的API密钥
+	Data    *APIKey `json:"data,omitempty"`
+}
+
+// GetAuditLogsParams GetAuditLogs的查询参数，为nil的字段不会发送
+type GetAuditLogsParams struct {
+	Page       *int64 // 页码，默认1
+	PageSize   *int64 // 每页数量，默认20，最大100
+	Actor      *string
+	Action     *string // create/update/delete/restore
+	EntityType *string
+	EntityID   *int64
+	From       *time.Time // 起始时间（RFC3339）
+	To         *time.Time // 结束时间（RFC3339）
+}
+
+func (p *GetAuditLogsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Page != nil {
+		q.Set("page", strconv.FormatInt(*p.Page, 10))
+	}
+	if p.PageSize != nil {
+		q.Set("page_size", strconv.FormatInt(*p.PageSize, 10))
+	}
+	if p.Actor != nil {
+		q.Set("actor", *p.Actor)
+	}
+	if p.Action != nil {
+		q.Set("action", *p.Action)
+	}
+	if p.EntityType != nil {
+		q.Set("entity_type", *p.EntityType)
+	}
+	if p.EntityID != nil {
+		q.Set("entity_id", strconv.FormatInt(*p.EntityID, 10))
+	}
+	if p.From != nil {
+		q.Set("from", p.From.Format(time.RFC3339))
+	}
+	if p.To != nil {
+		q.Set("to", p.To.Format(time.RFC3339))
+	}
+	return q
+}
+
+type GetAuditLogsResponse struct {
+	Data       []AuditLog  `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type GetTenantsResponse struct {
+	Data []Tenant `json:"data,omitempty"`
+}
+
+type GetTenantStatsResponse struct {
+	Data []TenantStats `json:"data,omitempty"`
+}
+
+type GetTenantResponse struct {
+	Data *Tenant `json:"data,omitempty"`
+}
+
+type ReloadResponse struct {
+	Message string        `json:"message"`
+	Data    *ReloadResult `json:"data,omitempty"`
+}
+
+// GetGoogleAuthStatusParams GetGoogleAuthStatus的查询参数，为nil的字段不会发送
+type GetGoogleAuthStatusParams struct {
+	MailboxID *int64 // 邮箱ID，0为默认邮箱
+}
+
+func (p *GetGoogleAuthStatusParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.MailboxID != nil {
+		q.Set("mailbox_id", strconv.FormatInt(*p.MailboxID, 10))
+	}
+	return q
+}
+
+type GetGoogleAuthStatusResponse struct {
+	Data *GoogleAuthStatus `json:"data,omitempty"`
+}
+
+// StartGoogleAuthParams StartGoogleAuth的查询参数，为nil的字段不会发送
+type StartGoogleAuthParams struct {
+	MailboxID *int64 // 邮箱ID，0为默认邮箱
+}
+
+func (p *StartGoogleAuthParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.MailboxID != nil {
+		q.Set("mailbox_id", strconv.FormatInt(*p.MailboxID, 10))
+	}
+	return q
+}
+
+type StartGoogleAuthResponse struct {
+	Message string           `json:"message"`
+	Data    *GoogleAuthStart `json:"data,omitempty"`
+}
+
+// GoogleCallbackParams GoogleCallback的查询参数，为nil的字段不会发送
+type GoogleCallbackParams struct {
+	State *string
+	Code  *string
+	Error *string
+}
+
+func (p *GoogleCallbackParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.State != nil {
+		q.Set("state", *p.State)
+	}
+	if p.Code != nil {
+		q.Set("code", *p.Code)
+	}
+	if p.Error != nil {
+		q.Set("error", *p.Error)
+	}
+	return q
+}
+
+// Liveness 存活检查
+//
+// GET /healthz。
+func (c *Client) Liveness(ctx context.Context) (*Liveness, error) {
+	out := new(Liveness)
+	if err := c.do(ctx, http.MethodGet, "/healthz", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Readiness 就绪检查（/health 为别名）
+//
+// GET /readyz。
+func (c *Client) Readiness(ctx context.Context) (*HealthReport, error) {
+	out := new(HealthReport)
+	if err := c.do(ctx, http.MethodGet, "/readyz", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOpenAPISpec OpenAPI文档（JSON格式）
+//
+// GET /api/v1/openapi.json。
+// 返回原始响应，调用方需要关闭响应体。
+func (c *Client) GetOpenAPISpec(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, http.MethodGet, "/api/v1/openapi.json", nil, nil, "")
+}
+
+// ProcessEmails 立即处理默认邮箱的未读邮件
+//
+// POST /api/v1/emails/process，需要 operator 角色。
+func (c *Client) ProcessEmails(ctx context.Context) (*Message, error) {
+	out := new(Message)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/emails/process", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetEmailLogs 邮件日志列表
+//
+// GET /api/v1/emails/logs，需要 viewer 角色。
+func (c *Client) GetEmailLogs(ctx context.Context, params *GetEmailLogsParams) (*GetEmailLogsResponse, error) {
+	out := new(GetEmailLogsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/emails/logs", params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportEmailLogs 按日志列表的筛选条件导出邮件日志（CSV或Excel）
+//
+// GET /api/v1/emails/logs/export，需要 viewer 角色。
+// 返回原始响应，调用方需要关闭响应体。
+func (c *Client) ExportEmailLogs(ctx context.Context, params *ExportEmailLogsParams) (*http.Response, error) {
+	return c.doRaw(ctx, http.MethodGet, "/api/v1/emails/logs/export", params.values(), nil, "")
+}
+
+// GetEmailLog 邮件日志详情，包含转发内容预览
+//
+// GET /api/v1/emails/logs/{id}，需要 viewer 角色。
+func (c *Client) GetEmailLog(ctx context.Context, id int64) (*GetEmailLogResponse, error) {
+	out := new(GetEmailLogResponse)
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/emails/logs/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAttachment 下载已处理邮件的附件
+//
+// GET /api/v1/emails/messages/{message_id}/attachments/{part_id}，需要 viewer 角色。
+// 返回原始响应，调用方需要关闭响应体。
+func (c *Client) GetAttachment(ctx context.Context, messageID string, partID string) (*http.Response, error) {
+	return c.doRaw(ctx, http.MethodGet, fmt.Sprintf("/api/v1/emails/messages/%s/attachments/%s", url.PathEscape(messageID), url.PathEscape(partID)), nil, nil, "")
+}
+
+// GetStats 最近几天的处理统计
+//
+// GET /api/v1/stats，需要 viewer 角色。
+func (c *Client) GetStats(ctx context.Context, params *GetStatsParams) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats", params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StreamEvents 实时事件流（Server-Sent Events）
+//
+// GET /api/v1/events，需要 viewer 角色。
+// 返回原始响应，调用方需要关闭响应体。
+func (c *Client) StreamEvents(ctx context.Context, params *StreamEventsParams) (*http.Response, error) {
+	return c.doRaw(ctx, http.MethodGet, "/api/v1/events", params.values(), nil, "")
+}
+
+// TestRule 用邮件标题测试转发规则
+//
+// POST /api/v1/rules/test，需要 viewer 角色。
+func (c *Client) TestRule(ctx context.Context, body *RuleTestRequest) (*TestRuleResponse, error) {
+	out := new(TestRuleResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/rules/test", nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSchedulers 当前租户各邮箱的定时任务状态
+//
+// GET /api/v1/schedulers，需要 viewer 角色。
+func (c *Client) GetSchedulers(ctx context.Context) (*GetSchedulersResponse, error) {
+	out := new(GetSchedulersResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/schedulers", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PauseScheduler 暂停邮箱的定时任务
+//
+// POST /api/v1/schedulers/{mailbox_id}/pause，需要 operator 角色。
+func (c *Client) PauseScheduler(ctx context.Context, mailboxID int64) (*SchedulerResponse, error) {
+	out := new(SchedulerResponse)
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/schedulers/%d/pause", mailboxID), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResumeScheduler 恢复邮箱的定时任务
+//
+// POST /api/v1/schedulers/{mailbox_id}/resume，需要 operator 角色。
+func (c *Client) ResumeScheduler(ctx context.Context, mailboxID int64) (*SchedulerResponse, error) {
+	out := new(SchedulerResponse)
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/schedulers/%d/resume", mailboxID), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetForwardTargets 转发目标列表
+//
+// GET /api/v1/targets，需要 viewer 角色。
+func (c *Client) GetForwardTargets(ctx context.Context, params *GetForwardTargetsParams) (*GetForwardTargetsResponse, error) {
+	out := new(GetForwardTargetsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/targets", params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateForwardTarget 创建转发目标
+//
+// POST /api/v1/targets，需要 operator 角色。
+func (c *Client) CreateForwardTarget(ctx context.Context, body *ForwardTargetInput) (*TargetResponse, error) {
+	out := new(TargetResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/targets", nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetForwardTarget 转发目标详情
+//
+// GET /api/v1/targets/{id}，需要 viewer 角色。
+func (c *Client) GetForwardTarget(ctx context.Context, id int64) (*GetForwardTargetResponse, error) {
+	out := new(GetForwardTargetResponse)
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/targets/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateForwardTarget 整体替换转发目标，未提供的字段恢复默认值
+//
+// PUT /api/v1/targets/{id}，需要 operator 角色。
+func (c *Client) UpdateForwardTarget(ctx context.Context, id int64, body *ForwardTargetInput) (*TargetResponse, error) {
+	out := new(TargetResponse)
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/v1/targets/%d", id), nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatchForwardTarget 部分更新转发目标，只修改请求中出现的字段
+//
+// PATCH /api/v1/targets/{id}，需要 operator 角色。
+func (c *Client) PatchForwardTarget(ctx context.Context, id int64, body *ForwardTargetInput) (*TargetResponse, error) {
+	out := new(TargetResponse)
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/v1/targets/%d", id), nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteForwardTarget 删除转发目标
+//
+// DELETE /api/v1/targets/{id}，需要 admin 角色。
+func (c *Client) DeleteForwardTarget(ctx context.Context, id int64) (*Message, error) {
+	out := new(Message)
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/targets/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ActivateForwardTarget 启用转发目标
+//
+// POST /api/v1/targets/{id}/activate，需要 operator 角色。
+func (c *Client) ActivateForwardTarget(ctx context.Context, id int64) (*TargetResponse, error) {
+	out := new(TargetResponse)
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/targets/%d/activate", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeactivateForwardTarget 停用转发目标
+//
+// POST /api/v1/targets/{id}/deactivate，需要 operator 角色。
+func (c *Client) DeactivateForwardTarget(ctx context.Context, id int64) (*TargetResponse, error) {
+	out := new(TargetResponse)
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/targets/%d/deactivate", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMailboxes 邮箱列表及运行状态
+//
+// GET /api/v1/mailboxes，需要 viewer 角色。
+func (c *Client) GetMailboxes(ctx context.Context) (*GetMailboxesResponse, error) {
+	out := new(GetMailboxesResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/mailboxes", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateMailbox 登记邮箱，启用的邮箱立即开始定时检查
+//
+// POST /api/v1/mailboxes，需要 admin 角色。
+func (c *Client) CreateMailbox(ctx context.Context, body *MailboxInput) (*MailboxResponse, error) {
+	out := new(MailboxResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/mailboxes", nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMailbox 邮箱详情及运行状态
+//
+// GET /api/v1/mailboxes/{id}，需要 viewer 角色。
+func (c *Client) GetMailbox(ctx context.Context, id int64) (*GetMailboxResponse, error) {
+	out := new(GetMailboxResponse)
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/mailboxes/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatchMailbox 部分更新邮箱，之后按新配置重启定时任务
+//
+// PATCH /api/v1/mailboxes/{id}，需要 admin 角色。
+func (c *Client) PatchMailbox(ctx context.Context, id int64, body *MailboxInput) (*MailboxResponse, error) {
+	out := new(MailboxResponse)
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/v1/mailboxes/%d", id), nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteMailbox 删除邮箱，仍有转发目标指定该邮箱时返回409
+//
+// DELETE /api/v1/mailboxes/{id}，需要 admin 角色。
+func (c *Client) DeleteMailbox(ctx context.Context, id int64) (*Message, error) {
+	out := new(Message)
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/mailboxes/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProcessMailbox 立即检查一次邮箱
+//
+// POST /api/v1/mailboxes/{id}/process，需要 operator 角色。
+func (c *Client) ProcessMailbox(ctx context.Context, id int64) (*Message, error) {
+	out := new(Message)
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/mailboxes/%d/process", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportConfig 导出当前租户的转发目标
+//
+// GET /api/v1/config/export，需要 viewer 角色。
+// 返回原始响应，调用方需要关闭响应体。
+func (c *Client) ExportConfig(ctx context.Context, params *ExportConfigParams) (*http.Response, error) {
+	return c.doRaw(ctx, http.MethodGet, "/api/v1/config/export", params.values(), nil, "")
+}
+
+// ImportConfig 导入转发目标
+//
+// POST /api/v1/config/import，需要 admin 角色。
+func (c *Client) ImportConfig(ctx context.Context, params *ImportConfigParams, body io.Reader, contentType string) (*ImportConfigResponse, error) {
+	resp, err := c.doRaw(ctx, http.MethodPost, "/api/v1/config/import", params.values(), body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out := new(ImportConfigResponse)
+	if err := decodeJSON(resp, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSubscriptions 事件订阅列表
+//
+// GET /api/v1/webhooks，需要 admin 角色。
+func (c *Client) GetSubscriptions(ctx context.Context) (*GetSubscriptionsResponse, error) {
+	out := new(GetSubscriptionsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/webhooks", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateSubscription 创建事件订阅
+//
+// POST /api/v1/webhooks，需要 admin 角色。
+func (c *Client) CreateSubscription(ctx context.Context, body *SubscriptionInput) (*SubscriptionResponse, error) {
+	out := new(SubscriptionResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/webhooks", nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSubscription 事件订阅详情
+//
+// GET /api/v1/webhooks/{id}，需要 admin 角色。
+func (c *Client) GetSubscription(ctx context.Context, id int64) (*GetSubscriptionResponse, error) {
+	out := new(GetSubscriptionResponse)
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/webhooks/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatchSubscription 部分更新事件订阅
+//
+// PATCH /api/v1/webhooks/{id}，需要 admin 角色。
+func (c *Client) PatchSubscription(ctx context.Context, id int64, body *SubscriptionInput) (*SubscriptionResponse, error) {
+	out := new(SubscriptionResponse)
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/v1/webhooks/%d", id), nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteSubscription 删除事件订阅
+//
+// DELETE /api/v1/webhooks/{id}，需要 admin 角色。
+func (c *Client) DeleteSubscription(ctx context.Context, id int64) (*Message, error) {
+	out := new(Message)
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/webhooks/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetDeliveries 事件订阅的推送记录
+//
+// GET /api/v1/webhooks/{id}/deliveries，需要 admin 角色。
+func (c *Client) GetDeliveries(ctx context.Context, id int64, params *GetDeliveriesParams) (*GetDeliveriesResponse, error) {
+	out := new(GetDeliveriesResponse)
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/webhooks/%d/deliveries", id), params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Redeliver 重新推送一条推送记录
+//
+// POST /api/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver，需要 admin 角色。
+func (c *Client) Redeliver(ctx context.Context, id int64, deliveryID int64) (*RedeliverResponse, error) {
+	out := new(RedeliverResponse)
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/webhooks/%d/deliveries/%d/redeliver", id, deliveryID), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAPIKeys API密钥列表
+//
+// GET /api/v1/api-keys，需要 admin 角色。
+func (c *Client) GetAPIKeys(ctx context.Context) (*GetAPIKeysResponse, error) {
+	out := new(GetAPIKeysResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/api-keys", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateAPIKey 创建API密钥，密钥只在创建时返回一次
+//
+// POST /api/v1/api-keys，需要 admin 角色。
+func (c *Client) CreateAPIKey(ctx context.Context, body *APIKeyInput) (*CreateAPIKeyResponse, error) {
+	out := new(CreateAPIKeyResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/api-keys", nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeAPIKey 吊销API密钥
+//
+// DELETE /api/v1/api-keys/{id}，需要 admin 角色。
+func (c *Client) RevokeAPIKey(ctx context.Context, id int64) (*Message, error) {
+	out := new(Message)
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/api-keys/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAuditLogs 审计记录
+//
+// GET /api/v1/audit，需要 admin 角色。
+func (c *Client) GetAuditLogs(ctx context.Context, params *GetAuditLogsParams) (*GetAuditLogsResponse, error) {
+	out := new(GetAuditLogsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/audit", params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreFromAudit 根据删除记录恢复转发目标
+//
+// POST /api/v1/audit/{id}/restore，需要 admin 角色。
+func (c *Client) RestoreFromAudit(ctx context.Context, id int64) (*TargetResponse, error) {
+	out := new(TargetResponse)
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/audit/%d/restore", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTenants 租户列表
+//
+// GET /api/v1/tenants，需要 superadmin 角色。
+func (c *Client) GetTenants(ctx context.Context) (*GetTenantsResponse, error) {
+	out := new(GetTenantsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tenants", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateTenant 创建租户
+//
+// POST /api/v1/tenants，需要 superadmin 角色。
+func (c *Client) CreateTenant(ctx context.Context, body *TenantInput) (*TenantResponse, error) {
+	out := new(TenantResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/tenants", nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTenantStats 各租户的用量统计
+//
+// GET /api/v1/tenants/stats，需要 superadmin 角色。
+func (c *Client) GetTenantStats(ctx context.Context) (*GetTenantStatsResponse, error) {
+	out := new(GetTenantStatsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tenants/stats", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTenant 租户详情
+//
+// GET /api/v1/tenants/{id}，需要 superadmin 角色。
+func (c *Client) GetTenant(ctx context.Context, id int64) (*GetTenantResponse, error) {
+	out := new(GetTenantResponse)
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/tenants/%d", id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatchTenant 部分更新租户
+//
+// PATCH /api/v1/tenants/{id}，需要 superadmin 角色。
+func (c *Client) PatchTenant(ctx context.Context, id int64, body *TenantInput) (*TenantResponse, error) {
+	out := new(TenantResponse)
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/v1/tenants/%d", id), nil, body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Reload 重新加载配置文件和转发目标
+//
+// POST /api/v1/admin/reload，需要 superadmin 角色。
+func (c *Client) Reload(ctx context.Context) (*ReloadResponse, error) {
+	out := new(ReloadResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/reload", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetGoogleAuthStatus 邮箱的Gmail授权状态
+//
+// GET /api/v1/auth/google/status，需要 viewer 角色。
+func (c *Client) GetGoogleAuthStatus(ctx context.Context, params *GetGoogleAuthStatusParams) (*GetGoogleAuthStatusResponse, error) {
+	out := new(GetGoogleAuthStatusResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/google/status", params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StartGoogleAuth 发起Gmail OAuth授权，返回授权链接
+//
+// GET /api/v1/auth/google/start，需要 admin 角色。
+func (c *Client) StartGoogleAuth(ctx context.Context, params *StartGoogleAuthParams) (*StartGoogleAuthResponse, error) {
+	out := new(StartGoogleAuthResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/google/start", params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GoogleCallback Google授权回调，由浏览器跳转而来
+//
+// GET /api/v1/auth/google/callback。
+func (c *Client) GoogleCallback(ctx context.Context, params *GoogleCallbackParams) (*Message, error) {
+	out := new(Message)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/google/callback", params.values(), nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
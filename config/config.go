@@ -1,79 +1,299 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Gmail    GmailConfig
-	Server   ServerConfig
-	App      AppConfig
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Database DatabaseConfig `yaml:"database"`
+	Gmail    GmailConfig    `yaml:"gmail"`
+	Server   ServerConfig   `yaml:"server"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Proxy    ProxyConfig    `yaml:"proxy"`
+	App      AppConfig      `yaml:"app"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`  // 日志级别：debug/info/warn/error
+	Format string `yaml:"format"` // 日志格式：text/json
+	Output string `yaml:"output"` // 输出位置：stdout/stderr/文件路径
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`     // 导出方式：none/stdout/otlp
+	SampleRatio float64 `yaml:"sample_ratio"` // 采样比例，0到1之间
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	Name     string
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	LogLevel string `yaml:"log_level"` // GORM日志级别：silent/error/warn/info
 }
 
 type GmailConfig struct {
-	CredentialsFile string
-	TokenFile       string
-	UserEmail       string
+	AuthMode           string `yaml:"auth_mode"`            // 认证方式：oauth（已安装应用授权）或 service_account（服务账号域范围委派）
+	CredentialsFile    string `yaml:"credentials_file"`     // oauth方式使用的OAuth客户端凭据
+	TokenFile          string `yaml:"token_file"`           // oauth方式保存授权token的文件
+	ServiceAccountFile string `yaml:"service_account_file"` // service_account方式使用的服务账号JSON密钥
+	UserEmail          string `yaml:"user_email"`
+	Query              string `yaml:"query"`        // 拉取邮件时使用的Gmail搜索条件
+	RedirectURL        string `yaml:"redirect_url"` // OAuth授权回调地址，为空时根据请求地址推断
 }
 
 type ServerConfig struct {
-	Port string
-	Mode string
+	Port            string        `yaml:"port"`
+	Mode            string        `yaml:"mode"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // 优雅关闭的最长等待时间
+	CORSOrigins     []string      `yaml:"cors_origins"`     // 允许跨域访问的来源，"*"表示全部允许
+	PublicURL       string        `yaml:"public_url"`       // 对外访问地址，用于生成webhook中附件的下载链接
 }
 
-type AppConfig struct {
-	CheckInterval    time.Duration
-	Keywords         []string
-	MaxEmailsPerBatch int64 // 每批获取的最大邮件数量
-	MaxBatches       int    // 最大批次数
+type AuthConfig struct {
+	Enabled   bool   `yaml:"enabled"`    // 是否启用API认证
+	JWTSecret string `yaml:"jwt_secret"` // JWT的HS256签名密钥，为空时只接受API密钥
+}
+
+type ProxyConfig struct {
+	URL      string   `yaml:"url"`      // 访问Google API使用的代理地址，支持 http/https/socks5/socks5h，为空时使用环境变量中的代理
+	NoProxy  []string `yaml:"no_proxy"` // 不经过代理的主机、域名（.example.com）或网段（10.0.0.0/8）
+	Username string   `yaml:"username"` // 代理认证用户名，可选
+	Password string   `yaml:"password"` // 代理认证密码，可选
 }
 
-func LoadConfig() *Config {
-	port, _ := strconv.Atoi(getEnv("DB_PORT", "3306"))
-	checkInterval, _ := time.ParseDuration(getEnv("CHECK_INTERVAL", "5m"))
-	maxEmails, _ := strconv.ParseInt(getEnv("MAX_EMAILS_PER_BATCH", "50"), 10, 64)
-	maxBatches, _ := strconv.Atoi(getEnv("MAX_BATCHES", "10"))
+// MinCheckInterval 检查间隔的下限，全局配置和邮箱单独设置的间隔都不能小于它，避免过于频繁地调用Gmail API
+const MinCheckInterval = 30 * time.Second
+
+type AppConfig struct {
+	CheckInterval     time.Duration `yaml:"check_interval"`       // 检查间隔，不能小于 MinCheckInterval
+	Keywords          []string      `yaml:"keywords"`             // 全局关键字白名单，非空时只转发关键字包含其中之一的邮件
+	MaxEmailsPerBatch int64         `yaml:"max_emails_per_batch"` // 每批获取的最大邮件数量
+	MaxBatches        int           `yaml:"max_batches"`          // 最大批次数
+	MaxAttempts       int           `yaml:"max_attempts"`         // 转发失败后最多尝试的次数（含第一次）
+	RetryBackoff      time.Duration `yaml:"retry_backoff"`        // 第一次重试的等待时间，之后每次翻倍
+}
 
+// Default 返回默认配置
+func Default() *Config {
 	return &Config{
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+			Output: "stdout",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     port,
-			User:     getEnv("DB_USER", "root"),
-			Password: getEnv("DB_PASSWORD", ""),
-			Name:     getEnv("DB_NAME", "email_forwarding"),
+			Host:     "localhost",
+			Port:     3306,
+			User:     "root",
+			Name:     "email_forwarding",
+			LogLevel: "warn",
 		},
 		Gmail: GmailConfig{
-			CredentialsFile: getEnv("GMAIL_CREDENTIALS_FILE", "credentials.json"),
-			TokenFile:       getEnv("GMAIL_TOKEN_FILE", "token.json"),
-			UserEmail:       getEnv("GMAIL_USER_EMAIL", ""),
+			AuthMode:           "oauth",
+			CredentialsFile:    "credentials.json",
+			TokenFile:          "token.json",
+			ServiceAccountFile: "service-account.json",
+			Query:              "is:unread -in:trash -in:spam",
 		},
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Port:            "8080",
+			Mode:            "debug",
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Auth: AuthConfig{
+			Enabled: true,
 		},
 		App: AppConfig{
-			CheckInterval:    checkInterval,
-			Keywords:         []string{"紧急", "重要", "客户", "投诉"}, // 可配置的关键字
-			MaxEmailsPerBatch: maxEmails,
-			MaxBatches:       maxBatches,
+			CheckInterval:     5 * time.Minute,
+			MaxEmailsPerBatch: 50,
+			MaxBatches:        10,
+			MaxAttempts:       5,
+			RetryBackoff:      time.Minute,
 		},
 	}
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// LoadConfig 加载配置：默认值 < 配置文件 < 环境变量，加载后进行校验
+// path 为空时使用环境变量 CONFIG_FILE，仍为空时如果存在 config.yaml 则加载它
+func LoadConfig(path string) (*Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		if _, err := os.Stat(DefaultConfigFile); err == nil {
+			path = DefaultConfigFile
+		}
+	}
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// 环境变量的格式错误和校验错误一起返回，便于一次修正所有问题
+	if err := errors.Join(applyEnv(cfg), cfg.Validate()); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv 用环境变量覆盖配置，只处理已设置的变量，格式错误的值会被汇总返回
+func applyEnv(cfg *Config) error {
+	e := &envLoader{}
+
+	e.str("LOG_LEVEL", &cfg.Log.Level)
+	e.str("LOG_FORMAT", &cfg.Log.Format)
+	e.str("LOG_OUTPUT", &cfg.Log.Output)
+
+	e.str("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	e.float("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+
+	e.str("DB_HOST", &cfg.Database.Host)
+	e.int("DB_PORT", &cfg.Database.Port)
+	e.str("DB_USER", &cfg.Database.User)
+	e.str("DB_PASSWORD", &cfg.Database.Password)
+	e.str("DB_NAME", &cfg.Database.Name)
+	e.str("DB_LOG_LEVEL", &cfg.Database.LogLevel)
+
+	e.str("GMAIL_AUTH_MODE", &cfg.Gmail.AuthMode)
+	e.str("GMAIL_CREDENTIALS_FILE", &cfg.Gmail.CredentialsFile)
+	e.str("GMAIL_TOKEN_FILE", &cfg.Gmail.TokenFile)
+	e.str("GMAIL_SERVICE_ACCOUNT_FILE", &cfg.Gmail.ServiceAccountFile)
+	e.str("GMAIL_USER_EMAIL", &cfg.Gmail.UserEmail)
+	e.str("GMAIL_QUERY", &cfg.Gmail.Query)
+	e.str("GMAIL_REDIRECT_URL", &cfg.Gmail.RedirectURL)
+
+	e.str("SERVER_PORT", &cfg.Server.Port)
+	e.str("GIN_MODE", &cfg.Server.Mode)
+	e.duration("SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	e.list("CORS_ALLOWED_ORIGINS", &cfg.Server.CORSOrigins)
+	e.str("SERVER_PUBLIC_URL", &cfg.Server.PublicURL)
+
+	e.bool("AUTH_ENABLED", &cfg.Auth.Enabled)
+	e.str("JWT_SECRET", &cfg.Auth.JWTSecret)
+
+	e.str("PROXY_URL", &cfg.Proxy.URL)
+	e.list("PROXY_NO_PROXY", &cfg.Proxy.NoProxy)
+	e.str("PROXY_USERNAME", &cfg.Proxy.Username)
+	e.str("PROXY_PASSWORD", &cfg.Proxy.Password)
+
+	e.duration("CHECK_INTERVAL", &cfg.App.CheckInterval)
+	e.list("KEYWORDS", &cfg.App.Keywords)
+	e.int64("MAX_EMAILS_PER_BATCH", &cfg.App.MaxEmailsPerBatch)
+	e.int("MAX_BATCHES", &cfg.App.MaxBatches)
+	e.int("MAX_ATTEMPTS", &cfg.App.MaxAttempts)
+	e.duration("RETRY_BACKOFF", &cfg.App.RetryBackoff)
+
+	return errors.Join(e.errs...)
+}
+
+// envLoader 读取环境变量并记录解析错误
+type envLoader struct {
+	errs []error
+}
+
+func (e *envLoader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+func (e *envLoader) fail(key, value, expect string) {
+	e.errs = append(e.errs, fmt.Errorf("环境变量 %s=%q 无效，应为%s", key, value, expect))
+}
+
+func (e *envLoader) str(key string, dst *string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (e *envLoader) int(key string, dst *int) {
+	if value, ok := e.lookup(key); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			e.fail(key, value, "整数")
+			return
+		}
+		*dst = n
 	}
-	return defaultValue
-}
\ No newline at end of file
+}
+
+func (e *envLoader) int64(key string, dst *int64) {
+	if value, ok := e.lookup(key); ok {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			e.fail(key, value, "整数")
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envLoader) float(key string, dst *float64) {
+	if value, ok := e.lookup(key); ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.fail(key, value, "数字")
+			return
+		}
+		*dst = f
+	}
+}
+
+func (e *envLoader) bool(key string, dst *bool) {
+	if value, ok := e.lookup(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			e.fail(key, value, "true或false")
+			return
+		}
+		*dst = b
+	}
+}
+
+func (e *envLoader) duration(key string, dst *time.Duration) {
+	if value, ok := e.lookup(key); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			e.fail(key, value, "时长，例如 30s、5m")
+			return
+		}
+		*dst = d
+	}
+}
+
+func (e *envLoader) list(key string, dst *[]string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = splitList(value)
+	}
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
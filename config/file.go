@@ -0,0 +1,49 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile 未指定配置文件时，如果当前目录存在该文件则自动加载
+const DefaultConfigFile = "config.yaml"
+
+// loadFile 读取配置文件并覆盖到 cfg 上，根据扩展名识别 YAML（.yaml/.yml）或 TOML（.toml）
+// 未知的配置项会报错，避免拼写错误被静默忽略
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件 %s 失败: %v", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		// TOML先解析为通用结构再转成YAML，与YAML共用同一套字段映射和时长解析
+		var raw map[string]interface{}
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+		}
+		if data, err = yaml.Marshal(raw); err != nil {
+			return fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s（支持 .yaml、.yml、.toml）", path)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+
+	return nil
+}
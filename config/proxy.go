@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyFunc 返回供 http.Transport 使用的代理选择函数
+// 配置了 proxy.url 时所有请求都经过该代理（no_proxy 中的地址除外）；
+// 未配置时使用标准环境变量 HTTP_PROXY、HTTPS_PROXY、NO_PROXY
+func (p ProxyConfig) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if p.URL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := p.parseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    strings.Join(p.NoProxy, ","),
+	}
+	proxyFunc := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// String 返回用于日志输出的代理描述，不包含密码
+func (p ProxyConfig) String() string {
+	if p.URL == "" {
+		return "使用环境变量（HTTP_PROXY/HTTPS_PROXY/NO_PROXY）"
+	}
+	proxyURL, err := p.parseURL()
+	if err != nil {
+		return p.URL
+	}
+	if proxyURL.User != nil {
+		proxyURL.User = url.User(proxyURL.User.Username())
+	}
+	desc := proxyURL.String()
+	if len(p.NoProxy) > 0 {
+		desc += "，不代理: " + strings.Join(p.NoProxy, ",")
+	}
+	return desc
+}
+
+// parseURL 解析代理地址，username/password 配置项优先于地址中的认证信息
+func (p ProxyConfig) parseURL() (*url.URL, error) {
+	proxyURL, err := url.Parse(p.URL)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy.url 无效: %q", p.URL)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy.url 协议不支持: %q，应为 http、https、socks5 或 socks5h", proxyURL.Scheme)
+	}
+
+	if p.Username != "" {
+		proxyURL.User = url.UserPassword(p.Username, p.Password)
+	}
+	return proxyURL, nil
+}
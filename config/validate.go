@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+)
+
+// Validate 校验配置，返回所有不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !oneOf(c.Log.Level, "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic") {
+		fail("log.level 无效: %q", c.Log.Level)
+	}
+	if !oneOf(c.Log.Format, "text", "json") {
+		fail("log.format 无效: %q，应为 text 或 json", c.Log.Format)
+	}
+
+	if !oneOf(c.Tracing.Exporter, "none", "stdout", "otlp") {
+		fail("tracing.exporter 无效: %q，应为 none、stdout 或 otlp", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio 必须在0到1之间: %v", c.Tracing.SampleRatio)
+	}
+
+	if c.Database.Host == "" {
+		fail("database.host 不能为空")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		fail("database.port 无效: %d", c.Database.Port)
+	}
+	if c.Database.Name == "" {
+		fail("database.name 不能为空")
+	}
+	if !oneOf(c.Database.LogLevel, "silent", "error", "warn", "info") {
+		fail("database.log_level 无效: %q，应为 silent、error、warn 或 info", c.Database.LogLevel)
+	}
+
+	if c.Gmail.UserEmail == "" {
+		fail("gmail.user_email 不能为空（环境变量 GMAIL_USER_EMAIL）")
+	} else if _, err := mail.ParseAddress(c.Gmail.UserEmail); err != nil {
+		fail("gmail.user_email 不是有效的邮箱地址: %q", c.Gmail.UserEmail)
+	}
+	switch c.Gmail.AuthMode {
+	case "oauth":
+		if c.Gmail.CredentialsFile == "" {
+			fail("gmail.credentials_file 不能为空")
+		}
+		if c.Gmail.TokenFile == "" {
+			fail("gmail.token_file 不能为空")
+		}
+	case "service_account":
+		if c.Gmail.ServiceAccountFile == "" {
+			fail("gmail.auth_mode 为 service_account 时 gmail.service_account_file 不能为空")
+		}
+	default:
+		fail("gmail.auth_mode 无效: %q，应为 oauth 或 service_account", c.Gmail.AuthMode)
+	}
+	if c.Gmail.RedirectURL != "" {
+		if u, err := url.Parse(c.Gmail.RedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("gmail.redirect_url 无效: %q", c.Gmail.RedirectURL)
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		fail("server.port 无效: %q，应为1到65535之间的整数", c.Server.Port)
+	}
+	if !oneOf(c.Server.Mode, "debug", "release", "test") {
+		fail("server.mode 无效: %q，应为 debug、release 或 test", c.Server.Mode)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout 必须大于0: %s", c.Server.ShutdownTimeout)
+	}
+	if c.Server.PublicURL != "" {
+		if u, err := url.Parse(c.Server.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("server.public_url 无效: %q", c.Server.PublicURL)
+		}
+	}
+
+	if c.Proxy.URL != "" {
+		if _, err := c.Proxy.parseURL(); err != nil {
+			errs = append(errs, err)
+		}
+	} else if c.Proxy.Username != "" {
+		fail("proxy.username 需要同时配置 proxy.url")
+	}
+
+	if c.App.CheckInterval < MinCheckInterval {
+		fail("app.check_interval 不能小于%s: %s", MinCheckInterval, c.App.CheckInterval)
+	}
+	if c.App.MaxEmailsPerBatch < 1 || c.App.MaxEmailsPerBatch > 500 {
+		fail("app.max_emails_per_batch 必须在1到500之间: %d", c.App.MaxEmailsPerBatch)
+	}
+	if c.App.MaxBatches < 1 {
+		fail("app.max_batches 必须大于0: %d", c.App.MaxBatches)
+	}
+	if c.App.MaxAttempts < 1 {
+		fail("app.max_attempts 必须大于0: %d", c.App.MaxAttempts)
+	}
+	if c.App.RetryBackoff <= 0 {
+		fail("app.retry_backoff 必须大于0: %s", c.App.RetryBackoff)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Masked 返回隐藏了密码和密钥的配置副本，用于打印
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.Database.Password = mask(c.Database.Password)
+	masked.Auth.JWTSecret = mask(c.Auth.JWTSecret)
+	masked.Proxy.Password = mask(c.Proxy.Password)
+	if u, err := url.Parse(c.Proxy.URL); err == nil && u.User != nil {
+		u.User = url.User(u.User.Username())
+		masked.Proxy.URL = u.String()
+	}
+	return &masked
+}
+
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "******"
+}
+
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
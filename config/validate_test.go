@@ -0,0 +1,30 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateCheckInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		valid    bool
+	}{
+		{MinCheckInterval, true},
+		{5 * time.Minute, true},
+		{time.Millisecond, false},
+		{MinCheckInterval - time.Second, false},
+		{0, false},
+	}
+
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.App.CheckInterval = tt.interval
+		err := cfg.Validate()
+		rejected := err != nil && strings.Contains(err.Error(), "app.check_interval")
+		if rejected == tt.valid {
+			t.Errorf("check_interval %s: valid = %v, 错误 = %v", tt.interval, tt.valid, err)
+		}
+	}
+}
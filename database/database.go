@@ -1,14 +1,14 @@
 package database
 
 import (
+	"context"
 	"email-forwarding/config"
 	"email-forwarding/models"
+	"email-forwarding/utils"
 	"fmt"
-	"log"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
@@ -23,30 +23,72 @@ func InitDatabase(cfg *config.Config) error {
 		cfg.Database.Name,
 	)
 
-	var err error
+	gormLog, err := newGormLogger(cfg.Database.LogLevel)
+	if err != nil {
+		return err
+	}
+
 	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: gormLog,
+		// 把唯一索引冲突等数据库错误转换为 gorm.ErrDuplicatedKey 等通用错误
+		TranslateError: true,
 	})
 
 	if err != nil {
 		return fmt.Errorf("failed to connect database: %v", err)
 	}
 
+	// SQL链路追踪
+	if err := DB.Use(newTracingPlugin()); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %v", err)
+	}
+
 	// 自动迁移数据表
 	if err := autoMigrate(); err != nil {
 		return fmt.Errorf("failed to migrate database: %v", err)
 	}
+	if err := ensureDefaultTenant(); err != nil {
+		return fmt.Errorf("failed to create default tenant: %v", err)
+	}
 
-	log.Println("数据库连接成功")
+	utils.GetLogger().Info("数据库连接成功")
 	return nil
 }
 
 // autoMigrate 自动迁移数据表
 func autoMigrate() error {
-	return DB.AutoMigrate(
+	err := DB.AutoMigrate(
 		&models.ForwardTarget{},
 		&models.EmailLog{},
+		&models.APIKey{},
+		&models.AuditLog{},
+		&models.Mailbox{},
+		&models.Tenant{},
+		&models.WebhookSubscription{},
+		&models.EventDelivery{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// 邮件去重改为按邮箱和Gmail消息ID，删除旧版本只按Gmail消息ID建立的唯一索引
+	if DB.Migrator().HasIndex(&models.EmailLog{}, "idx_email_logs_gmail_message_id") {
+		return DB.Migrator().DropIndex(&models.EmailLog{}, "idx_email_logs_gmail_message_id")
+	}
+	return nil
+}
+
+// ensureDefaultTenant 确保默认租户存在，升级前的数据都属于默认租户
+func ensureDefaultTenant() error {
+	var count int64
+	if err := DB.Model(&models.Tenant{}).Where("id = ?", models.DefaultTenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return DB.Create(&models.Tenant{ID: models.DefaultTenantID, Name: "default", IsActive: true}).Error
 }
 
 // GetDB 获取数据库连接
@@ -90,8 +132,33 @@ func CreateDefaultForwardTargets() error {
 			}
 		}
 
-		log.Println("创建默认转发目标成功")
+		utils.GetLogger().Info("创建默认转发目标成功")
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// Close 关闭数据库连接
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Ping 检查数据库连接是否可用
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
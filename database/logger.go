@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"email-forwarding/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold 超过该耗时的SQL按警告级别输出
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger 将GORM的日志输出到统一的logrus日志，并带上context中的日志字段
+type gormLogger struct {
+	level logger.LogLevel
+}
+
+// newGormLogger 创建GORM日志适配器，level 可选 silent/error/warn/info
+func newGormLogger(level string) (logger.Interface, error) {
+	levels := map[string]logger.LogLevel{
+		"silent": logger.Silent,
+		"error":  logger.Error,
+		"warn":   logger.Warn,
+		"info":   logger.Info,
+	}
+
+	if level == "" {
+		level = "warn"
+	}
+	lvl, ok := levels[level]
+	if !ok {
+		return nil, fmt.Errorf("无效的数据库日志级别: %s", level)
+	}
+	return &gormLogger{level: lvl}, nil
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &gormLogger{level: level}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		utils.LoggerFromContext(ctx).Infof(msg, args...)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		utils.LoggerFromContext(ctx).Warnf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		utils.LoggerFromContext(ctx).Errorf(msg, args...)
+	}
+}
+
+// Trace 输出SQL语句：出错时为error，慢查询为warn，其余只在info级别输出
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	entry := utils.LoggerFromContext(ctx).WithFields(logrus.Fields{
+		"component":   "gorm",
+		"duration_ms": elapsed.Milliseconds(),
+		"rows":        rows,
+		"sql":         sql,
+	})
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		entry.WithError(err).Error("SQL执行失败")
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		entry.Warn("慢查询")
+	case l.level >= logger.Info:
+		entry.Info("SQL")
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"email-forwarding/models"
+	"email-forwarding/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	authService *services.AuthService
+}
+
+// NewAuthHandler 创建认证处理器
+func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+// createAPIKeyRequest 创建API密钥的请求参数
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GetAPIKeys 获取API密钥列表
+func (h *AuthHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.authService.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": keys,
+	})
+}
+
+// CreateAPIKey 创建API密钥
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+	if req.Name == "" || !models.IsValidRole(req.Role) {
+		invalidRequest(c, "名称不能为空，角色必须是 viewer/operator/admin/superadmin 之一")
+		return
+	}
+
+	rawKey, key, err := h.authService.CreateAPIKey(c.Request.Context(), req.Name, req.Role, req.ExpiresAt)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "创建成功，请妥善保存密钥，之后将无法再次查看",
+		"key":     rawKey,
+		"data":    key,
+	})
+}
+
+// RevokeAPIKey 吊销API密钥
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		invalidRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(c.Request.Context(), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "吊销成功",
+	})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"email-forwarding/services"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configContentTypes 各配置格式对应的Content-Type
+var configContentTypes = map[string]string{
+	services.FormatYAML: "application/yaml; charset=utf-8",
+	services.FormatJSON: "application/json; charset=utf-8",
+	services.FormatCSV:  "text/csv; charset=utf-8",
+}
+
+type ConfigHandler struct {
+	configService *services.ConfigService
+}
+
+// NewConfigHandler 创建配置导入导出处理器
+func NewConfigHandler(configService *services.ConfigService) *ConfigHandler {
+	return &ConfigHandler{
+		configService: configService,
+	}
+}
+
+// ExportConfig 导出转发配置，format 可选 yaml/json/csv，默认yaml
+func (h *ConfigHandler) ExportConfig(c *gin.Context) {
+	format := c.DefaultQuery("format", services.FormatYAML)
+	contentType, ok := configContentTypes[format]
+	if !ok {
+		invalidRequest(c, "不支持的格式: %s", format)
+		return
+	}
+
+	doc, err := h.configService.ExportConfig(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	filename := "forward-targets-" + time.Now().Format("20060102") + "." + format
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+	if err := services.EncodeConfig(c.Writer, doc, format); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// ImportConfig 导入转发配置
+// 请求体为配置内容，format 未指定时根据Content-Type判断；dry_run=true 时只返回差异，prune=true 时删除配置中不存在的目标
+func (h *ConfigHandler) ImportConfig(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		format = formatFromContentType(c.ContentType())
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	prune, _ := strconv.ParseBool(c.DefaultQuery("prune", "false"))
+
+	doc, err := services.DecodeConfig(c.Request.Body, format)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	result, err := h.configService.ImportConfig(c.Request.Context(), doc, services.ImportOptions{
+		DryRun: dryRun,
+		Prune:  prune,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	message := "导入成功"
+	if dryRun {
+		message = "预览完成，未写入任何变更"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    result,
+	})
+}
+
+// formatFromContentType 根据Content-Type推断配置格式，无法判断时按yaml处理
+func formatFromContentType(contentType string) string {
+	switch contentType {
+	case "application/json":
+		return services.FormatJSON
+	case "text/csv":
+		return services.FormatCSV
+	default:
+		return services.FormatYAML
+	}
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"email-forwarding/middleware"
 	"email-forwarding/models"
 	"email-forwarding/services"
-	"email-forwarding/utils"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,14 +25,8 @@ func NewEmailHandler(emailService *services.EmailService) *EmailHandler {
 
 // ProcessEmails 手动处理邮件
 func (h *EmailHandler) ProcessEmails(c *gin.Context) {
-	logger := utils.GetLogger()
-	
-	if err := h.emailService.ProcessEmails(); err != nil {
-		logger.Errorf("处理邮件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "处理邮件失败",
-			"message": err.Error(),
-		})
+	if err := h.emailService.ProcessEmails(c.Request.Context()); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -43,7 +39,10 @@ func (h *EmailHandler) ProcessEmails(c *gin.Context) {
 func (h *EmailHandler) GetEmailLogs(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	status := c.Query("status")
+	filter, ok := parseEmailLogFilter(c)
+	if !ok {
+		return
+	}
 
 	if page < 1 {
 		page = 1
@@ -52,12 +51,9 @@ func (h *EmailHandler) GetEmailLogs(c *gin.Context) {
 		pageSize = 20
 	}
 
-	logs, total, err := h.emailService.GetEmailLogs(page, pageSize, status)
+	logs, total, err := h.emailService.GetEmailLogs(c.Request.Context(), filter, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "获取邮件日志失败",
-			"message": err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
@@ -72,14 +68,81 @@ func (h *EmailHandler) GetEmailLogs(c *gin.Context) {
 	})
 }
 
+// logExportContentTypes 各导出格式对应的Content-Type
+var logExportContentTypes = map[string]string{
+	services.FormatCSV:  "text/csv; charset=utf-8",
+	services.FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportEmailLogs 导出邮件日志，筛选条件与日志列表相同
+// format 可选 csv/xlsx，默认csv；columns 为逗号分隔的列名；excerpt 为正文摘录的字数，默认不导出正文
+func (h *EmailHandler) ExportEmailLogs(c *gin.Context) {
+	filter, ok := parseEmailLogFilter(c)
+	if !ok {
+		return
+	}
+	excerpt, err := strconv.Atoi(c.DefaultQuery("excerpt", "0"))
+	if err != nil {
+		invalidRequest(c, "无效的excerpt")
+		return
+	}
+
+	export, err := services.NewLogExport(c.DefaultQuery("format", services.FormatCSV), splitQuery(c.Query("columns")), excerpt, middleware.Language(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	filename := "email-logs-" + time.Now().Format("20060102") + "." + export.Format()
+	c.Header("Content-Type", logExportContentTypes[export.Format()])
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+	if err := h.emailService.ExportEmailLogs(c.Request.Context(), filter, export, c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// parseEmailLogFilter 解析日志列表和导出共用的筛选参数，无效时直接返回400
+// from、to 为RFC3339格式的时间，筛选记录时间在 [from, to) 内的日志
+func parseEmailLogFilter(c *gin.Context) (services.EmailLogFilter, bool) {
+	mailboxID, ok := parseMailboxQuery(c)
+	if !ok {
+		return services.EmailLogFilter{}, false
+	}
+	filter := services.EmailLogFilter{
+		Status:    c.Query("status"),
+		MailboxID: mailboxID,
+		Search:    c.Query("search"),
+	}
+
+	for param, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			invalidRequest(c, "无效的时间参数 %s，时间格式应为RFC3339，例如 2024-01-02T15:04:05+08:00", param)
+			return services.EmailLogFilter{}, false
+		}
+		*dst = &t
+	}
+	return filter, true
+}
+
 // GetForwardTargets 获取转发目标列表
+// 默认只返回启用的目标，include_inactive=true 时包含已停用的目标，search 按名称/邮箱/关键字搜索，
+// mailbox_id 只返回适用于该邮箱的目标
 func (h *EmailHandler) GetForwardTargets(c *gin.Context) {
-	targets, err := h.emailService.GetForwardTargets()
+	includeInactive, _ := strconv.ParseBool(c.DefaultQuery("include_inactive", "false"))
+	mailboxID, ok := parseMailboxQuery(c)
+	if !ok {
+		return
+	}
+
+	targets, err := h.emailService.GetForwardTargets(c.Request.Context(), includeInactive, c.Query("search"), mailboxID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "获取转发目标失败",
-			"message": err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
@@ -88,98 +151,130 @@ func (h *EmailHandler) GetForwardTargets(c *gin.Context) {
 	})
 }
 
-// CreateForwardTarget 创建转发目标
-func (h *EmailHandler) CreateForwardTarget(c *gin.Context) {
-	var target models.ForwardTarget
-	if err := c.ShouldBindJSON(&target); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "参数错误",
-			"message": err.Error(),
-		})
+// GetForwardTarget 获取单个转发目标
+func (h *EmailHandler) GetForwardTarget(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
 		return
 	}
 
-	// 验证必填字段
-	if target.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "名称不能为空",
-		})
+	target, err := h.emailService.GetForwardTarget(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if target.Email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "邮箱不能为空",
-		})
+	c.JSON(http.StatusOK, gin.H{
+		"data": target,
+	})
+}
+
+// CreateForwardTarget 创建转发目标
+func (h *EmailHandler) CreateForwardTarget(c *gin.Context) {
+	var req services.ForwardTargetPatch
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// 设置默认值
-	target.IsActive = true
-
-	if err := h.emailService.CreateForwardTarget(&target); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "创建转发目标失败",
-			"message": err.Error(),
-		})
+	target := targetFromRequest(req)
+	if err := h.emailService.CreateForwardTarget(c.Request.Context(), &target); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "创建成功",
-		"data": target,
+		"data":    target,
 	})
 }
 
-// UpdateForwardTarget 更新转发目标
+// UpdateForwardTarget 整体更新转发目标，未提供的字段会被清空，is_active未提供时视为启用
 func (h *EmailHandler) UpdateForwardTarget(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req services.ForwardTargetPatch
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	target := targetFromRequest(req)
+	updated, err := h.emailService.UpdateForwardTarget(c.Request.Context(), id, &target)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "无效的ID",
-		})
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"data":    updated,
+	})
+}
+
+// PatchForwardTarget 部分更新转发目标，只修改请求中出现的字段
+func (h *EmailHandler) PatchForwardTarget(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
 		return
 	}
 
-	var target models.ForwardTarget
-	if err := c.ShouldBindJSON(&target); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "参数错误",
-			"message": err.Error(),
-		})
+	var patch services.ForwardTargetPatch
+	if !bindJSON(c, &patch) {
 		return
 	}
 
-	if err := h.emailService.UpdateForwardTarget(uint(id), &target); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "更新转发目标失败",
-			"message": err.Error(),
-		})
+	updated, err := h.emailService.PatchForwardTarget(c.Request.Context(), id, patch)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "更新成功",
+		"data":    updated,
+	})
+}
+
+// ActivateForwardTarget 启用转发目标
+func (h *EmailHandler) ActivateForwardTarget(c *gin.Context) {
+	h.setForwardTargetActive(c, true)
+}
+
+// DeactivateForwardTarget 停用转发目标
+func (h *EmailHandler) DeactivateForwardTarget(c *gin.Context) {
+	h.setForwardTargetActive(c, false)
+}
+
+// setForwardTargetActive 修改转发目标的启用状态
+func (h *EmailHandler) setForwardTargetActive(c *gin.Context, active bool) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.emailService.SetForwardTargetActive(c.Request.Context(), id, active)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"data":    updated,
 	})
 }
 
 // DeleteForwardTarget 删除转发目标
 func (h *EmailHandler) DeleteForwardTarget(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "无效的ID",
-		})
+	id, ok := parseIDParam(c)
+	if !ok {
 		return
 	}
 
-	if err := h.emailService.DeleteForwardTarget(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "删除转发目标失败",
-			"message": err.Error(),
-		})
+	if err := h.emailService.DeleteForwardTarget(c.Request.Context(), id); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -188,12 +283,122 @@ func (h *EmailHandler) DeleteForwardTarget(c *gin.Context) {
 	})
 }
 
-// GetStats 获取统计信息
+// targetFromRequest 根据请求参数构造转发目标，is_active未提供时默认启用
+func targetFromRequest(req services.ForwardTargetPatch) models.ForwardTarget {
+	target := models.ForwardTarget{IsActive: true}
+	if req.Name != nil {
+		target.Name = *req.Name
+	}
+	if req.Type != nil {
+		target.Type = *req.Type
+	}
+	if req.Email != nil {
+		target.Email = *req.Email
+	}
+	if req.WebhookURL != nil {
+		target.WebhookURL = *req.WebhookURL
+	}
+	if req.Secret != nil {
+		target.Secret = *req.Secret
+	}
+	if req.AttachmentMode != nil {
+		target.AttachmentMode = *req.AttachmentMode
+	}
+	if req.Keywords != nil {
+		target.Keywords = *req.Keywords
+	}
+	if req.IsActive != nil {
+		target.IsActive = *req.IsActive
+	}
+	if req.MailboxID != nil {
+		target.MailboxID = *req.MailboxID
+	}
+	return target
+}
+
+// parseIDParam 解析路径中的ID参数，失败时直接返回400
+func parseIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		invalidRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseMailboxQuery 解析查询参数中的mailbox_id，未提供时返回nil，无效时直接返回400
+func parseMailboxQuery(c *gin.Context) (*uint, bool) {
+	value := c.Query("mailbox_id")
+	if value == "" {
+		return nil, true
+	}
+
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		invalidRequest(c, "无效的mailbox_id")
+		return nil, false
+	}
+	mailboxID := uint(id)
+	return &mailboxID, true
+}
+
+// GetEmailLog 获取单条邮件日志，包含邮件内容和转发内容预览
+func (h *EmailHandler) GetEmailLog(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.emailService.GetEmailLog(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": detail,
+	})
+}
+
+// RuleTestRequest 规则测试请求
+type RuleTestRequest struct {
+	Subject   string `json:"subject" binding:"required"`
+	MailboxID uint   `json:"mailbox_id"`
+}
+
+// TestRule 用邮件标题测试转发规则，返回解析出的关键字、目标名字和匹配到的转发目标
+func (h *EmailHandler) TestRule(c *gin.Context) {
+	var req RuleTestRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.emailService.TestRule(c.Request.Context(), req.MailboxID, req.Subject)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
+// GetStats 获取当前租户最近几天的处理统计，days 默认14，最多90
 func (h *EmailHandler) GetStats(c *gin.Context) {
-	// 这里可以添加统计信息的逻辑
-	// 比如：总处理邮件数、成功转发数、失败数等
-	
+	days, err := strconv.Atoi(c.DefaultQuery("days", "14"))
+	if err != nil || days < 1 || days > 90 {
+		invalidRequest(c, "days 必须是1到90之间的整数")
+		return
+	}
+
+	stats, err := h.emailService.GetStats(c.Request.Context(), days)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "统计功能待实现",
+		"data": stats,
 	})
 }
\ No newline at end of file
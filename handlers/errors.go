@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"email-forwarding/middleware"
+	"email-forwarding/services"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError 返回统一格式的错误响应，HTTP状态码和错误码由错误类别决定
+func respondError(c *gin.Context, err error) {
+	middleware.AbortWithError(c, err)
+}
+
+// invalidRequest 返回400，format说明哪个参数无效
+func invalidRequest(c *gin.Context, format string, args ...interface{}) {
+	respondError(c, fmt.Errorf("%w: %s", services.ErrInvalidRequest, fmt.Sprintf(format, args...)))
+}
+
+// bindJSON 解析JSON请求体，失败时直接返回400并列出校验失败的字段
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondError(c, fmt.Errorf("%w: %w", services.ErrInvalidRequest, err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"email-forwarding/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService *services.HealthService
+}
+
+// NewHealthHandler 创建健康检查处理器
+func NewHealthHandler(healthService *services.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+// Liveness 存活检查，只要进程能响应请求即返回200
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":         services.HealthUp,
+		"uptime_seconds": int64(h.healthService.Uptime().Seconds()),
+		"timestamp":      time.Now().Unix(),
+	})
+}
+
+// Readiness 就绪检查，数据库、Gmail或定时任务任一不可用时返回503，降级时仍返回200
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.healthService.Readiness(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == services.HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
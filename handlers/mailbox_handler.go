@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"email-forwarding/models"
+	"email-forwarding/services"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MailboxHandler struct {
+	mailboxService *services.MailboxService
+}
+
+// NewMailboxHandler 创建邮箱处理器
+func NewMailboxHandler(mailboxService *services.MailboxService) *MailboxHandler {
+	return &MailboxHandler{
+		mailboxService: mailboxService,
+	}
+}
+
+// GetMailboxes 获取邮箱列表及运行状态
+func (h *MailboxHandler) GetMailboxes(c *gin.Context) {
+	mailboxes, err := h.mailboxService.GetMailboxes(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": mailboxes,
+	})
+}
+
+// GetMailbox 获取单个邮箱及运行状态
+func (h *MailboxHandler) GetMailbox(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	mailbox, err := h.mailboxService.GetMailbox(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": mailbox,
+	})
+}
+
+// CreateMailbox 创建邮箱，启用的邮箱立即开始定时检查
+func (h *MailboxHandler) CreateMailbox(c *gin.Context) {
+	var req services.MailboxPatch
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	mailbox := mailboxFromRequest(req)
+	if err := h.mailboxService.CreateMailbox(c.Request.Context(), &mailbox); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "创建成功",
+		"data":    mailbox,
+	})
+}
+
+// PatchMailbox 部分更新邮箱，只修改请求中出现的字段，之后该邮箱的定时任务按新配置重启
+func (h *MailboxHandler) PatchMailbox(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var patch services.MailboxPatch
+	if !bindJSON(c, &patch) {
+		return
+	}
+
+	updated, err := h.mailboxService.PatchMailbox(c.Request.Context(), id, patch)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"data":    updated,
+	})
+}
+
+// DeleteMailbox 删除邮箱并停止其定时任务
+func (h *MailboxHandler) DeleteMailbox(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mailboxService.DeleteMailbox(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// ProcessMailbox 立即检查一次邮箱
+func (h *MailboxHandler) ProcessMailbox(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mailboxService.ProcessMailbox(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "邮件处理完成",
+	})
+}
+
+// GetAttachment 下载已处理邮件的附件，通用webhook请求体中的附件链接指向这里
+func (h *MailboxHandler) GetAttachment(c *gin.Context) {
+	attachment, data, err := h.mailboxService.GetAttachment(c.Request.Context(), c.Param("message_id"), c.Param("part_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	mimeType := attachment.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Data(http.StatusOK, mimeType, data)
+}
+
+// mailboxFromRequest 根据请求参数构造邮箱，is_active未提供时默认启用
+func mailboxFromRequest(req services.MailboxPatch) models.Mailbox {
+	mailbox := models.Mailbox{IsActive: true}
+	for dst, value := range map[*string]*string{
+		&mailbox.Name:               req.Name,
+		&mailbox.Address:            req.Address,
+		&mailbox.Provider:           req.Provider,
+		&mailbox.AuthMode:           req.AuthMode,
+		&mailbox.CredentialsFile:    req.CredentialsFile,
+		&mailbox.TokenFile:          req.TokenFile,
+		&mailbox.ServiceAccountFile: req.ServiceAccountFile,
+		&mailbox.Query:              req.Query,
+		&mailbox.CheckInterval:      req.CheckInterval,
+	} {
+		if value != nil {
+			*dst = *value
+		}
+	}
+	if req.IsActive != nil {
+		mailbox.IsActive = *req.IsActive
+	}
+	return mailbox
+}
+
+// GetSchedulers 获取当前租户各邮箱的定时任务状态，默认邮箱的mailbox_id为0
+func (h *MailboxHandler) GetSchedulers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": h.mailboxService.GetSchedulers(c.Request.Context()),
+	})
+}
+
+// PauseScheduler 暂停邮箱的定时任务
+func (h *MailboxHandler) PauseScheduler(c *gin.Context) {
+	h.setSchedulerPaused(c, true)
+}
+
+// ResumeScheduler 恢复邮箱的定时任务
+func (h *MailboxHandler) ResumeScheduler(c *gin.Context) {
+	h.setSchedulerPaused(c, false)
+}
+
+// setSchedulerPaused 暂停或恢复路径中mailbox_id对应邮箱的定时任务
+func (h *MailboxHandler) setSchedulerPaused(c *gin.Context, paused bool) {
+	id, err := strconv.ParseUint(c.Param("mailbox_id"), 10, 32)
+	if err != nil {
+		invalidRequest(c, "无效的邮箱ID")
+		return
+	}
+
+	info, err := h.mailboxService.PauseScheduler(c.Request.Context(), uint(id), paused)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	message := "定时任务已恢复"
+	if paused {
+		message = "定时任务已暂停"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    info,
+	})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"email-forwarding/models"
+	"email-forwarding/services"
+	"email-forwarding/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GoogleCallbackPath Google OAuth授权完成后的回调地址
+const GoogleCallbackPath = "/api/v1/auth/google/callback"
+
+type OAuthHandler struct {
+	mailboxService *services.MailboxService
+	redirectURL    string
+}
+
+// NewOAuthHandler 创建Google授权处理器
+// redirectURL 为空时根据请求的Host推断回调地址
+func NewOAuthHandler(mailboxService *services.MailboxService, redirectURL string) *OAuthHandler {
+	return &OAuthHandler{
+		mailboxService: mailboxService,
+		redirectURL:    redirectURL,
+	}
+}
+
+// gmailService 根据查询参数mailbox_id选择邮箱，未提供时为默认邮箱，失败时直接返回错误响应
+func (h *OAuthHandler) gmailService(c *gin.Context) (*services.GmailService, bool) {
+	mailboxID, ok := parseMailboxQuery(c)
+	if !ok {
+		return nil, false
+	}
+	id := uint(models.DefaultMailboxID)
+	if mailboxID != nil {
+		id = *mailboxID
+	}
+
+	gmailService, err := h.mailboxService.GmailService(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	return gmailService, true
+}
+
+// GetGoogleAuthStatus 查询Gmail是否已授权，mailbox_id 指定数据库中的邮箱
+func (h *OAuthHandler) GetGoogleAuthStatus(c *gin.Context) {
+	gmailService, ok := h.gmailService(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"mailbox":    gmailService.UserEmail(),
+			"auth_mode":  gmailService.AuthMode(),
+			"authorized": gmailService.Authorized(),
+		},
+	})
+}
+
+// StartGoogleAuth 发起Google授权，返回需要在浏览器中打开的授权链接，mailbox_id 指定数据库中的邮箱
+func (h *OAuthHandler) StartGoogleAuth(c *gin.Context) {
+	gmailService, ok := h.gmailService(c)
+	if !ok {
+		return
+	}
+
+	redirectURL := h.redirectURL
+	if redirectURL == "" {
+		redirectURL = requestScheme(c) + "://" + c.Request.Host + GoogleCallbackPath
+	}
+
+	authURL, err := gmailService.StartAuthorization(redirectURL)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请在浏览器中打开授权链接，10分钟内有效",
+		"data": gin.H{
+			"mailbox":      gmailService.UserEmail(),
+			"auth_url":     authURL,
+			"redirect_url": redirectURL,
+		},
+	})
+}
+
+// GoogleCallback Google授权完成后的回调，通过state校验请求来自 StartGoogleAuth 发起的授权，因此不需要API密钥
+func (h *OAuthHandler) GoogleCallback(c *gin.Context) {
+	if reason := c.Query("error"); reason != "" {
+		invalidRequest(c, "授权未完成: %s", reason)
+		return
+	}
+
+	err := h.mailboxService.CompleteAuthorization(c.Request.Context(), c.Query("state"), c.Query("code"))
+	if err != nil {
+		utils.LoggerFromContext(c.Request.Context()).Errorf("Google授权失败: %v", err)
+		respondError(c, err)
+		return
+	}
+
+	utils.LoggerFromContext(c.Request.Context()).Info("Google授权成功，Gmail服务已启用")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "授权成功，Gmail服务已启用，可以关闭此页面",
+	})
+}
+
+// requestScheme 判断请求使用的协议，支持反向代理设置的 X-Forwarded-Proto
+func requestScheme(c *gin.Context) string {
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		return proto
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIPath OpenAPI文档（JSON格式）的访问路径
+const OpenAPIPath = "/api/v1/openapi.json"
+
+// SwaggerUIPath Swagger UI页面，内嵌在管理界面的静态文件中
+const SwaggerUIPath = "/ui/docs.html"
+
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+// NewOpenAPIHandler 创建OpenAPI文档处理器，spec为JSON格式的文档
+func NewOpenAPIHandler(spec []byte) *OpenAPIHandler {
+	return &OpenAPIHandler{
+		spec: spec,
+	}
+}
+
+// GetOpenAPISpec 返回OpenAPI文档，不需要认证
+func (h *OpenAPIHandler) GetOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// Docs 跳转到Swagger UI页面
+func (h *OpenAPIHandler) Docs(c *gin.Context) {
+	c.Redirect(http.StatusFound, SwaggerUIPath)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"email-forwarding/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReloadHandler struct {
+	reloadService *services.ReloadService
+}
+
+// NewReloadHandler 创建热加载处理器
+func NewReloadHandler(reloadService *services.ReloadService) *ReloadHandler {
+	return &ReloadHandler{
+		reloadService: reloadService,
+	}
+}
+
+// Reload 重新加载配置和转发目标，新配置无效时返回422并保持原配置
+func (h *ReloadHandler) Reload(c *gin.Context) {
+	result, err := h.reloadService.Reload(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "重新加载成功",
+		"data":    result,
+	})
+}
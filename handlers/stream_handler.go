@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"email-forwarding/services"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamBuffer 每个客户端缓冲的事件数，客户端消费过慢时超出的事件被丢弃
+const streamBuffer = 256
+
+// streamHeartbeat 没有事件时发送心跳注释的间隔，防止代理断开空闲连接
+const streamHeartbeat = 15 * time.Second
+
+// streamRetry 建议客户端断线后的重连间隔
+const streamRetry = 3 * time.Second
+
+type StreamHandler struct {
+	bus *services.EventBus
+}
+
+// NewStreamHandler 创建实时事件流处理器
+func NewStreamHandler(bus *services.EventBus) *StreamHandler {
+	return &StreamHandler{
+		bus: bus,
+	}
+}
+
+// StreamEvents 以Server-Sent Events推送当前租户的实时事件
+// 支持按 type、target、status 筛选（均可用逗号分隔多个值），
+// 断线重连时根据 Last-Event-ID 请求头（或 last_event_id 参数）补发最近的事件
+func (h *StreamHandler) StreamEvents(c *gin.Context) {
+	filter := services.LiveFilter{
+		Types:    splitQuery(c.Query("type")),
+		Targets:  splitQuery(c.Query("target")),
+		Statuses: splitQuery(c.Query("status")),
+	}
+	for _, t := range filter.Types {
+		if !isLiveEventType(t) {
+			invalidRequest(c, "未知的事件类型 %s，可选值: %s", t, strings.Join(services.LiveEventTypes, ", "))
+			return
+		}
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			invalidRequest(c, "无效的Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	ctx := c.Request.Context()
+	sub, err := h.bus.Subscribe(ctx, filter, streamBuffer, lastID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// retry 告诉客户端断线后多久重连
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry.Milliseconds())
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events():
+			if !ok {
+				// 服务关闭，客户端按 retry 间隔重连
+				return
+			}
+			if dropped := sub.TakeDropped(); dropped > 0 {
+				writeSSE(c.Writer, 0, "dropped", gin.H{"dropped": dropped})
+			}
+			if err := writeSSE(c.Writer, e.ID, e.Type, e); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeSSE 写出一条SSE消息，id为0时不写id字段
+func writeSSE(w io.Writer, id uint64, event string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if id > 0 {
+		fmt.Fprintf(&b, "id: %d\n", id)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", event, body)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// splitQuery 按逗号拆分查询参数，忽略空值
+func splitQuery(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// isLiveEventType 判断是否为已知的实时事件类型
+func isLiveEventType(eventType string) bool {
+	for _, t := range services.LiveEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
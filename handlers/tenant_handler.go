@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"email-forwarding/models"
+	"email-forwarding/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TenantHandler struct {
+	tenantService *services.TenantService
+}
+
+// NewTenantHandler 创建租户处理器
+func NewTenantHandler(tenantService *services.TenantService) *TenantHandler {
+	return &TenantHandler{
+		tenantService: tenantService,
+	}
+}
+
+// GetTenants 获取租户列表
+func (h *TenantHandler) GetTenants(c *gin.Context) {
+	tenants, err := h.tenantService.GetTenants()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tenants,
+	})
+}
+
+// GetTenant 获取单个租户
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	tenant, err := h.tenantService.GetTenant(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tenant,
+	})
+}
+
+// CreateTenant 创建租户，is_active未提供时默认启用
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	var req services.TenantPatch
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tenant := models.Tenant{IsActive: true}
+	if req.Name != nil {
+		tenant.Name = *req.Name
+	}
+	for dst, value := range map[*int]*int{
+		&tenant.MaxMailboxes:     req.MaxMailboxes,
+		&tenant.MaxTargets:       req.MaxTargets,
+		&tenant.MaxDailyForwards: req.MaxDailyForwards,
+	} {
+		if value != nil {
+			*dst = *value
+		}
+	}
+	if req.IsActive != nil {
+		tenant.IsActive = *req.IsActive
+	}
+
+	if err := h.tenantService.CreateTenant(c.Request.Context(), &tenant); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "创建成功",
+		"data":    tenant,
+	})
+}
+
+// PatchTenant 部分更新租户，只修改请求中出现的字段
+func (h *TenantHandler) PatchTenant(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var patch services.TenantPatch
+	if !bindJSON(c, &patch) {
+		return
+	}
+
+	updated, err := h.tenantService.PatchTenant(c.Request.Context(), id, patch)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"data":    updated,
+	})
+}
+
+// GetTenantStats 获取所有租户的用量统计
+func (h *TenantHandler) GetTenantStats(c *gin.Context) {
+	stats, err := h.tenantService.GetTenantStats()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": stats,
+	})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"email-forwarding/models"
+	"email-forwarding/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	eventService *services.EventService
+}
+
+// NewWebhookHandler 创建事件订阅处理器
+func NewWebhookHandler(eventService *services.EventService) *WebhookHandler {
+	return &WebhookHandler{
+		eventService: eventService,
+	}
+}
+
+// GetSubscriptions 获取事件订阅列表
+func (h *WebhookHandler) GetSubscriptions(c *gin.Context) {
+	subs, err := h.eventService.GetSubscriptions(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   subs,
+		"events": models.Events,
+	})
+}
+
+// GetSubscription 获取单个事件订阅
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.eventService.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": sub,
+	})
+}
+
+// CreateSubscription 创建事件订阅，is_active未提供时默认启用
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req services.SubscriptionPatch
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sub := models.WebhookSubscription{IsActive: true}
+	for dst, value := range map[*string]*string{
+		&sub.Name:   req.Name,
+		&sub.URL:    req.URL,
+		&sub.Secret: req.Secret,
+		&sub.Events: req.Events,
+	} {
+		if value != nil {
+			*dst = *value
+		}
+	}
+	if req.IsActive != nil {
+		sub.IsActive = *req.IsActive
+	}
+
+	if err := h.eventService.CreateSubscription(c.Request.Context(), &sub); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "创建成功",
+		"data":    sub,
+	})
+}
+
+// PatchSubscription 部分更新事件订阅，只修改请求中出现的字段
+func (h *WebhookHandler) PatchSubscription(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var patch services.SubscriptionPatch
+	if !bindJSON(c, &patch) {
+		return
+	}
+
+	sub, err := h.eventService.PatchSubscription(c.Request.Context(), id, patch)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"data":    sub,
+	})
+}
+
+// DeleteSubscription 删除事件订阅
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.eventService.DeleteSubscription(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// GetDeliveries 获取事件订阅的推送记录，支持按 event、status 筛选
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := services.EventDeliveryFilter{
+		Event:  c.Query("event"),
+		Status: c.Query("status"),
+	}
+
+	deliveries, total, err := h.eventService.GetDeliveries(c.Request.Context(), id, filter, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+		"pagination": gin.H{
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Redeliver 重新推送一条推送记录，返回新的推送记录
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil || deliveryID == 0 {
+		invalidRequest(c, "无效的推送记录ID")
+		return
+	}
+
+	delivery, err := h.eventService.Redeliver(c.Request.Context(), id, uint(deliveryID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已重新推送",
+		"data":    delivery,
+	})
+}
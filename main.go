@@ -1,121 +1,394 @@
 package main
 
 import (
+	"context"
+	"email-forwarding/api"
 	"email-forwarding/config"
 	"email-forwarding/database"
 	"email-forwarding/handlers"
+	"email-forwarding/middleware"
+	"email-forwarding/models"
 	"email-forwarding/services"
 	"email-forwarding/utils"
+	"email-forwarding/web"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
 	// 加载环境变量
-	if err := godotenv.Load(); err != nil {
-		log.Println("没有找到.env文件，使用默认配置")
+	envErr := godotenv.Load()
+
+	// config check 只校验配置，不连接数据库
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfigCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 加载配置
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效:\n%v\n", err)
+		os.Exit(1)
 	}
 
 	// 初始化日志
-	utils.InitLogger()
+	if err := utils.InitLogger(cfg.Log.Level, cfg.Log.Format, cfg.Log.Output); err != nil {
+		log.Fatalf("日志初始化失败: %v", err)
+	}
 	logger := utils.GetLogger()
+	if envErr != nil {
+		logger.Info("没有找到.env文件，使用默认配置")
+	}
 
-	// 加载配置
-	cfg := config.LoadConfig()
+	// 初始化链路追踪
+	shutdownTracing, err := utils.InitTracing(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.SampleRatio)
+	if err != nil {
+		logger.Fatalf("链路追踪初始化失败: %v", err)
+	}
+
+	// auth google 只需要Gmail凭据，不连接数据库
+	if len(os.Args) > 1 && os.Args[1] == "auth" {
+		if err := runAuthCommand(cfg, os.Args[2:]); err != nil {
+			logger.Fatalf("授权失败: %v", err)
+		}
+		return
+	}
 
 	// 初始化数据库
 	if err := database.InitDatabase(cfg); err != nil {
 		logger.Fatalf("数据库初始化失败: %v", err)
 	}
 
+	// 命令行子命令（如 apikey create），执行完直接退出
+	if len(os.Args) > 1 {
+		err := runCLI(os.Args[1:])
+		database.Close()
+		if err != nil {
+			logger.Fatalf("命令执行失败: %v", err)
+		}
+		return
+	}
+
 	// 创建默认转发目标
 	if err := database.CreateDefaultForwardTargets(); err != nil {
 		logger.Errorf("创建默认转发目标失败: %v", err)
 	}
 
-	// 设置代理（如果需要）
-	// 在这里设置您的代理地址，例如：
-	services.SetProxy("http://127.0.0.1:10810")  // 本地代理
-	
+	// 代理配置，访问Google的所有请求都会使用
+	proxy, err := cfg.Proxy.ProxyFunc()
+	if err != nil {
+		logger.Fatalf("代理配置无效: %v", err)
+	}
+	logger.Infof("代理: %s", cfg.Proxy)
+
 	// 初始化Gmail服务
-	gmailService, err := services.NewGmailService(
-		cfg.Gmail.CredentialsFile,
-		cfg.Gmail.TokenFile,
-		cfg.Gmail.UserEmail,
-	)
+	gmailService, err := services.NewGmailServiceFromConfig(cfg.Gmail, proxy)
 	if err != nil {
 		logger.Fatalf("Gmail服务初始化失败: %v", err)
 	}
+	logger.Infof("Gmail认证方式: %s", gmailService.AuthMode())
+	if !gmailService.Authorized() {
+		logger.Warn("Gmail尚未授权，服务以降级模式启动：请调用 GET /api/v1/auth/google/start 或执行 go run . auth google 完成授权")
+	}
+	gmailService.SetFetchOptions(cfg.Gmail.Query, cfg.App.MaxEmailsPerBatch, cfg.App.MaxBatches)
 
 	// 初始化邮件服务
-	emailService := services.NewEmailService(gmailService)
+	emailService := services.NewEmailService(gmailService, cfg.App.Keywords)
+	emailService.SetRetryPolicy(cfg.App.MaxAttempts, cfg.App.RetryBackoff)
+	emailService.SetPublicURL(cfg.Server.PublicURL)
+
+	// 初始化事件订阅服务，处理邮件和修改转发目标时在后台推送事件
+	eventService := services.NewEventService()
+	emailService.SetEventService(eventService)
+
+	// 初始化认证服务
+	authService := services.NewAuthService(cfg.Auth.JWTSecret)
+	if !cfg.Auth.Enabled {
+		logger.Warn("API认证已关闭，所有接口均可匿名访问")
+	} else if count, err := authService.CountAPIKeys(); err == nil && count == 0 && cfg.Auth.JWTSecret == "" {
+		logger.Warn("尚未创建任何API密钥，请先执行: go run . apikey create -name admin -role admin")
+	}
+
+	// 监听退出信号
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// 启动定时任务
-	go startScheduler(emailService, cfg.App.CheckInterval)
+	scheduler := services.NewScheduler(emailService, cfg.App.CheckInterval)
+	go scheduler.Run(ctx)
+
+	// 启动数据库中其他邮箱的定时任务
+	mailboxService := services.NewMailboxService(cfg, emailService, proxy)
+	mailboxService.SetDefaultScheduler(scheduler)
+	if err := mailboxService.Start(ctx); err != nil {
+		logger.Errorf("启动邮箱定时任务失败: %v", err)
+	}
+
+	// 配置热加载：收到SIGHUP或调用 /api/v1/admin/reload 时重新加载配置和转发目标
+	reloadService := services.NewReloadService(cfg, emailService, gmailService, scheduler, mailboxService)
+	go watchReload(ctx, reloadService)
 
 	// 设置Gin模式
 	gin.SetMode(cfg.Server.Mode)
 
 	// 创建路由
-	router := setupRoutes(emailService)
+	healthService := services.NewHealthService(gmailService, scheduler, mailboxService)
+	router := setupRoutes(cfg, emailService, mailboxService, authService, healthService, reloadService, eventService)
 
 	// 启动服务器
-	logger.Infof("服务器启动在端口 %s", cfg.Server.Port)
-	if err := router.Run(":" + cfg.Server.Port); err != nil {
-		logger.Fatalf("服务器启动失败: %v", err)
+	server := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: router,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Infof("服务器启动在端口 %s", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Info("收到退出信号，开始优雅关闭...")
+	case err := <-serverErr:
+		logger.Errorf("服务器启动失败: %v", err)
+	}
+	stop()
+
+	shutdown(server, emailService, eventService, scheduler, mailboxService, shutdownTracing, cfg.Server.ShutdownTimeout)
+}
+
+// shutdown 按顺序关闭各组件：停止接受新任务并等待处理中的邮件完成，推送完已产生的事件，再关闭HTTP服务器和数据库
+func shutdown(server *http.Server, emailService *services.EmailService, eventService *services.EventService, scheduler *services.Scheduler, mailboxService *services.MailboxService, shutdownTracing func(context.Context) error, timeout time.Duration) {
+	logger := utils.GetLogger()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := emailService.Shutdown(ctx); err != nil {
+		logger.Errorf("等待邮件处理完成超时: %v", err)
+	}
+
+	// 关闭实时事件流，否则HTTP服务器会一直等待这些长连接
+	emailService.EventBus().Close()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Errorf("HTTP服务器关闭失败: %v", err)
+	}
+
+	// 定时任务和HTTP请求都已结束，不会再产生新事件
+	if err := eventService.Shutdown(ctx); err != nil {
+		logger.Errorf("等待事件推送完成超时: %v", err)
+	}
+
+	select {
+	case <-scheduler.Done():
+	case <-ctx.Done():
+		logger.Warn("等待定时任务退出超时")
+	}
+	if err := mailboxService.Wait(ctx); err != nil {
+		logger.Warn("等待邮箱定时任务退出超时")
+	}
+
+	if err := database.Close(); err != nil {
+		logger.Errorf("关闭数据库连接失败: %v", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Errorf("刷新链路追踪数据失败: %v", err)
+	}
+
+	logger.Info("服务已关闭")
+}
+
+// watchReload 收到SIGHUP时重新加载配置，加载失败时继续使用原配置
+func watchReload(ctx context.Context, reloadService *services.ReloadService) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	reloadCtx := services.ContextWithPrincipal(ctx, &services.Principal{Name: "sighup"})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			utils.GetLogger().Info("收到SIGHUP，重新加载配置...")
+			if _, err := reloadService.Reload(reloadCtx); err != nil {
+				utils.GetLogger().Errorf("重新加载失败，继续使用原配置: %v", err)
+			}
+		}
 	}
 }
 
 // setupRoutes 设置路由
-func setupRoutes(emailService *services.EmailService) *gin.Engine {
-	router := gin.Default()
+func setupRoutes(cfg *config.Config, emailService *services.EmailService, mailboxService *services.MailboxService, authService *services.AuthService, healthService *services.HealthService, reloadService *services.ReloadService, eventService *services.EventService) *gin.Engine {
+	spec, err := api.Spec()
+	if err != nil {
+		utils.GetLogger().Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(otelgin.Middleware(utils.ServiceName), middleware.RequestID(), middleware.AccessLog(), middleware.Recovery())
+	// 未注册的路由和不支持的请求方法也返回统一格式的错误
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(middleware.NotFound)
+	router.NoMethod(middleware.MethodNotAllowed)
 
 	// 创建处理器
 	emailHandler := handlers.NewEmailHandler(emailService)
+	authHandler := handlers.NewAuthHandler(authService)
+	auditHandler := handlers.NewAuditHandler(services.NewAuditService())
+	configHandler := handlers.NewConfigHandler(services.NewConfigService(emailService))
+	healthHandler := handlers.NewHealthHandler(healthService)
+	reloadHandler := handlers.NewReloadHandler(reloadService)
+	oauthHandler := handlers.NewOAuthHandler(mailboxService, cfg.Gmail.RedirectURL)
+	mailboxHandler := handlers.NewMailboxHandler(mailboxService)
+	tenantHandler := handlers.NewTenantHandler(services.NewTenantService())
+	webhookHandler := handlers.NewWebhookHandler(eventService)
+	streamHandler := handlers.NewStreamHandler(emailService.EventBus())
+	openapiHandler := handlers.NewOpenAPIHandler(spec)
 
 	// 添加CORS中间件
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	router.Use(middleware.CORS(cfg.Server.CORSOrigins))
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
+	viewer := middleware.RequireRole(models.RoleViewer)
+	operator := middleware.RequireRole(models.RoleOperator)
+	admin := middleware.RequireRole(models.RoleAdmin)
+	superAdmin := middleware.RequireRole(models.RoleSuperAdmin)
 
 	// API路由组
 	api := router.Group("/api/v1")
+	api.Use(middleware.Auth(authService, cfg.Auth.Enabled))
 	{
 		// 邮件处理相关
-		api.POST("/emails/process", emailHandler.ProcessEmails)
-		api.GET("/emails/logs", emailHandler.GetEmailLogs)
-		api.GET("/stats", emailHandler.GetStats)
+		api.POST("/emails/process", operator, emailHandler.ProcessEmails)
+		api.GET("/emails/logs", viewer, emailHandler.GetEmailLogs)
+		api.GET("/emails/logs/export", viewer, emailHandler.ExportEmailLogs)
+		api.GET("/emails/logs/:id", viewer, emailHandler.GetEmailLog)
+		api.GET("/emails/messages/:message_id/attachments/:part_id", viewer, mailboxHandler.GetAttachment)
+		api.GET("/stats", viewer, emailHandler.GetStats)
+		api.GET("/events", viewer, streamHandler.StreamEvents)
+		api.POST("/rules/test", viewer, emailHandler.TestRule)
+
+		// 定时任务，mailbox_id为0表示默认邮箱
+		schedulers := api.Group("/schedulers")
+		{
+			schedulers.GET("", viewer, mailboxHandler.GetSchedulers)
+			schedulers.POST("/:mailbox_id/pause", operator, mailboxHandler.PauseScheduler)
+			schedulers.POST("/:mailbox_id/resume", operator, mailboxHandler.ResumeScheduler)
+		}
 
 		// 转发目标管理
 		targets := api.Group("/targets")
 		{
-			targets.GET("", emailHandler.GetForwardTargets)
-			targets.POST("", emailHandler.CreateForwardTarget)
-			targets.PUT("/:id", emailHandler.UpdateForwardTarget)
-			targets.DELETE("/:id", emailHandler.DeleteForwardTarget)
+			targets.GET("", viewer, emailHandler.GetForwardTargets)
+			targets.GET("/:id", viewer, emailHandler.GetForwardTarget)
+			targets.POST("", operator, emailHandler.CreateForwardTarget)
+			targets.PUT("/:id", operator, emailHandler.UpdateForwardTarget)
+			targets.PATCH("/:id", operator, emailHandler.PatchForwardTarget)
+			targets.POST("/:id/activate", operator, emailHandler.ActivateForwardTarget)
+			targets.POST("/:id/deactivate", operator, emailHandler.DeactivateForwardTarget)
+			targets.DELETE("/:id", admin, emailHandler.DeleteForwardTarget)
 		}
+
+		// 邮箱管理，凭据文件路径等属于敏感配置，只允许admin修改
+		mailboxes := api.Group("/mailboxes")
+		{
+			mailboxes.GET("", viewer, mailboxHandler.GetMailboxes)
+			mailboxes.GET("/:id", viewer, mailboxHandler.GetMailbox)
+			mailboxes.POST("", admin, mailboxHandler.CreateMailbox)
+			mailboxes.PATCH("/:id", admin, mailboxHandler.PatchMailbox)
+			mailboxes.DELETE("/:id", admin, mailboxHandler.DeleteMailbox)
+			mailboxes.POST("/:id/process", operator, mailboxHandler.ProcessMailbox)
+		}
+
+		// 转发配置导入导出
+		configs := api.Group("/config")
+		{
+			configs.GET("/export", viewer, configHandler.ExportConfig)
+			configs.POST("/import", admin, configHandler.ImportConfig)
+		}
+
+		// 事件订阅，事件中包含邮件内容摘要，只允许admin管理
+		webhooks := api.Group("/webhooks", admin)
+		{
+			webhooks.GET("", webhookHandler.GetSubscriptions)
+			webhooks.GET("/:id", webhookHandler.GetSubscription)
+			webhooks.POST("", webhookHandler.CreateSubscription)
+			webhooks.PATCH("/:id", webhookHandler.PatchSubscription)
+			webhooks.DELETE("/:id", webhookHandler.DeleteSubscription)
+			webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+		}
+
+		// API密钥管理
+		keys := api.Group("/api-keys", admin)
+		{
+			keys.GET("", authHandler.GetAPIKeys)
+			keys.POST("", authHandler.CreateAPIKey)
+			keys.DELETE("/:id", authHandler.RevokeAPIKey)
+		}
+
+		// 审计记录
+		audit := api.Group("/audit", admin)
+		{
+			audit.GET("", auditHandler.GetAuditLogs)
+			audit.POST("/:id/restore", auditHandler.RestoreFromAudit)
+		}
+
+		// 租户管理和跨租户统计
+		tenants := api.Group("/tenants", superAdmin)
+		{
+			tenants.GET("", tenantHandler.GetTenants)
+			tenants.GET("/stats", tenantHandler.GetTenantStats)
+			tenants.GET("/:id", tenantHandler.GetTenant)
+			tenants.POST("", tenantHandler.CreateTenant)
+			tenants.PATCH("/:id", tenantHandler.PatchTenant)
+		}
+
+		// 热加载配置和转发目标，配置文件对所有租户生效，只允许超级管理员操作
+		api.POST("/admin/reload", superAdmin, reloadHandler.Reload)
+
+		// Gmail授权
+		api.GET("/auth/google/status", viewer, oauthHandler.GetGoogleAuthStatus)
+		api.GET("/auth/google/start", admin, oauthHandler.StartGoogleAuth)
 	}
 
-	// 健康检查
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-			"message": "邮件转发系统运行正常",
-			"timestamp": time.Now().Unix(),
-		})
-	})
+	// Google授权回调由浏览器跳转而来，无法携带API密钥，通过state参数校验
+	router.GET(handlers.GoogleCallbackPath, oauthHandler.GoogleCallback)
+
+	// OpenAPI文档和Swagger UI，不需要认证
+	router.GET(handlers.OpenAPIPath, openapiHandler.GetOpenAPISpec)
+	router.GET("/docs", openapiHandler.Docs)
+
+	// 健康检查：/healthz 存活检查，/readyz 就绪检查（/health 保留为 /readyz 的别名）
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+	router.GET("/health", healthHandler.Readiness)
+
+	// 管理界面，静态文件内嵌在程序中，数据通过 /api/v1 接口获取
+	router.StaticFS("/ui", http.FS(web.FS()))
+
+	// Prometheus指标
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// 首页
 	router.GET("/", func(c *gin.Context) {
@@ -123,36 +396,30 @@ func setupRoutes(emailService *services.EmailService) *gin.Engine {
 			"message": "欢迎使用邮件转发系统",
 			"version": "1.0.0",
 			"endpoints": gin.H{
-				"health": "/health",
+				"health": "/healthz",
+				"ready": "/readyz",
+				"metrics": "/metrics",
 				"api": "/api/v1",
+				"ui": "/ui/",
+				"openapi": "/api/v1/openapi.json",
+				"docs": "/docs",
 				"process_emails": "/api/v1/emails/process",
 				"email_logs": "/api/v1/emails/logs",
+				"email_logs_export": "/api/v1/emails/logs/export",
 				"targets": "/api/v1/targets",
+				"mailboxes": "/api/v1/mailboxes",
+				"api_keys": "/api/v1/api-keys",
+				"audit": "/api/v1/audit",
+				"tenants": "/api/v1/tenants",
+				"webhooks": "/api/v1/webhooks",
+				"events": "/api/v1/events",
+				"config_export": "/api/v1/config/export",
+				"config_import": "/api/v1/config/import",
+				"reload": "/api/v1/admin/reload",
+				"google_auth": "/api/v1/auth/google/start",
 			},
 		})
 	})
 
 	return router
 }
-
-// startScheduler 启动定时任务
-func startScheduler(emailService *services.EmailService, interval time.Duration) {
-	logger := utils.GetLogger()
-	logger.Infof("定时任务已启动，检查间隔: %v", interval)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			logger.Info("开始定时检查邮件...")
-			
-			if err := emailService.ProcessEmails(); err != nil {
-				logger.Errorf("定时处理邮件失败: %v", err)
-			} else {
-				logger.Info("定时邮件检查完成")
-			}
-		}
-	}
-}
\ No newline at end of file
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"email-forwarding/api"
+	"email-forwarding/config"
+	"email-forwarding/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPIMatchesRoutes 接口文档与实际注册的路由一致，operationId 与处理函数同名
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	emailService := services.NewEmailService(nil, nil)
+	mailboxService := services.NewMailboxService(cfg, emailService, nil)
+	eventService := services.NewEventService()
+	defer eventService.Shutdown(context.Background())
+
+	router := setupRoutes(cfg, emailService, mailboxService, services.NewAuthService("test"),
+		services.NewHealthService(nil, nil, mailboxService),
+		services.NewReloadService(cfg, emailService, nil, nil, mailboxService),
+		eventService)
+
+	if err := api.CheckRoutes(router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"email-forwarding/models"
+	"email-forwarding/services"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey gin上下文中保存调用方信息的键
+const principalKey = "principal"
+
+// TenantHeader 超级管理员用来指定要操作的租户的请求头
+const TenantHeader = "X-Tenant-ID"
+
+// Auth 认证中间件，支持 X-API-Key 头和 Authorization: Bearer <API密钥或JWT>
+// enabled为false时所有请求按超级管理员处理
+func Auth(authService *services.AuthService, enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			principal := &services.Principal{Name: "anonymous", Role: models.RoleSuperAdmin, AllTenants: true}
+			if selectTenant(c, authService, principal) {
+				setPrincipal(c, principal)
+				c.Next()
+			}
+			return
+		}
+
+		token := c.GetHeader("X-API-Key")
+		if token == "" {
+			if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				token = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+
+		principal, err := authService.Authenticate(token)
+		if err != nil {
+			AbortWithError(c, fmt.Errorf("%w: 请提供有效的API密钥或访问令牌", services.ErrUnauthorized))
+			return
+		}
+
+		if !selectTenant(c, authService, principal) {
+			return
+		}
+
+		setPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// selectTenant 按 X-Tenant-ID 请求头选择租户，只有超级管理员可以指定其他租户，失败时直接返回错误响应
+func selectTenant(c *gin.Context, authService *services.AuthService, principal *services.Principal) bool {
+	header := c.GetHeader(TenantHeader)
+	if header == "" {
+		return true
+	}
+
+	tenantID, err := strconv.ParseUint(header, 10, 32)
+	if err != nil || tenantID == 0 {
+		AbortWithError(c, fmt.Errorf("%w: 无效的租户ID %q", services.ErrInvalidRequest, header))
+		return false
+	}
+
+	if err := authService.SelectTenant(principal, uint(tenantID)); err != nil {
+		AbortWithError(c, err)
+		return false
+	}
+	return true
+}
+
+// setPrincipal 将调用方信息同时保存到gin上下文和请求context，供服务层记录操作人
+func setPrincipal(c *gin.Context, principal *services.Principal) {
+	c.Set(principalKey, principal)
+	c.Request = c.Request.WithContext(services.ContextWithPrincipal(c.Request.Context(), principal))
+}
+
+// RequireRole 要求调用方至少具备指定角色
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		if principal == nil || !models.RoleAllows(principal.Role, role) {
+			AbortWithError(c, fmt.Errorf("%w: 该操作需要 %s 角色", services.ErrForbidden, role))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetPrincipal 获取当前请求的调用方，未认证时返回nil
+func GetPrincipal(c *gin.Context) *services.Principal {
+	if v, ok := c.Get(principalKey); ok {
+		if principal, ok := v.(*services.Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"email-forwarding/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrorResponse 所有接口统一的错误响应
+type ErrorResponse struct {
+	Code      string       `json:"code"`                 // 机器可读的错误码
+	Error     string       `json:"error"`                // 按 Accept-Language 本地化的错误说明
+	Message   string       `json:"message,omitempty"`    // 具体原因，没有或内部错误时不返回
+	Details   []FieldError `json:"details,omitempty"`    // 请求参数校验失败的字段
+	RequestID string       `json:"request_id,omitempty"` // 与访问日志对应的请求ID
+}
+
+// FieldError 请求参数校验失败的字段
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"` // 未通过的校验规则，例如 required；类型不匹配时为 type
+}
+
+func init() {
+	// 校验失败时使用JSON字段名，与请求体保持一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// AbortWithError 按错误类别中止请求并返回统一格式的错误响应
+// 只有显式用业务错误包装时才返回具体原因；内部错误和按类型归类的数据库、上游服务错误
+// 可能包含SQL、上游地址等信息，只返回错误类别的说明，完整错误记录在访问日志中，可按请求ID查找
+func AbortWithError(c *gin.Context, err error) {
+	e := services.AsError(err)
+	lang := Language(c)
+
+	resp := ErrorResponse{
+		Code:      e.Code,
+		Error:     e.Localize(lang),
+		Details:   fieldErrors(err),
+		RequestID: c.Writer.Header().Get(RequestIDHeader),
+	}
+	var wrapped *services.Error
+	if e.Kind != services.KindInternal && errors.As(err, &wrapped) {
+		resp.Message = e.Detail(err)
+	}
+
+	_ = c.Error(err)
+	c.AbortWithStatusJSON(e.Kind.HTTPStatus(), resp)
+}
+
+// Language 按 Accept-Language 请求头选择错误说明的语言，支持 zh（默认）和 en
+func Language(c *gin.Context) string {
+	for _, tag := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), ";")
+		switch {
+		case strings.HasPrefix(strings.ToLower(tag), "zh"):
+			return "zh"
+		case strings.HasPrefix(strings.ToLower(tag), "en"):
+			return "en"
+		}
+	}
+	return "zh"
+}
+
+// fieldErrors 从请求体绑定错误中取出校验失败的字段
+func fieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{Field: fe.Field(), Rule: fe.Tag()})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{Field: typeErr.Field, Rule: "type"}}
+	}
+	return nil
+}
+
+// Recovery 捕获处理请求时的panic，返回统一格式的内部错误
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		AbortWithError(c, fmt.Errorf("%w: panic: %v", services.ErrInternal, recovered))
+	})
+}
+
+// NotFound 处理未注册的路由
+func NotFound(c *gin.Context) {
+	AbortWithError(c, fmt.Errorf("%w: %s", services.ErrNotFound, c.Request.URL.Path))
+}
+
+// MethodNotAllowed 处理路由存在但请求方法不支持的请求
+func MethodNotAllowed(c *gin.Context) {
+	AbortWithError(c, fmt.Errorf("%w: %s %s", services.ErrMethodNotAllowed, c.Request.Method, c.Request.URL.Path))
+}
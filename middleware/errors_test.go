@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"email-forwarding/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/googleapi"
+	"gorm.io/gorm"
+)
+
+func TestAbortWithErrorMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"业务错误返回具体原因", fmt.Errorf("%w: %d", services.ErrNotFound, 42), http.StatusNotFound, "not_found", "42"},
+		{"业务错误附带原因", fmt.Errorf("%w: %v", services.ErrDuplicate, "ops"), http.StatusConflict, "duplicate", "ops"},
+		{"唯一索引冲突", fmt.Errorf("创建转发目标失败: %w: Error 1062: Duplicate entry 'ops@example.com' for key 'idx_email'", gorm.ErrDuplicatedKey), http.StatusConflict, "duplicate", ""},
+		{"记录不存在", fmt.Errorf("查询失败: %w", gorm.ErrRecordNotFound), http.StatusNotFound, "not_found", ""},
+		{"Gmail API错误", fmt.Errorf("读取邮件失败: %w", &googleapi.Error{Code: 500, Message: "backend error"}), http.StatusBadGateway, "upstream_unavailable", ""},
+		{"上游请求失败", &url.Error{Op: "Post", URL: "https://oauth2.googleapis.com/token?secret=x", Err: errors.New("connection refused")}, http.StatusBadGateway, "upstream_unavailable", ""},
+		{"内部错误", fmt.Errorf("%w: panic: boom", services.ErrInternal), http.StatusInternalServerError, "internal_error", ""},
+		{"无法识别的错误", errors.New("dial tcp 10.0.0.1:3306: connect: connection refused"), http.StatusInternalServerError, "internal_error", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+			AbortWithError(c, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("状态码 = %d, 期望 %d", w.Code, tt.wantStatus)
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != tt.wantCode || resp.Message != tt.wantMessage {
+				t.Errorf("code = %q, message = %q, 期望 %q, %q", resp.Code, resp.Message, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"email-forwarding/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader 请求ID的HTTP头
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID 为每个请求分配请求ID，优先沿用调用方传入的X-Request-ID
+// 请求ID会写入响应头，并和链路追踪ID一起附加到请求context的日志字段中
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = utils.NewID()
+		}
+
+		c.Header(RequestIDHeader, requestID)
+		fields := logrus.Fields{"request_id": requestID}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			fields["trace_id"] = sc.TraceID().String()
+		}
+		ctx := utils.ContextWithLogFields(c.Request.Context(), fields)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// AccessLog 通过统一的日志输出访问日志，替代gin默认的文本日志
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := utils.LoggerFromContext(c.Request.Context()).WithFields(logrus.Fields{
+			"method":      c.Request.Method,
+			"path":        c.Request.URL.Path,
+			"status":      c.Writer.Status(),
+			"duration_ms": time.Since(start).Milliseconds(),
+			"client_ip":   c.ClientIP(),
+		})
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", c.Errors.String())
+		}
+
+		switch {
+		case c.Writer.Status() >= 500:
+			entry.Error("HTTP请求")
+		case c.Writer.Status() >= 400:
+			entry.Warn("HTTP请求")
+		default:
+			entry.Info("HTTP请求")
+		}
+	}
+}
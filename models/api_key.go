@@ -0,0 +1,57 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// APIKey API密钥表，只保存密钥的哈希值
+type APIKey struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	TenantID   uint           `gorm:"not null;default:1;index" json:"tenant_id"`     // 所属租户
+	Name       string         `gorm:"size:100;not null" json:"name"`                 // 密钥名称，用作操作人标识
+	Prefix     string         `gorm:"size:20;not null;index" json:"prefix"`          // 密钥前缀，便于识别
+	KeyHash    string         `gorm:"size:64;not null;uniqueIndex" json:"-"`         // 密钥的SHA-256哈希
+	Role       string         `gorm:"size:20;not null;default:'viewer'" json:"role"` // 角色：viewer/operator/admin
+	ExpiresAt  *time.Time     `json:"expires_at"`                                    // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time     `json:"last_used_at"`                                  // 最后使用时间
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// 角色常量，权限依次递增
+// viewer/operator/admin 只能访问所属租户的数据，superadmin 可以跨租户访问并管理租户
+const (
+	RoleViewer     = "viewer"
+	RoleOperator   = "operator"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "superadmin"
+)
+
+// roleLevels 角色权限等级
+var roleLevels = map[string]int{
+	RoleViewer:     1,
+	RoleOperator:   2,
+	RoleAdmin:      3,
+	RoleSuperAdmin: 4,
+}
+
+// IsValidRole 检查角色是否有效
+func IsValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// RoleAllows 检查角色role是否具备required角色的权限
+func RoleAllows(role, required string) bool {
+	level, ok := roleLevels[role]
+	if !ok {
+		return false
+	}
+	return level >= roleLevels[required]
+}
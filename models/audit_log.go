@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog 配置变更审计记录表
+type AuditLog struct {
+	ID         uint            `gorm:"primarykey" json:"id"`
+	TenantID   uint            `gorm:"not null;default:1;index" json:"tenant_id"`                  // 所属租户
+	Actor      string          `gorm:"size:100;not null;index" json:"actor"`                       // 操作人
+	Action     string          `gorm:"size:20;not null;index" json:"action"`                       // 操作：create/update/delete/restore
+	EntityType string          `gorm:"size:50;not null;index:idx_audit_entity" json:"entity_type"` // 实体类型
+	EntityID   uint            `gorm:"not null;index:idx_audit_entity" json:"entity_id"`           // 实体ID
+	Before     json.RawMessage `gorm:"type:longtext" json:"before,omitempty"`                      // 变更前的JSON
+	After      json.RawMessage `gorm:"type:longtext" json:"after,omitempty"`                       // 变更后的JSON
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// 审计操作常量
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// 审计实体类型常量
+const (
+	EntityForwardTarget = "forward_target"
+	EntityAPIKey        = "api_key"
+	EntityMailbox       = "mailbox"
+	EntityTenant        = "tenant"
+	EntityWebhook       = "webhook_subscription"
+)
@@ -7,18 +7,27 @@ import (
 
 // EmailLog 邮件处理记录表
 type EmailLog struct {
-	ID             uint           `gorm:"primarykey" json:"id"`
-	GmailMessageID string         `gorm:"size:100;not null;uniqueIndex" json:"gmail_message_id"` // Gmail消息ID
-	Subject        string         `gorm:"size:500;not null" json:"subject"`                      // 邮件主题
-	FromEmail      string         `gorm:"size:255;not null" json:"from_email"`                   // 发件人
-	ToEmail        string         `gorm:"size:255;not null" json:"to_email"`                     // 收件人
-	Content        string         `gorm:"type:longtext" json:"content"`                          // 邮件内容
-	Keyword        string         `gorm:"size:100" json:"keyword"`                               // 匹配的关键字
-	ForwardTarget  string         `gorm:"size:100" json:"forward_target"`                        // 转发目标名字
-	ForwardEmail   string         `gorm:"size:255" json:"forward_email"`                         // 转发目标邮箱
-	ForwardStatus  string         `gorm:"size:50;default:'pending'" json:"forward_status"`       // 转发状态：pending/success/failed
-	ErrorMessage   string         `gorm:"type:text" json:"error_message"`                        // 错误信息
-	ProcessedAt    *time.Time     `json:"processed_at"`                                          // 处理时间
+	ID       uint `gorm:"primarykey" json:"id"`
+	TenantID uint `gorm:"not null;default:1;index" json:"tenant_id"` // 所属租户
+	// MailboxID 收到邮件的邮箱，0为默认邮箱
+	MailboxID uint `gorm:"not null;default:0;index;uniqueIndex:idx_email_logs_mailbox_message,priority:1" json:"mailbox_id"`
+	// GmailMessageID Gmail消息ID，同一封邮件投递到多个邮箱时各自处理
+	GmailMessageID string         `gorm:"size:100;not null;uniqueIndex:idx_email_logs_mailbox_message,priority:2" json:"gmail_message_id"`
+	Subject        string         `gorm:"size:500;not null" json:"subject"`                // 邮件主题
+	FromEmail      string         `gorm:"size:255;not null" json:"from_email"`             // 发件人
+	ToEmail        string         `gorm:"size:255;not null" json:"to_email"`               // 收件人
+	Content        string         `gorm:"type:longtext" json:"content"`                    // 邮件内容
+	Keyword        string         `gorm:"size:100" json:"keyword"`                         // 匹配的关键字
+	ForwardTarget  string         `gorm:"size:100" json:"forward_target"`                  // 转发目标名字
+	ForwardEmail   string         `gorm:"size:255" json:"forward_email"`                   // 转发目标邮箱
+	TargetType     string         `gorm:"size:20" json:"target_type"`                      // 转发目标的投递方式
+	TargetID       uint           `gorm:"not null;default:0;index" json:"target_id"`       // 转发目标ID，重试时使用
+	ForwardStatus  string         `gorm:"size:50;default:'pending'" json:"forward_status"` // 转发状态：pending/success/failed/retrying
+	Attempts       int            `gorm:"not null;default:0" json:"attempts"`              // 已尝试转发的次数
+	NextRetryAt    *time.Time     `gorm:"index" json:"next_retry_at"`                      // 下次重试时间，只在retrying状态下有值
+	ErrorMessage   string         `gorm:"type:text" json:"error_message"`                  // 错误信息
+	TraceID        string         `gorm:"size:32;index" json:"trace_id"`                   // 处理该邮件的链路追踪ID
+	ProcessedAt    *time.Time     `json:"processed_at"`                                    // 处理时间
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
@@ -33,4 +42,6 @@ const (
 	StatusPending = "pending"
 	StatusSuccess = "success"
 	StatusFailed  = "failed"
+	// StatusRetrying 转发失败，等待下次重试
+	StatusRetrying = "retrying"
 )
\ No newline at end of file
@@ -7,16 +7,47 @@ import (
 
 // ForwardTarget 转发目标表
 type ForwardTarget struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Name      string         `gorm:"size:100;not null;index" json:"name"`           // 转发对象名字
-	Email     string         `gorm:"size:255;not null;index" json:"email"`          // 转发目标邮箱
-	Keywords  string         `gorm:"type:text" json:"keywords"`                     // 关联的关键字，用逗号分隔
-	IsActive  bool           `gorm:"default:true" json:"is_active"`                 // 是否启用
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primarykey" json:"id"`
+	TenantID       uint           `gorm:"not null;default:1;index" json:"tenant_id"`    // 所属租户
+	Name           string         `gorm:"size:100;not null;index" json:"name"`          // 转发对象名字
+	Type           string         `gorm:"size:20;not null;default:'email'" json:"type"` // 投递方式，见 TargetType* 常量
+	Email          string         `gorm:"size:255;not null;index" json:"email"`         // 转发目标邮箱，只用于email类型
+	WebhookURL     string         `gorm:"size:500" json:"webhook_url,omitempty"`        // 群机器人或webhook地址，用于email以外的类型
+	Secret         string         `gorm:"size:255" json:"-"`                            // 签名密钥（钉钉加签、飞书签名校验、webhook签名），不会在接口中返回
+	AttachmentMode string         `gorm:"size:10" json:"attachment_mode,omitempty"`     // webhook中附件的传递方式：url（默认）或 base64
+	Keywords       string         `gorm:"type:text" json:"keywords"`                    // 关联的关键字，用逗号分隔
+	IsActive       bool           `json:"is_active"`                                    // 是否启用；不设列默认值，否则GORM创建时会把false替换为true
+	MailboxID      uint           `gorm:"not null;default:0;index" json:"mailbox_id"`   // 适用的邮箱，0表示适用于所有邮箱
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (ForwardTarget) TableName() string {
 	return "forward_targets"
+}
+
+// 转发目标的投递方式
+const (
+	TargetTypeEmail    = "email"
+	TargetTypeDingTalk = "dingtalk"
+	TargetTypeWeCom    = "wecom"
+	TargetTypeFeishu   = "feishu"
+	TargetTypeSlack    = "slack"
+	TargetTypeWebhook  = "webhook"
+)
+
+// webhook中附件的传递方式
+const (
+	AttachmentModeURL    = "url"    // 只传下载链接
+	AttachmentModeBase64 = "base64" // 附件内容以base64内嵌在请求体中
+)
+
+// IsValidTargetType 检查投递方式是否有效
+func IsValidTargetType(targetType string) bool {
+	switch targetType {
+	case TargetTypeEmail, TargetTypeDingTalk, TargetTypeWeCom, TargetTypeFeishu, TargetTypeSlack, TargetTypeWebhook:
+		return true
+	}
+	return false
 }
\ No newline at end of file
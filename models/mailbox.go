@@ -0,0 +1,38 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Mailbox 监控的邮箱表，每个邮箱有独立的拉取循环
+// 配置文件中的 gmail.user_email 为默认邮箱，不在此表中，其ID视为 DefaultMailboxID
+type Mailbox struct {
+	ID                 uint           `gorm:"primarykey" json:"id"`
+	TenantID           uint           `gorm:"not null;default:1;index" json:"tenant_id"`         // 所属租户
+	Name               string         `gorm:"size:100;not null" json:"name"`                     // 邮箱名称
+	Address            string         `gorm:"size:255;not null;index" json:"address"`            // 邮箱地址
+	Provider           string         `gorm:"size:20;not null;default:'gmail'" json:"provider"`  // 邮箱提供方，目前只支持gmail
+	AuthMode           string         `gorm:"size:20;not null;default:'oauth'" json:"auth_mode"` // 认证方式：oauth/service_account
+	CredentialsFile    string         `gorm:"size:255" json:"credentials_file"`                  // OAuth客户端凭据文件，为空时使用全局配置
+	TokenFile          string         `gorm:"size:255" json:"token_file"`                        // 授权token文件，为空时使用 token-<邮箱地址>.json
+	ServiceAccountFile string         `gorm:"size:255" json:"service_account_file"`              // 服务账号密钥文件，为空时使用全局配置
+	Query              string         `gorm:"size:500" json:"query"`                             // Gmail搜索条件，为空时使用全局配置
+	CheckInterval      string         `gorm:"size:20" json:"check_interval"`                     // 检查间隔，例如5m，为空时使用全局配置
+	IsActive           bool           `json:"is_active"`                                         // 是否启用；不设列默认值，否则GORM创建时会把false替换为true
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Mailbox) TableName() string {
+	return "mailboxes"
+}
+
+// DefaultMailboxID 默认邮箱（配置文件中的邮箱）的ID
+const DefaultMailboxID = 0
+
+// 邮箱提供方常量
+const (
+	ProviderGmail = "gmail"
+)
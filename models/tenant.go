@@ -0,0 +1,26 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Tenant 租户表，转发目标、邮箱、邮件日志、API密钥和审计记录都属于某个租户，租户之间互相不可见
+type Tenant struct {
+	ID               uint           `gorm:"primarykey" json:"id"`
+	Name             string         `gorm:"size:100;not null" json:"name"` // 租户名称
+	MaxMailboxes     int            `json:"max_mailboxes"`                 // 最多可登记的邮箱数量，0表示不限制
+	MaxTargets       int            `json:"max_targets"`                   // 最多可创建的转发目标数量，0表示不限制
+	MaxDailyForwards int            `json:"max_daily_forwards"`            // 每天最多转发的邮件数量，0表示不限制
+	IsActive         bool           `json:"is_active"`                     // 停用后该租户的API密钥失效，邮箱停止处理；不设列默认值，否则GORM创建时会把false替换为true
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Tenant) TableName() string {
+	return "tenants"
+}
+
+// DefaultTenantID 默认租户的ID，升级前的数据和配置文件中的默认邮箱都属于默认租户
+const DefaultTenantID = 1
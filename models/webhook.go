@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription 事件订阅表，事件发生时向URL推送签名的JSON
+type WebhookSubscription struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	TenantID  uint           `gorm:"not null;default:1;index" json:"tenant_id"` // 所属租户
+	Name      string         `gorm:"size:100;not null" json:"name"`             // 订阅名称
+	URL       string         `gorm:"size:500;not null" json:"url"`              // 接收事件的地址
+	Secret    string         `gorm:"size:255" json:"-"`                         // 签名密钥，不会在接口中返回
+	Events    string         `gorm:"type:text" json:"events"`                   // 订阅的事件，逗号分隔，为空表示所有事件
+	IsActive  bool           `json:"is_active"`                                 // 是否启用；不设列默认值，否则GORM创建时会把false替换为true
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// EventDelivery 事件推送记录表，每次推送（包括重新推送）一条记录
+type EventDelivery struct {
+	ID             uint            `gorm:"primarykey" json:"id"`
+	TenantID       uint            `gorm:"not null;default:1;index" json:"tenant_id"`              // 所属租户
+	SubscriptionID uint            `gorm:"not null;index" json:"subscription_id"`                  // 事件订阅
+	EventID        string          `gorm:"size:36;not null;index" json:"event_id"`                 // 事件ID，重新推送时不变
+	Event          string          `gorm:"size:50;not null;index" json:"event"`                    // 事件类型
+	Payload        json.RawMessage `gorm:"type:longtext" json:"payload"`                           // 推送的请求体
+	Status         string          `gorm:"size:20;not null;default:'pending';index" json:"status"` // 推送状态：pending/success/failed
+	RedeliveryOf   uint            `gorm:"not null;default:0" json:"redelivery_of,omitempty"`      // 重新推送时为原推送记录的ID
+	ResponseStatus int             `json:"response_status"`                                        // 接收方返回的HTTP状态码
+	ResponseBody   string          `gorm:"type:text" json:"response_body"`                         // 接收方返回的内容（截断）
+	ErrorMessage   string          `gorm:"type:text" json:"error_message"`                         // 错误信息
+	DurationMs     int64           `json:"duration_ms"`                                            // 推送耗时（毫秒）
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"` // 推送完成时间
+}
+
+func (EventDelivery) TableName() string {
+	return "event_deliveries"
+}
+
+// 事件类型
+const (
+	EventMessageReceived  = "message.received"  // 拉取到一封新邮件
+	EventMessageForwarded = "message.forwarded" // 邮件转发成功
+	EventMessageFailed    = "message.failed"    // 邮件转发失败（包括之后还会重试的失败）
+	EventMessageUnmatched = "message.unmatched" // 邮件不符合转发规则或找不到转发目标
+	EventTargetChanged    = "target.changed"    // 转发目标被创建、修改或删除
+)
+
+// Events 所有事件类型
+var Events = []string{EventMessageReceived, EventMessageForwarded, EventMessageFailed, EventMessageUnmatched, EventTargetChanged}
+
+// IsValidEvent 检查事件类型是否有效
+func IsValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
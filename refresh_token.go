@@ -1,32 +1,60 @@
+//go:build ignore
+
 package main
 
 import (
+	"context"
+	"email-forwarding/config"
 	"email-forwarding/services"
 	"fmt"
 	"log"
+
+	"github.com/joho/godotenv"
 )
 
+// 用法: go run refresh_token.go
+// 与主程序读取相同的配置（.env、配置文件和环境变量），包括代理设置
 func main() {
 	fmt.Println("=== Gmail Token 刷新工具 ===")
-	
-	// 设置代理
-	services.SetProxy("http://127.0.0.1:10810")
-	
-	fmt.Println("正在刷新Gmail OAuth2 token...")
-	fmt.Println("请确保您有有效的 credentials.json 文件")
-	
-	// 尝试创建Gmail服务，这会自动刷新token
-	_, err := services.NewGmailService("credentials.json", "token.json", "your-email@gmail.com")
+
+	godotenv.Load()
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		log.Fatalf("配置无效:\n%v", err)
+	}
+
+	proxy, err := cfg.Proxy.ProxyFunc()
+	if err != nil {
+		log.Fatalf("代理配置无效: %v", err)
+	}
+	fmt.Printf("代理: %s\n", cfg.Proxy)
+
+	fmt.Printf("正在刷新Gmail token（认证方式: %s）...\n", cfg.Gmail.AuthMode)
+	if cfg.Gmail.AuthMode == services.GmailAuthServiceAccount {
+		fmt.Printf("请确保您有有效的 %s 文件，并已在Google Workspace管理后台授权域范围委派\n", cfg.Gmail.ServiceAccountFile)
+	} else {
+		fmt.Printf("请确保您有有效的 %s 文件\n", cfg.Gmail.CredentialsFile)
+	}
+
+	// 尝试创建Gmail服务并检查连通性，这会自动刷新token
+	gmailService, err := services.NewGmailServiceFromConfig(cfg.Gmail, proxy)
+	if err == nil && !gmailService.Authorized() {
+		fmt.Println("token不存在或已失效，请执行 go run . auth google 重新授权")
+		return
+	}
+	if err == nil {
+		err = gmailService.CheckHealth(context.Background())
+	}
 	if err != nil {
 		log.Printf("Token刷新失败: %v", err)
 		fmt.Println("\n可能的原因:")
-		fmt.Println("1. credentials.json 文件不存在或无效")
+		fmt.Println("1. credentials.json 或服务账号密钥文件不存在或无效，或服务账号未授权域范围委派")
 		fmt.Println("2. 代理配置不正确")
 		fmt.Println("3. 网络连接问题")
 		fmt.Println("4. Google OAuth2 配置问题")
 		return
 	}
-	
+
 	fmt.Println("Token刷新成功！")
 	fmt.Println("现在可以正常运行主程序了。")
-} 
\ No newline at end of file
+}
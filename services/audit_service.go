@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAuditLogNotFound 审计记录不存在
+	ErrAuditLogNotFound = newError(KindNotFound, "audit_log_not_found", "审计记录不存在", "audit log not found")
+	// ErrNotRestorable 审计记录对应的操作无法恢复
+	ErrNotRestorable = newError(KindConflict, "not_restorable", "无法恢复", "cannot be restored")
+)
+
+// AuditFilter 审计记录查询条件
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   uint
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditService 审计服务
+type AuditService struct{}
+
+// NewAuditService 创建审计服务实例
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// recordAudit 在事务tx中写入一条审计记录，tenantID为被操作对象所属的租户，before/after为nil时不记录对应快照
+func recordAudit(ctx context.Context, tx *gorm.DB, tenantID uint, action, entityType string, entityID uint, before, after interface{}) error {
+	entry := models.AuditLog{
+		TenantID:   tenantID,
+		Actor:      actorFromContext(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("序列化审计快照失败: %v", err)
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("序列化审计快照失败: %v", err)
+		}
+	}
+
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("保存审计记录失败: %v", err)
+	}
+	return nil
+}
+
+// GetAuditLogs 获取调用方所属租户的审计记录
+func (as *AuditService) GetAuditLogs(ctx context.Context, filter AuditFilter, page, pageSize int) ([]models.AuditLog, int64, error) {
+	db := database.GetDB()
+
+	var logs []models.AuditLog
+	var total int64
+
+	query := db.Model(&models.AuditLog{}).Scopes(tenantScope(ctx))
+
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id desc").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// Restore 根据删除操作的审计记录恢复被软删除的转发目标
+func (as *AuditService) Restore(ctx context.Context, auditID uint) (*models.ForwardTarget, error) {
+	// 结束后使转发目标缓存失效，下次匹配时重新加载
+	defer forwardTargets.invalidate()
+
+	db := database.GetDB()
+
+	var entry models.AuditLog
+	if err := db.Scopes(tenantScope(ctx)).First(&entry, auditID).Error; err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrAuditLogNotFound, auditID)
+	}
+
+	if entry.Action != models.AuditActionDelete || entry.EntityType != models.EntityForwardTarget {
+		return nil, fmt.Errorf("%w: 只能恢复已删除的转发目标", ErrNotRestorable)
+	}
+
+	var target models.ForwardTarget
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().First(&target, entry.EntityID).Error; err != nil {
+			return fmt.Errorf("%w: %d", ErrTargetNotFound, entry.EntityID)
+		}
+		if !target.DeletedAt.Valid {
+			return fmt.Errorf("%w: 转发目标 %d 未被删除", ErrNotRestorable, target.ID)
+		}
+
+		// 恢复后邮箱不能与同一租户的现有目标重复
+		if target.Type == models.TargetTypeEmail {
+			if err := checkTargetEmailUnique(tx, target.TenantID, target.Email, 0); err != nil {
+				return err
+			}
+		}
+		if err := checkTargetQuota(tx, target.TenantID); err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&target).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		target.DeletedAt = gorm.DeletedAt{}
+
+		return recordAudit(ctx, tx, target.TenantID, models.AuditActionRestore, models.EntityForwardTarget, target.ID, nil, target)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &target, nil
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"email-forwarding/utils"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix API密钥的固定前缀，用于区分API密钥和JWT
+const APIKeyPrefix = "efk_"
+
+var (
+	// ErrUnauthorized 认证失败
+	ErrUnauthorized = newError(KindUnauthorized, "unauthorized", "认证失败", "authentication failed")
+	// ErrRoleNotAllowed 调用方无权授予该角色
+	ErrRoleNotAllowed = newError(KindForbidden, "role_not_allowed", "无权授予该角色", "not allowed to grant this role")
+	// ErrInvalidAPIKey API密钥参数无效
+	ErrInvalidAPIKey = newError(KindValidation, "invalid_api_key", "API密钥参数无效", "invalid API key")
+	// ErrAPIKeyNotFound API密钥不存在
+	ErrAPIKeyNotFound = newError(KindNotFound, "api_key_not_found", "API密钥不存在", "API key not found")
+)
+
+// Principal 已认证的调用方
+type Principal struct {
+	Name       string // 操作人标识：API密钥名称或JWT的sub
+	Role       string
+	KeyID      uint // 通过API密钥认证时的密钥ID
+	TenantID   uint // 所属租户，为0时按默认租户处理
+	AllTenants bool // 超级管理员未指定租户时可以读取所有租户的数据
+}
+
+// principalContextKey context中保存调用方信息的键
+type principalContextKey struct{}
+
+// ContextWithPrincipal 将调用方信息写入context
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext 从context中获取调用方信息，不存在时返回nil
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// actorFromContext 获取用于审计的操作人标识，没有调用方时视为系统操作
+func actorFromContext(ctx context.Context) string {
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		return principal.Name
+	}
+	return "system"
+}
+
+// AuthService 认证服务
+type AuthService struct {
+	jwtSecret []byte
+}
+
+// NewAuthService 创建认证服务实例，jwtSecret为空时不接受JWT
+func NewAuthService(jwtSecret string) *AuthService {
+	return &AuthService{
+		jwtSecret: []byte(jwtSecret),
+	}
+}
+
+// jwtClaims JWT声明，tenant_id缺省时为默认租户
+type jwtClaims struct {
+	Role     string `json:"role"`
+	TenantID uint   `json:"tenant_id"`
+	jwt.RegisteredClaims
+}
+
+// Authenticate 校验API密钥或JWT，返回调用方信息
+func (as *AuthService) Authenticate(token string) (*Principal, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		return as.authenticateAPIKey(token)
+	}
+	return as.authenticateJWT(token)
+}
+
+// authenticateAPIKey 校验API密钥
+func (as *AuthService) authenticateAPIKey(rawKey string) (*Principal, error) {
+	db := database.GetDB()
+
+	var key models.APIKey
+	if err := db.Where("key_hash = ?", hashAPIKey(rawKey)).First(&key).Error; err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(now) {
+		return nil, ErrUnauthorized
+	}
+	if err := checkTenantActive(db, key.TenantID); err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	// 降低写入频率，最多每分钟记录一次使用时间
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+		if err := db.Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+			utils.GetLogger().Warnf("更新API密钥使用时间失败 [%d]: %v", key.ID, err)
+		}
+	}
+
+	return newPrincipal(key.Name, key.Role, key.ID, key.TenantID), nil
+}
+
+// authenticateJWT 校验HS256签名的JWT
+func (as *AuthService) authenticateJWT(token string) (*Principal, error) {
+	if len(as.jwtSecret) == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	claims := &jwtClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return as.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	if claims.Subject == "" || !models.IsValidRole(claims.Role) {
+		return nil, ErrUnauthorized
+	}
+	if claims.TenantID == 0 {
+		claims.TenantID = models.DefaultTenantID
+	}
+	if err := checkTenantActive(database.GetDB(), claims.TenantID); err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	return newPrincipal(claims.Subject, claims.Role, 0, claims.TenantID), nil
+}
+
+// newPrincipal 创建调用方，超级管理员默认可以读取所有租户的数据
+func newPrincipal(name, role string, keyID, tenantID uint) *Principal {
+	return &Principal{
+		Name:       name,
+		Role:       role,
+		KeyID:      keyID,
+		TenantID:   tenantID,
+		AllTenants: role == models.RoleSuperAdmin,
+	}
+}
+
+// SelectTenant 超级管理员通过 X-Tenant-ID 指定要操作的租户，之后的读写都限定在该租户内
+func (as *AuthService) SelectTenant(principal *Principal, tenantID uint) error {
+	if principal.Role != models.RoleSuperAdmin {
+		if tenantID != principal.TenantID {
+			return fmt.Errorf("%w: 只有超级管理员可以访问其他租户", ErrRoleNotAllowed)
+		}
+		return nil
+	}
+
+	if _, err := loadTenant(database.GetDB(), tenantID); err != nil {
+		return err
+	}
+	principal.TenantID = tenantID
+	principal.AllTenants = false
+	return nil
+}
+
+// checkTenantActive 检查租户存在且未停用
+func checkTenantActive(tx *gorm.DB, tenantID uint) error {
+	tenant, err := loadTenant(tx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !tenant.IsActive {
+		return fmt.Errorf("%w: %d", ErrTenantInactive, tenantID)
+	}
+	return nil
+}
+
+// CreateAPIKey 在调用方所属租户下创建API密钥，返回的明文密钥只在创建时可见
+// 只有超级管理员和命令行可以创建超级管理员密钥
+func (as *AuthService) CreateAPIKey(ctx context.Context, name, role string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("%w: 名称不能为空", ErrInvalidAPIKey)
+	}
+	if !models.IsValidRole(role) {
+		return "", nil, fmt.Errorf("%w: 无效的角色 %s", ErrInvalidAPIKey, role)
+	}
+	if principal := PrincipalFromContext(ctx); role == models.RoleSuperAdmin && principal != nil &&
+		principal.Role != "" && principal.Role != models.RoleSuperAdmin {
+		return "", nil, fmt.Errorf("%w: 只有超级管理员可以创建 %s 角色的密钥", ErrRoleNotAllowed, models.RoleSuperAdmin)
+	}
+	tenantID := tenantFromContext(ctx)
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("生成密钥失败: %v", err)
+	}
+	rawKey := APIKeyPrefix + hex.EncodeToString(buf)
+
+	key := &models.APIKey{
+		TenantID:  tenantID,
+		Name:      name,
+		Prefix:    rawKey[:len(APIKeyPrefix)+8],
+		KeyHash:   hashAPIKey(rawKey),
+		Role:      role,
+		ExpiresAt: expiresAt,
+	}
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := checkTenantActive(tx, tenantID); err != nil {
+			return err
+		}
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, tenantID, models.AuditActionCreate, models.EntityAPIKey, key.ID, nil, key)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return rawKey, key, nil
+}
+
+// ListAPIKeys 获取调用方所属租户的API密钥列表
+func (as *AuthService) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := database.GetDB().Scopes(tenantScope(ctx)).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey 吊销API密钥
+func (as *AuthService) RevokeAPIKey(ctx context.Context, id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var key models.APIKey
+		if err := tx.Scopes(tenantScope(ctx)).First(&key, id).Error; err != nil {
+			return fmt.Errorf("%w: %d", ErrAPIKeyNotFound, id)
+		}
+
+		if err := tx.Delete(&key).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, key.TenantID, models.AuditActionDelete, models.EntityAPIKey, id, key, nil)
+	})
+}
+
+// CountAPIKeys 统计有效的API密钥数量
+func (as *AuthService) CountAPIKeys() (int64, error) {
+	var count int64
+	err := database.GetDB().Model(&models.APIKey{}).Count(&count).Error
+	return count, err
+}
+
+// hashAPIKey 计算API密钥的哈希，密钥本身是高熵随机值，SHA-256即可
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// ConfigVersion 导出配置的格式版本
+const ConfigVersion = 1
+
+// 支持的配置格式
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// ErrInvalidConfig 导入的配置无效
+var ErrInvalidConfig = newError(KindValidation, "invalid_config", "配置无效", "invalid configuration")
+
+// csvHeader CSV格式的表头
+var csvHeader = []string{"name", "email", "keywords", "is_active", "mailbox_id", "type", "webhook_url", "attachment_mode"}
+
+// TargetSpec 导入导出时的转发目标，email类型以邮箱作为唯一标识，其他类型以投递方式和名字作为唯一标识
+// 签名密钥不会导出，导入时也不会修改，需要通过转发目标接口设置
+type TargetSpec struct {
+	Name       string `json:"name" yaml:"name"`
+	Type       string `json:"type,omitempty" yaml:"type,omitempty"`
+	Email      string `json:"email,omitempty" yaml:"email,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty" yaml:"webhook_url,omitempty"`
+	// AttachmentMode webhook中附件的传递方式，只用于webhook类型
+	AttachmentMode string `json:"attachment_mode,omitempty" yaml:"attachment_mode,omitempty"`
+	Keywords       string `json:"keywords" yaml:"keywords"`
+	IsActive       bool   `json:"is_active" yaml:"is_active"`
+	// MailboxID 适用的邮箱，0表示适用于所有邮箱
+	MailboxID uint `json:"mailbox_id,omitempty" yaml:"mailbox_id,omitempty"`
+}
+
+// UnmarshalJSON 解析JSON，未提供is_active时默认启用
+func (t *TargetSpec) UnmarshalJSON(data []byte) error {
+	type plain TargetSpec
+	p := plain{IsActive: true}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*t = TargetSpec(p)
+	return nil
+}
+
+// UnmarshalYAML 解析YAML，未提供is_active时默认启用
+func (t *TargetSpec) UnmarshalYAML(value *yaml.Node) error {
+	type plain TargetSpec
+	p := plain{IsActive: true}
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	*t = TargetSpec(p)
+	return nil
+}
+
+// ConfigDocument 导入导出的配置文档
+type ConfigDocument struct {
+	Version int          `json:"version" yaml:"version"`
+	Targets []TargetSpec `json:"targets" yaml:"targets"`
+}
+
+// TargetChange 导入时对已有目标的变更
+type TargetChange struct {
+	ID     uint       `json:"id"`
+	Before TargetSpec `json:"before"`
+	After  TargetSpec `json:"after"`
+}
+
+// TargetRef 导入时被删除的目标
+type TargetRef struct {
+	ID uint `json:"id"`
+	TargetSpec
+}
+
+// ImportResult 导入的差异结果
+type ImportResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Creates []TargetSpec   `json:"creates"`
+	Updates []TargetChange `json:"updates"`
+	Deletes []TargetRef    `json:"deletes"`
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	DryRun bool // 只计算差异，不写入数据库
+	Prune  bool // 删除配置中不存在的目标
+}
+
+// ConfigService 转发配置导入导出服务
+type ConfigService struct {
+	emailService *EmailService // 导入后通过它发送 target.changed 事件
+}
+
+// NewConfigService 创建配置服务实例
+func NewConfigService(emailService *EmailService) *ConfigService {
+	return &ConfigService{emailService: emailService}
+}
+
+// targetChange 导入时实际写入的一个转发目标变更
+type targetChange struct {
+	action string // create/update/delete
+	target models.ForwardTarget
+}
+
+// ExportConfig 导出调用方所属租户当前的转发配置（包括已停用的目标）
+func (cs *ConfigService) ExportConfig(ctx context.Context) (*ConfigDocument, error) {
+	var targets []models.ForwardTarget
+	if err := database.GetDB().Where("tenant_id = ?", tenantFromContext(ctx)).Order("id").Find(&targets).Error; err != nil {
+		return nil, err
+	}
+
+	doc := &ConfigDocument{
+		Version: ConfigVersion,
+		Targets: make([]TargetSpec, 0, len(targets)),
+	}
+	for _, t := range targets {
+		doc.Targets = append(doc.Targets, specFromTarget(t))
+	}
+	return doc, nil
+}
+
+// ImportConfig 按邮箱对比配置与调用方所属租户的目标，在同一事务中应用所有变更
+func (cs *ConfigService) ImportConfig(ctx context.Context, doc *ConfigDocument, opts ImportOptions) (*ImportResult, error) {
+	// 结束后使转发目标缓存失效，下次匹配时重新加载
+	defer forwardTargets.invalidate()
+
+	specs, err := normalizeSpecs(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		DryRun:  opts.DryRun,
+		Creates: []TargetSpec{},
+		Updates: []TargetChange{},
+		Deletes: []TargetRef{},
+	}
+
+	tenantID := tenantFromContext(ctx)
+	var changes []targetChange
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		tenant, err := loadTenant(tx, tenantID)
+		if err != nil {
+			return err
+		}
+
+		var existing []models.ForwardTarget
+		if err := tx.Where("tenant_id = ?", tenantID).Order("id").Find(&existing).Error; err != nil {
+			return err
+		}
+
+		byKey := make(map[string]models.ForwardTarget, len(existing))
+		for _, t := range existing {
+			byKey[specFromTarget(t).key()] = t
+		}
+
+		seen := make(map[string]bool, len(specs))
+		for _, spec := range specs {
+			seen[spec.key()] = true
+			if err := checkTargetMailbox(tx, tenantID, spec.MailboxID); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+			}
+
+			current, ok := byKey[spec.key()]
+			if !ok {
+				result.Creates = append(result.Creates, spec)
+				continue
+			}
+			if before := specFromTarget(current); before != spec {
+				result.Updates = append(result.Updates, TargetChange{ID: current.ID, Before: before, After: spec})
+			}
+		}
+
+		if opts.Prune {
+			for _, t := range existing {
+				if !seen[specFromTarget(t).key()] {
+					result.Deletes = append(result.Deletes, TargetRef{ID: t.ID, TargetSpec: specFromTarget(t)})
+				}
+			}
+		}
+
+		if count := len(existing) - len(result.Deletes) + len(result.Creates); tenant.MaxTargets > 0 && count > tenant.MaxTargets {
+			return fmt.Errorf("%w: 导入后共有 %d 个转发目标，租户 %d 最多可创建 %d 个", ErrQuotaExceeded, count, tenantID, tenant.MaxTargets)
+		}
+
+		if opts.DryRun {
+			return nil
+		}
+		changes, err = applyImport(ctx, tx, tenantID, result)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后与单个目标的增删改一样发送事件
+	for i := range changes {
+		cs.emailService.emitTarget(ctx, changes[i].action, &changes[i].target)
+	}
+	return result, nil
+}
+
+// applyImport 写入导入的差异并记录审计，新建的目标属于tenantID，返回写入的变更
+func applyImport(ctx context.Context, tx *gorm.DB, tenantID uint, result *ImportResult) ([]targetChange, error) {
+	changes := make([]targetChange, 0, len(result.Deletes)+len(result.Updates)+len(result.Creates))
+	for _, ref := range result.Deletes {
+		before := targetFromSpec(ref.TargetSpec)
+		before.ID = ref.ID
+		before.TenantID = tenantID
+		if err := tx.Delete(&models.ForwardTarget{}, ref.ID).Error; err != nil {
+			return nil, err
+		}
+		if err := recordAudit(ctx, tx, tenantID, models.AuditActionDelete, models.EntityForwardTarget, ref.ID, before, nil); err != nil {
+			return nil, err
+		}
+		changes = append(changes, targetChange{models.AuditActionDelete, before})
+	}
+
+	for _, change := range result.Updates {
+		var before models.ForwardTarget
+		if err := tx.First(&before, change.ID).Error; err != nil {
+			return nil, err
+		}
+
+		after := before
+		after.Name = change.After.Name
+		after.Email = change.After.Email
+		after.WebhookURL = change.After.WebhookURL
+		after.AttachmentMode = change.After.AttachmentMode
+		after.Keywords = change.After.Keywords
+		after.IsActive = change.After.IsActive
+		after.MailboxID = change.After.MailboxID
+		if err := tx.Model(&before).Select("name", "email", "webhook_url", "attachment_mode", "keywords", "is_active", "mailbox_id", "updated_at").Updates(&after).Error; err != nil {
+			return nil, err
+		}
+		if err := recordAudit(ctx, tx, tenantID, models.AuditActionUpdate, models.EntityForwardTarget, change.ID, before, after); err != nil {
+			return nil, err
+		}
+		changes = append(changes, targetChange{models.AuditActionUpdate, after})
+	}
+
+	for _, spec := range result.Creates {
+		target := targetFromSpec(spec)
+		target.TenantID = tenantID
+		if err := tx.Create(&target).Error; err != nil {
+			return nil, err
+		}
+		if err := recordAudit(ctx, tx, tenantID, models.AuditActionCreate, models.EntityForwardTarget, target.ID, nil, target); err != nil {
+			return nil, err
+		}
+		changes = append(changes, targetChange{models.AuditActionCreate, target})
+	}
+
+	return changes, nil
+}
+
+// key 导入时用来对比的唯一标识
+func (t TargetSpec) key() string {
+	if t.Type == models.TargetTypeEmail {
+		return t.Type + ":" + t.Email
+	}
+	return t.Type + ":" + strings.ToLower(t.Name)
+}
+
+// normalizeSpecs 校验配置文档，规范化每个目标并检查唯一标识是否重复
+func normalizeSpecs(doc *ConfigDocument) ([]TargetSpec, error) {
+	if doc.Version != 0 && doc.Version != ConfigVersion {
+		return nil, fmt.Errorf("%w: 不支持的版本 %d", ErrInvalidConfig, doc.Version)
+	}
+
+	specs := make([]TargetSpec, 0, len(doc.Targets))
+	seen := make(map[string]int, len(doc.Targets))
+	for i, spec := range doc.Targets {
+		target := targetFromSpec(spec)
+		if err := normalizeForwardTarget(&target); err != nil {
+			return nil, fmt.Errorf("%w: 第 %d 个目标: %v", ErrInvalidConfig, i+1, err)
+		}
+		normalized := specFromTarget(target)
+		if j, ok := seen[normalized.key()]; ok {
+			return nil, fmt.Errorf("%w: 第 %d 个目标与第 %d 个目标重复（%s）", ErrInvalidConfig, i+1, j+1, normalized.key())
+		}
+		seen[normalized.key()] = i
+		specs = append(specs, normalized)
+	}
+	return specs, nil
+}
+
+// EncodeConfig 按指定格式输出配置文档
+func EncodeConfig(w io.Writer, doc *ConfigDocument, format string) error {
+	switch format {
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, t := range doc.Targets {
+			if err := cw.Write([]string{t.Name, t.Email, t.Keywords, strconv.FormatBool(t.IsActive), strconv.FormatUint(uint64(t.MailboxID), 10), t.Type, t.WebhookURL, t.AttachmentMode}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("%w: 不支持的格式 %s", ErrInvalidConfig, format)
+	}
+}
+
+// DecodeConfig 按指定格式解析配置文档
+func DecodeConfig(r io.Reader, format string) (*ConfigDocument, error) {
+	doc := &ConfigDocument{}
+
+	switch format {
+	case FormatYAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(doc); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(doc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	case FormatCSV:
+		targets, err := decodeCSVTargets(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		doc.Version = ConfigVersion
+		doc.Targets = targets
+	default:
+		return nil, fmt.Errorf("%w: 不支持的格式 %s", ErrInvalidConfig, format)
+	}
+
+	return doc, nil
+}
+
+// decodeCSVTargets 解析CSV，表头需包含name列，其余列可选
+func decodeCSVTargets(r io.Reader) ([]TargetSpec, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取表头失败: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("缺少 name 列")
+	}
+
+	get := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var targets []TargetSpec
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		spec := TargetSpec{
+			Name:           get(record, "name"),
+			Type:           get(record, "type"),
+			Email:          get(record, "email"),
+			WebhookURL:     get(record, "webhook_url"),
+			AttachmentMode: get(record, "attachment_mode"),
+			Keywords:       get(record, "keywords"),
+			IsActive:       true,
+		}
+		if active := get(record, "is_active"); active != "" {
+			if spec.IsActive, err = strconv.ParseBool(active); err != nil {
+				return nil, fmt.Errorf("第 %d 行 is_active 无效: %s", line, active)
+			}
+		}
+		if mailbox := get(record, "mailbox_id"); mailbox != "" {
+			id, err := strconv.ParseUint(mailbox, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行 mailbox_id 无效: %s", line, mailbox)
+			}
+			spec.MailboxID = uint(id)
+		}
+		targets = append(targets, spec)
+	}
+
+	return targets, nil
+}
+
+// specFromTarget 转发目标转为导出格式
+func specFromTarget(t models.ForwardTarget) TargetSpec {
+	return TargetSpec{
+		Name:           t.Name,
+		Type:           t.Type,
+		Email:          t.Email,
+		WebhookURL:     t.WebhookURL,
+		AttachmentMode: t.AttachmentMode,
+		Keywords:       t.Keywords,
+		IsActive:       t.IsActive,
+		MailboxID:      t.MailboxID,
+	}
+}
+
+// targetFromSpec 导出格式转为转发目标
+func targetFromSpec(spec TargetSpec) models.ForwardTarget {
+	return models.ForwardTarget{
+		Name:           spec.Name,
+		Type:           spec.Type,
+		Email:          spec.Email,
+		WebhookURL:     spec.WebhookURL,
+		AttachmentMode: spec.AttachmentMode,
+		Keywords:       spec.Keywords,
+		IsActive:       spec.IsActive,
+		MailboxID:      spec.MailboxID,
+	}
+}
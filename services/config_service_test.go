@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/models"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// inactiveConfigs 同一个停用的转发目标在各格式中的写法
+var inactiveConfigs = map[string]string{
+	FormatCSV:  "name,email,keywords,is_active\nops,ops@example.com,告警,false\n",
+	FormatYAML: "version: 1\ntargets:\n  - name: ops\n    email: ops@example.com\n    keywords: 告警\n    is_active: false\n",
+	FormatJSON: `{"version":1,"targets":[{"name":"ops","email":"ops@example.com","keywords":"告警","is_active":false}]}`,
+}
+
+func expectImportStart(mock sqlmock.Sqlmock, existing *sqlmock.Rows) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `tenants`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_active"}).AddRow(models.DefaultTenantID, true))
+	mock.ExpectQuery("SELECT \\* FROM `forward_targets` WHERE tenant_id = \\?").WillReturnRows(existing)
+}
+
+func TestImportConfigCreatesInactiveTargets(t *testing.T) {
+	for format, content := range inactiveConfigs {
+		t.Run(format, func(t *testing.T) {
+			mock := useMockDB(t)
+			inserts := captureInserts(t)
+
+			expectImportStart(mock, sqlmock.NewRows([]string{"id"}))
+			mock.ExpectExec("INSERT INTO `forward_targets`").WillReturnResult(sqlmock.NewResult(7, 1))
+			mock.ExpectExec("INSERT INTO `audit_logs`").WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+
+			doc, err := DecodeConfig(strings.NewReader(content), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := NewConfigService(NewEmailService(nil, nil)).ImportConfig(context.Background(), doc, ImportOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Creates) != 1 || result.Creates[0].IsActive {
+				t.Fatalf("creates = %+v", result.Creates)
+			}
+			if got, ok := inserts["forward_targets"]["is_active"]; !ok || got != false {
+				t.Errorf("写入的 is_active = %v（列存在: %v），期望 false", got, ok)
+			}
+		})
+	}
+}
+
+func TestImportConfigUnchangedInactiveTarget(t *testing.T) {
+	mock := useMockDB(t)
+	expectImportStart(mock, sqlmock.NewRows([]string{"id", "tenant_id", "name", "type", "email", "keywords", "is_active"}).
+		AddRow(3, models.DefaultTenantID, "ops", models.TargetTypeEmail, "ops@example.com", "告警", false))
+	mock.ExpectCommit()
+
+	doc, err := DecodeConfig(strings.NewReader(inactiveConfigs[FormatCSV]), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewConfigService(NewEmailService(nil, nil)).ImportConfig(context.Background(), doc, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Creates)+len(result.Updates)+len(result.Deletes) != 0 {
+		t.Errorf("再次导入相同的配置不应有差异: %+v", result)
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"email-forwarding/database"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// useMockDB 把 database.DB 替换为sqlmock，测试结束后检查所有预期的SQL都已执行
+func useMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return mock
+}
+
+// captureInserts 记录之后每条INSERT语句实际写入的列和值，按表名索引，同一张表只保留最后一行
+// 用于检查零值字段是否被列的默认值替换
+func captureInserts(t *testing.T) map[string]map[string]interface{} {
+	t.Helper()
+	inserts := make(map[string]map[string]interface{})
+	err := database.DB.Callback().Create().After("gorm:create").Register("test:capture_inserts", func(tx *gorm.DB) {
+		sql := tx.Statement.SQL.String()
+		start, end := strings.Index(sql, "("), strings.Index(sql, ") VALUES")
+		if start < 0 || end < start {
+			return
+		}
+		row := make(map[string]interface{})
+		for i, column := range strings.Split(sql[start+1:end], ",") {
+			if i < len(tx.Statement.Vars) {
+				row[strings.Trim(column, "`")] = tx.Statement.Vars[i]
+			}
+		}
+		inserts[tx.Statement.Table] = row
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inserts
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"email-forwarding/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// excerptLength 摘要卡片中正文摘录的最大字数
+const excerptLength = 200
+
+// deliveryTimeout 调用群机器人和webhook的超时时间
+const deliveryTimeout = 10 * time.Second
+
+// Summary 投递到聊天工具的邮件摘要，各平台按自己的格式渲染成卡片
+type Summary struct {
+	Subject    string    `json:"subject"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Keyword    string    `json:"keyword"`
+	TargetName string    `json:"target_name"`
+	Excerpt    string    `json:"excerpt"`
+	Link       string    `json:"link"` // 在Gmail网页版中打开原邮件的链接
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Delivery 把邮件摘要投递到一种类型的转发目标
+type Delivery interface {
+	Deliver(ctx context.Context, target *models.ForwardTarget, summary *Summary) error
+}
+
+// newDeliveries 创建群机器人各投递方式的实现，共用同一个HTTP客户端
+// email和通用webhook需要完整的邮件内容，由EmailService单独处理
+func newDeliveries(client *http.Client) map[string]Delivery {
+	return map[string]Delivery{
+		models.TargetTypeDingTalk: NewDingTalkDelivery(client),
+		models.TargetTypeWeCom:    NewWeComDelivery(client),
+		models.TargetTypeFeishu:   NewFeishuDelivery(client),
+		models.TargetTypeSlack:    NewSlackDelivery(client),
+	}
+}
+
+// newSummary 根据邮件和匹配结果生成摘要
+func newSummary(mailbox string, email *EmailMessage, keyword string, target *models.ForwardTarget) *Summary {
+	return &Summary{
+		Subject:    email.Subject,
+		From:       email.From,
+		To:         email.To,
+		Keyword:    keyword,
+		TargetName: target.Name,
+		Excerpt:    excerpt(email.Body, excerptLength),
+		Link:       gmailMessageLink(mailbox, email.ID),
+		ReceivedAt: email.ReceivedAt,
+	}
+}
+
+// gmailMessageLink 在Gmail网页版中打开邮件的链接，authuser保证多账号登录时打开正确的邮箱
+func gmailMessageLink(mailbox, messageID string) string {
+	return "https://mail.google.com/mail/?authuser=" + url.QueryEscape(mailbox) + "#all/" + messageID
+}
+
+var (
+	htmlBlockPattern = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// excerpt 去掉HTML标签并合并空白，截取前n个字
+func excerpt(body string, n int) string {
+	text := htmlBlockPattern.ReplaceAllString(body, " ")
+	text = htmlTagPattern.ReplaceAllString(text, " ")
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+	return truncateRunes(text, n)
+}
+
+// truncateRunes 超过n个字时截断并以省略号结尾
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// postJSON 以JSON发送payload，非2xx响应返回错误，成功时返回响应体供调用方检查平台的错误码
+func postJSON(ctx context.Context, client *http.Client, endpoint string, payload interface{}, headers map[string]string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, permanent(fmt.Errorf("序列化消息失败: %v", err))
+	}
+	return postBody(ctx, client, endpoint, body, headers)
+}
+
+// postBody 发送已序列化的JSON请求体
+// 除408、429以外的4xx响应说明请求本身有问题，重试也不会成功，返回不可重试的错误
+func postBody(ctx context.Context, client *http.Client, endpoint string, body []byte, headers map[string]string) ([]byte, error) {
+	status, respBody, err := sendJSON(ctx, client, endpoint, body, headers)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 {
+		err := fmt.Errorf("HTTP %d: %s", status, strings.TrimSpace(string(respBody)))
+		if status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+			return nil, permanent(err)
+		}
+		return nil, err
+	}
+	return respBody, nil
+}
+
+// sendJSON 发送JSON请求体，返回响应状态码和响应体（最多64KB），只有请求失败时返回错误
+func sendJSON(ctx context.Context, client *http.Client, endpoint string, body []byte, headers map[string]string) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, permanent(fmt.Errorf("创建请求失败: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// permanentError 重试也不会成功的投递错误，例如地址无效、被对方拒绝
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// permanent 把错误标记为不可重试
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// isPermanent 判断投递错误是否不可重试
+func isPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// checkErrCode 检查钉钉、企业微信风格的响应 {"errcode":0,"errmsg":"ok"}
+func checkErrCode(respBody []byte) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("无法解析响应: %s", strings.TrimSpace(string(respBody)))
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// markdownSummary 钉钉、企业微信等Markdown消息共用的正文
+func markdownSummary(s *Summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**发件人:** %s\n\n", s.From)
+	fmt.Fprintf(&b, "**关键字:** %s\n\n", s.Keyword)
+	fmt.Fprintf(&b, "**时间:** %s\n\n", s.ReceivedAt.Format("2006-01-02 15:04:05"))
+	if s.Excerpt != "" {
+		fmt.Fprintf(&b, "> %s\n\n", s.Excerpt)
+	}
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"email-forwarding/models"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DingTalkDelivery 钉钉群机器人，设置了密钥时按“加签”方式在URL中附加timestamp和sign
+type DingTalkDelivery struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewDingTalkDelivery 创建钉钉群机器人投递
+func NewDingTalkDelivery(client *http.Client) *DingTalkDelivery {
+	return &DingTalkDelivery{client: client, now: time.Now}
+}
+
+// Deliver 以ActionCard发送邮件摘要，按钮打开原邮件
+func (d *DingTalkDelivery) Deliver(ctx context.Context, target *models.ForwardTarget, s *Summary) error {
+	endpoint := target.WebhookURL
+	if target.Secret != "" {
+		signed, err := dingTalkSignURL(endpoint, target.Secret, d.now())
+		if err != nil {
+			return err
+		}
+		endpoint = signed
+	}
+
+	payload := map[string]interface{}{
+		"msgtype": "actionCard",
+		"actionCard": map[string]string{
+			"title":       s.Subject,
+			"text":        "### " + s.Subject + "\n\n" + markdownSummary(s),
+			"singleTitle": "查看原邮件",
+			"singleURL":   s.Link,
+		},
+	}
+	respBody, err := postJSON(ctx, d.client, endpoint, payload, nil)
+	if err != nil {
+		return fmt.Errorf("钉钉: %w", err)
+	}
+	if err := checkErrCode(respBody); err != nil {
+		return fmt.Errorf("钉钉: %w", err)
+	}
+	return nil
+}
+
+// dingTalkSignURL 钉钉加签：sign = Base64(HmacSHA256(secret, timestamp + "\n" + secret))，timestamp为毫秒
+func dingTalkSignURL(endpoint, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", permanent(fmt.Errorf("无效的webhook地址: %v", err))
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+
+	q := u.Query()
+	q.Set("timestamp", timestamp)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// WeComDelivery 企业微信群机器人，密钥包含在webhook地址的key参数中，无需签名
+type WeComDelivery struct {
+	client *http.Client
+}
+
+// NewWeComDelivery 创建企业微信群机器人投递
+func NewWeComDelivery(client *http.Client) *WeComDelivery {
+	return &WeComDelivery{client: client}
+}
+
+// Deliver 以Markdown消息发送邮件摘要
+func (d *WeComDelivery) Deliver(ctx context.Context, target *models.ForwardTarget, s *Summary) error {
+	content := "### " + s.Subject + "\n" + markdownSummary(s) + "[查看原邮件](" + s.Link + ")"
+	payload := map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": content},
+	}
+	respBody, err := postJSON(ctx, d.client, target.WebhookURL, payload, nil)
+	if err != nil {
+		return fmt.Errorf("企业微信: %w", err)
+	}
+	if err := checkErrCode(respBody); err != nil {
+		return fmt.Errorf("企业微信: %w", err)
+	}
+	return nil
+}
+
+// FeishuDelivery 飞书群机器人，设置了密钥时在请求体中附加timestamp和sign
+type FeishuDelivery struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewFeishuDelivery 创建飞书群机器人投递
+func NewFeishuDelivery(client *http.Client) *FeishuDelivery {
+	return &FeishuDelivery{client: client, now: time.Now}
+}
+
+// Deliver 以消息卡片发送邮件摘要，按钮打开原邮件
+func (d *FeishuDelivery) Deliver(ctx context.Context, target *models.ForwardTarget, s *Summary) error {
+	payload := map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"header": map[string]interface{}{
+				"title":    map[string]string{"tag": "plain_text", "content": s.Subject},
+				"template": "blue",
+			},
+			"elements": []interface{}{
+				map[string]interface{}{
+					"tag":  "div",
+					"text": map[string]string{"tag": "lark_md", "content": markdownSummary(s)},
+				},
+				map[string]interface{}{
+					"tag": "action",
+					"actions": []interface{}{
+						map[string]interface{}{
+							"tag":  "button",
+							"text": map[string]string{"tag": "plain_text", "content": "查看原邮件"},
+							"url":  s.Link,
+							"type": "primary",
+						},
+					},
+				},
+			},
+		},
+	}
+	if target.Secret != "" {
+		timestamp := strconv.FormatInt(d.now().Unix(), 10)
+		payload["timestamp"] = timestamp
+		payload["sign"] = feishuSign(timestamp, target.Secret)
+	}
+
+	respBody, err := postJSON(ctx, d.client, target.WebhookURL, payload, nil)
+	if err != nil {
+		return fmt.Errorf("飞书: %w", err)
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("飞书: 无法解析响应: %s", strings.TrimSpace(string(respBody)))
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("飞书: code %d: %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// feishuSign 飞书签名校验：以 timestamp + "\n" + secret 为密钥对空串做HmacSHA256，再Base64，timestamp为秒
+func feishuSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SlackDelivery Slack Incoming Webhook，地址本身即凭据，无需签名
+type SlackDelivery struct {
+	client *http.Client
+}
+
+// NewSlackDelivery 创建Slack投递
+func NewSlackDelivery(client *http.Client) *SlackDelivery {
+	return &SlackDelivery{client: client}
+}
+
+// Deliver 以Block Kit消息发送邮件摘要，text作为通知中的预览
+func (d *SlackDelivery) Deliver(ctx context.Context, target *models.ForwardTarget, s *Summary) error {
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			// header最多150个字符
+			"text": map[string]string{"type": "plain_text", "text": truncateRunes(s.Subject, 150)},
+		},
+		map[string]interface{}{
+			"type": "section",
+			"fields": []interface{}{
+				map[string]string{"type": "mrkdwn", "text": "*发件人*\n" + slackEscape(s.From)},
+				map[string]string{"type": "mrkdwn", "text": "*关键字*\n" + slackEscape(s.Keyword)},
+			},
+		},
+	}
+	if s.Excerpt != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": "> " + slackEscape(s.Excerpt)},
+		})
+	}
+	blocks = append(blocks, map[string]interface{}{
+		"type": "actions",
+		"elements": []interface{}{
+			map[string]interface{}{
+				"type": "button",
+				"text": map[string]string{"type": "plain_text", "text": "查看原邮件"},
+				"url":  s.Link,
+			},
+		},
+	})
+
+	payload := map[string]interface{}{
+		"text":   slackEscape(s.Subject + " (" + s.From + ")"),
+		"blocks": blocks,
+	}
+	if _, err := postJSON(ctx, d.client, target.WebhookURL, payload, nil); err != nil {
+		return fmt.Errorf("Slack: %w", err)
+	}
+	return nil
+}
+
+// slackEscape 转义Slack mrkdwn中的控制字符
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
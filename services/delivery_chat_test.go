@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/models"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chatServer 记录收到的请求并返回固定的响应
+type chatServer struct {
+	*httptest.Server
+	query url.Values
+	body  map[string]interface{}
+}
+
+func newChatServer(t *testing.T, status int, response string) *chatServer {
+	t.Helper()
+	cs := &chatServer{}
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("请求方法 = %s, 期望 POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		cs.query = r.URL.Query()
+		raw, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &cs.body); err != nil {
+			t.Errorf("请求体不是JSON: %v", err)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(cs.Close)
+	return cs
+}
+
+// field 按路径取出请求体中的字段，路径中的数字表示数组下标
+func (cs *chatServer) field(t *testing.T, path ...interface{}) interface{} {
+	t.Helper()
+	var v interface{} = cs.body
+	for _, p := range path {
+		switch key := p.(type) {
+		case string:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				t.Fatalf("请求体中 %v 不是对象", path)
+			}
+			v = m[key]
+		case int:
+			a, ok := v.([]interface{})
+			if !ok || key >= len(a) {
+				t.Fatalf("请求体中 %v 不是足够长的数组", path)
+			}
+			v = a[key]
+		}
+	}
+	return v
+}
+
+func testSummary() *Summary {
+	return &Summary{
+		Subject:    "服务器告警 <CPU>",
+		From:       "monitor@example.com",
+		Keyword:    "告警",
+		Excerpt:    "CPU使用率超过90%",
+		Link:       "https://mail.google.com/mail/?authuser=a%40example.com#all/abc",
+		ReceivedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+var testNow = time.Unix(1700000000, 0)
+
+func TestDingTalkDeliveryPayload(t *testing.T) {
+	srv := newChatServer(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	d := NewDingTalkDelivery(srv.Client())
+	d.now = func() time.Time { return testNow }
+
+	target := &models.ForwardTarget{WebhookURL: srv.URL + "/robot/send?access_token=tok", Secret: "SECxxx"}
+	if err := d.Deliver(context.Background(), target, testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := srv.field(t, "msgtype"); got != "actionCard" {
+		t.Errorf("msgtype = %v", got)
+	}
+	if got := srv.field(t, "actionCard", "singleURL"); got != testSummary().Link {
+		t.Errorf("singleURL = %v", got)
+	}
+	if text, _ := srv.field(t, "actionCard", "text").(string); !strings.Contains(text, "monitor@example.com") {
+		t.Errorf("text 缺少发件人: %q", text)
+	}
+
+	signed, err := dingTalkSignURL(target.WebhookURL, target.Secret, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := url.Parse(signed)
+	if srv.query.Get("access_token") != "tok" {
+		t.Errorf("access_token 丢失: %v", srv.query)
+	}
+	if srv.query.Get("timestamp") != "1700000000000" || srv.query.Get("sign") != want.Query().Get("sign") {
+		t.Errorf("签名参数 = %v, 期望 %v", srv.query, want.Query())
+	}
+}
+
+func TestDingTalkDeliveryWithoutSecret(t *testing.T) {
+	srv := newChatServer(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	target := &models.ForwardTarget{WebhookURL: srv.URL}
+	if err := NewDingTalkDelivery(srv.Client()).Deliver(context.Background(), target, testSummary()); err != nil {
+		t.Fatal(err)
+	}
+	if srv.query.Has("sign") || srv.query.Has("timestamp") {
+		t.Errorf("未设置密钥时不应签名: %v", srv.query)
+	}
+}
+
+func TestWeComDeliveryPayload(t *testing.T) {
+	srv := newChatServer(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	target := &models.ForwardTarget{WebhookURL: srv.URL + "/cgi-bin/webhook/send?key=k"}
+	if err := NewWeComDelivery(srv.Client()).Deliver(context.Background(), target, testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := srv.field(t, "msgtype"); got != "markdown" {
+		t.Errorf("msgtype = %v", got)
+	}
+	content, _ := srv.field(t, "markdown", "content").(string)
+	if !strings.HasPrefix(content, "### 服务器告警 <CPU>\n") || !strings.HasSuffix(content, "[查看原邮件]("+testSummary().Link+")") {
+		t.Errorf("content = %q", content)
+	}
+	if srv.query.Get("key") != "k" {
+		t.Errorf("key 丢失: %v", srv.query)
+	}
+}
+
+func TestFeishuDeliveryPayload(t *testing.T) {
+	srv := newChatServer(t, http.StatusOK, `{"code":0,"msg":"success"}`)
+	d := NewFeishuDelivery(srv.Client())
+	d.now = func() time.Time { return testNow }
+
+	target := &models.ForwardTarget{WebhookURL: srv.URL, Secret: "secret"}
+	if err := d.Deliver(context.Background(), target, testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := srv.field(t, "msg_type"); got != "interactive" {
+		t.Errorf("msg_type = %v", got)
+	}
+	if got := srv.field(t, "card", "header", "title", "content"); got != testSummary().Subject {
+		t.Errorf("标题 = %v", got)
+	}
+	if got := srv.field(t, "card", "elements", 1, "actions", 0, "url"); got != testSummary().Link {
+		t.Errorf("按钮链接 = %v", got)
+	}
+	if got := srv.field(t, "timestamp"); got != "1700000000" {
+		t.Errorf("timestamp = %v", got)
+	}
+	if got := srv.field(t, "sign"); got != feishuSign("1700000000", "secret") {
+		t.Errorf("sign = %v", got)
+	}
+}
+
+func TestSlackDeliveryPayload(t *testing.T) {
+	srv := newChatServer(t, http.StatusOK, "ok")
+	target := &models.ForwardTarget{WebhookURL: srv.URL}
+	if err := NewSlackDelivery(srv.Client()).Deliver(context.Background(), target, testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := srv.field(t, "text"); got != "服务器告警 &lt;CPU&gt; (monitor@example.com)" {
+		t.Errorf("text = %v", got)
+	}
+	if got := srv.field(t, "blocks", 0, "text", "text"); got != testSummary().Subject {
+		t.Errorf("header = %v", got)
+	}
+	if got := srv.field(t, "blocks", 2, "text", "text"); got != "> CPU使用率超过90%" {
+		t.Errorf("摘录 = %v", got)
+	}
+	if got := srv.field(t, "blocks", 3, "elements", 0, "url"); got != testSummary().Link {
+		t.Errorf("按钮链接 = %v", got)
+	}
+}
+
+func TestChatDeliveryErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		platform  string
+		status    int
+		response  string
+		wantErr   string
+		permanent bool
+	}{
+		{"钉钉errcode", models.TargetTypeDingTalk, http.StatusOK, `{"errcode":310000,"errmsg":"sign not match"}`, "钉钉: errcode 310000: sign not match", false},
+		{"钉钉响应无法解析", models.TargetTypeDingTalk, http.StatusOK, `<html>`, "钉钉: 无法解析响应", false},
+		{"企业微信errcode", models.TargetTypeWeCom, http.StatusOK, `{"errcode":93000,"errmsg":"invalid webhook url"}`, "企业微信: errcode 93000", false},
+		{"企业微信限流", models.TargetTypeWeCom, http.StatusTooManyRequests, `slow down`, "企业微信: HTTP 429: slow down", false},
+		{"飞书code", models.TargetTypeFeishu, http.StatusOK, `{"code":19021,"msg":"sign match fail"}`, "飞书: code 19021: sign match fail", false},
+		{"飞书服务端错误", models.TargetTypeFeishu, http.StatusBadGateway, `bad gateway`, "飞书: HTTP 502", false},
+		{"Slack地址无效", models.TargetTypeSlack, http.StatusNotFound, `no_service`, "Slack: HTTP 404: no_service", true},
+		{"Slack服务端错误", models.TargetTypeSlack, http.StatusInternalServerError, `error`, "Slack: HTTP 500", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newChatServer(t, tt.status, tt.response)
+			delivery := newDeliveries(srv.Client())[tt.platform]
+			err := delivery.Deliver(context.Background(), &models.ForwardTarget{WebhookURL: srv.URL}, testSummary())
+			if err == nil {
+				t.Fatal("期望返回错误")
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %q, 期望以 %q 开头", err, tt.wantErr)
+			}
+			if isPermanent(err) != tt.permanent {
+				t.Errorf("isPermanent = %v, 期望 %v", isPermanent(err), tt.permanent)
+			}
+		})
+	}
+}
+
+func TestChatDeliveryInvalidURL(t *testing.T) {
+	target := &models.ForwardTarget{WebhookURL: "://bad", Secret: "s"}
+	err := NewDingTalkDelivery(http.DefaultClient).Deliver(context.Background(), target, testSummary())
+	if err == nil || !isPermanent(err) {
+		t.Fatalf("无效地址应返回不可重试的错误: %v", err)
+	}
+}
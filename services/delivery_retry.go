@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"email-forwarding/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// maxRetryBackoff 重试间隔的上限
+const maxRetryBackoff = time.Hour
+
+// retryBatchSize 每次运行最多重试的邮件数量
+const retryBatchSize = 50
+
+// SetRetryPolicy 设置转发失败后的重试策略：最多尝试maxAttempts次（含第一次），
+// 第n次重试前等待 backoff * 2^(n-1)，最长 maxRetryBackoff
+func (es *EmailService) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.maxAttempts = maxAttempts
+	es.retryBackoff = backoff
+}
+
+// SetPublicURL 设置对外访问地址，用于生成webhook中附件的下载链接
+func (es *EmailService) SetPublicURL(publicURL string) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.publicURL = publicURL
+}
+
+// nextRetry 计算已尝试attempts次后的下次重试时间，不再重试时返回nil
+func (es *EmailService) nextRetry(attempts int, now time.Time) *time.Time {
+	es.mu.Lock()
+	maxAttempts, backoff := es.maxAttempts, es.retryBackoff
+	es.mu.Unlock()
+
+	if attempts >= maxAttempts || backoff <= 0 {
+		return nil
+	}
+	delay := backoff
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	next := now.Add(delay)
+	return &next
+}
+
+// recordAttempt 根据一次投递的结果更新处理记录：成功、等待重试，或在不可重试、次数用完时标记为失败
+func (es *EmailService) recordAttempt(emailLog *models.EmailLog, target *models.ForwardTarget, err error) {
+	now := time.Now()
+	emailLog.Attempts++
+	emailLog.NextRetryAt = nil
+
+	if err == nil {
+		emailLog.ForwardStatus = models.StatusSuccess
+		emailLog.ErrorMessage = ""
+		emailLog.ProcessedAt = &now
+		messagesForwarded.WithLabelValues(target.Name).Inc()
+		return
+	}
+
+	emailLog.ErrorMessage = fmt.Sprintf("转发邮件失败: %v", err)
+	if !isPermanent(err) {
+		if next := es.nextRetry(emailLog.Attempts, now); next != nil {
+			emailLog.ForwardStatus = models.StatusRetrying
+			emailLog.NextRetryAt = next
+			deliveryRetries.WithLabelValues(emailLog.TargetType).Inc()
+			return
+		}
+	}
+	emailLog.ForwardStatus = models.StatusFailed
+	emailLog.ProcessedAt = &now
+	messagesFailed.WithLabelValues(target.Name, reasonSendFailed).Inc()
+}
+
+// retryDeliveries 重试邮箱中已到重试时间的转发，重新读取原邮件后按目标当前的配置投递
+func (es *EmailService) retryDeliveries(ctx context.Context, mb mailbox, tenant *models.Tenant) error {
+	logger := utils.LoggerFromContext(ctx)
+	db := database.GetDB().WithContext(ctx)
+
+	var logs []models.EmailLog
+	err := db.Where("tenant_id = ? AND mailbox_id = ? AND forward_status = ? AND next_retry_at <= ?",
+		mb.tenantID, mb.id, models.StatusRetrying, time.Now()).
+		Order("next_retry_at").Limit(retryBatchSize).Find(&logs).Error
+	if err != nil {
+		return fmt.Errorf("查询待重试的邮件失败: %w", err)
+	}
+	if len(logs) > 0 {
+		logger.Infof("重试 %d 封转发失败的邮件", len(logs))
+	}
+
+	for i := range logs {
+		if ctx.Err() != nil || es.isClosing() {
+			break
+		}
+
+		msgCtx := utils.ContextWithLogFields(ctx, logrus.Fields{"message_id": logs[i].GmailMessageID, "attempt": logs[i].Attempts + 1})
+		if err := es.retryDelivery(msgCtx, mb, tenant, &logs[i]); err != nil {
+			if errors.Is(err, ErrQuotaExceeded) {
+				utils.LoggerFromContext(msgCtx).Warnf("%v，剩余的重试推迟到下次", err)
+				break
+			}
+			utils.LoggerFromContext(msgCtx).Errorf("重试转发失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// retryDelivery 重试一封邮件的转发，目标已删除或停用时直接标记为失败
+func (es *EmailService) retryDelivery(ctx context.Context, mb mailbox, tenant *models.Tenant, emailLog *models.EmailLog) (err error) {
+	ctx, span := tracer.Start(ctx, "email.retry", trace.WithAttributes(
+		attribute.String("gmail.message_id", emailLog.GmailMessageID),
+		attribute.Int("delivery.attempt", emailLog.Attempts+1),
+	))
+	defer func() { endSpan(span, err) }()
+
+	logger := utils.LoggerFromContext(ctx)
+	db := database.GetDB().WithContext(ctx)
+	previousStatus := emailLog.ForwardStatus
+
+	var target models.ForwardTarget
+	err = db.Where("tenant_id = ? AND is_active = ?", emailLog.TenantID, true).First(&target, emailLog.TargetID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		now := time.Now()
+		emailLog.ForwardStatus = models.StatusFailed
+		emailLog.ErrorMessage = "转发目标已删除或停用，不再重试"
+		emailLog.NextRetryAt = nil
+		emailLog.ProcessedAt = &now
+		messagesFailed.WithLabelValues(emailLog.ForwardTarget, reasonTargetNotFound).Inc()
+		if err := saveAttempt(db, emailLog); err != nil {
+			return err
+		}
+		es.publishLog(mb, LiveLogUpdated, emailLog, previousStatus)
+		es.emitResult(ctx, mb, emailLog)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if err := checkDailyForwardQuota(db, tenant); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	email, deliverErr := mb.gmail.GetMessage(ctx, emailLog.GmailMessageID)
+	if deliverErr == nil {
+		deliverErr = es.deliver(ctx, mb, email, emailLog.Keyword, &target, emailLog.Attempts+1)
+	}
+	emailLog.ForwardEmail = target.Email
+	emailLog.TargetType = target.Type
+	es.recordAttempt(emailLog, &target, deliverErr)
+	forwardDuration.WithLabelValues(emailLog.ForwardStatus).Observe(time.Since(start).Seconds())
+	span.SetAttributes(attribute.String("email.forward_status", emailLog.ForwardStatus))
+
+	if deliverErr != nil {
+		logger.Warnf("第 %d 次转发失败（%s）: %v", emailLog.Attempts, emailLog.ForwardStatus, deliverErr)
+	} else {
+		logger.Infof("第 %d 次转发成功到 %s", emailLog.Attempts, describeTarget(&target))
+	}
+	if err := saveAttempt(db, emailLog); err != nil {
+		return err
+	}
+	es.publishLog(mb, LiveLogUpdated, emailLog, previousStatus)
+	es.emitResult(ctx, mb, emailLog)
+	return nil
+}
+
+// saveAttempt 保存一次投递后的处理记录
+func saveAttempt(db *gorm.DB, emailLog *models.EmailLog) error {
+	err := db.Model(emailLog).
+		Select("forward_email", "target_type", "forward_status", "attempts", "next_retry_at", "error_message", "processed_at").
+		Updates(emailLog).Error
+	if err != nil {
+		return fmt.Errorf("保存邮件记录失败: %v", err)
+	}
+	return nil
+}
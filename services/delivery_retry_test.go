@@ -0,0 +1,94 @@
+package services
+
+import (
+	"email-forwarding/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextRetry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name        string
+		maxAttempts int
+		backoff     time.Duration
+		attempts    int
+		want        time.Duration // 0表示不再重试
+	}{
+		{"第一次失败后", 5, time.Minute, 1, time.Minute},
+		{"第二次失败后翻倍", 5, time.Minute, 2, 2 * time.Minute},
+		{"第四次失败后", 5, time.Minute, 4, 8 * time.Minute},
+		{"次数用完", 5, time.Minute, 5, 0},
+		{"超过最大次数", 5, time.Minute, 6, 0},
+		{"只尝试一次", 1, time.Minute, 1, 0},
+		{"未设置间隔", 5, 0, 1, 0},
+		{"不超过上限", 20, time.Minute, 10, maxRetryBackoff},
+		{"间隔本身超过上限", 5, 2 * time.Hour, 1, maxRetryBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := NewEmailService(nil, nil)
+			es.SetRetryPolicy(tt.maxAttempts, tt.backoff)
+
+			next := es.nextRetry(tt.attempts, now)
+			if tt.want == 0 {
+				if next != nil {
+					t.Fatalf("nextRetry = %s, 期望不再重试", next)
+				}
+				return
+			}
+			if next == nil {
+				t.Fatalf("nextRetry = nil, 期望 %s 后重试", tt.want)
+			}
+			if got := next.Sub(now); got != tt.want {
+				t.Errorf("重试间隔 = %s, 期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAttempt(t *testing.T) {
+	sendErr := errors.New("connection refused")
+	tests := []struct {
+		name       string
+		attempts   int // 本次投递之前已尝试的次数
+		err        error
+		wantStatus string
+		wantRetry  bool
+	}{
+		{"成功", 0, nil, models.StatusSuccess, false},
+		{"重试后成功", 2, nil, models.StatusSuccess, false},
+		{"第一次失败", 0, sendErr, models.StatusRetrying, true},
+		{"倒数第二次失败", 1, sendErr, models.StatusRetrying, true},
+		{"最后一次失败", 2, sendErr, models.StatusFailed, false},
+		{"不可重试的错误", 0, permanent(sendErr), models.StatusFailed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := NewEmailService(nil, nil)
+			es.SetRetryPolicy(3, time.Minute)
+
+			emailLog := &models.EmailLog{Attempts: tt.attempts, TargetType: models.TargetTypeWebhook, ErrorMessage: "上次的错误"}
+			es.recordAttempt(emailLog, &models.ForwardTarget{Name: "ops"}, tt.err)
+
+			if emailLog.Attempts != tt.attempts+1 {
+				t.Errorf("attempts = %d, 期望 %d", emailLog.Attempts, tt.attempts+1)
+			}
+			if emailLog.ForwardStatus != tt.wantStatus {
+				t.Errorf("forward_status = %s, 期望 %s", emailLog.ForwardStatus, tt.wantStatus)
+			}
+			if got := emailLog.NextRetryAt != nil; got != tt.wantRetry {
+				t.Errorf("next_retry_at = %v, 期望设置 = %v", emailLog.NextRetryAt, tt.wantRetry)
+			}
+			if got := emailLog.ProcessedAt != nil; got == tt.wantRetry {
+				t.Errorf("processed_at = %v，等待重试时不应设置，其他情况应设置", emailLog.ProcessedAt)
+			}
+			if (tt.err == nil) != (emailLog.ErrorMessage == "") {
+				t.Errorf("error_message = %q", emailLog.ErrorMessage)
+			}
+		})
+	}
+}
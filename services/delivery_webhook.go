@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"email-forwarding/models"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookPayloadVersion 通用webhook请求体的版本，字段有不兼容的修改时递增
+const WebhookPayloadVersion = "1"
+
+// maxInlineAttachmentSize base64方式内嵌附件的总大小上限，超出部分的附件只提供下载链接
+const maxInlineAttachmentSize = 10 << 20
+
+// 通用webhook请求头
+const (
+	webhookVersionHeader   = "X-Webhook-Version"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookPayload 通用webhook的请求体
+type WebhookPayload struct {
+	Version    string         `json:"version"`
+	Event      string         `json:"event"`       // 固定为 email.forward
+	DeliveryID string         `json:"delivery_id"` // 同一封邮件的多次重试相同，接收方可据此去重
+	Attempt    int            `json:"attempt"`     // 第几次尝试，从1开始
+	Timestamp  time.Time      `json:"timestamp"`
+	Message    WebhookMessage `json:"message"`
+	Routing    WebhookRouting `json:"routing"`
+}
+
+// WebhookMessage 原邮件的完整内容
+type WebhookMessage struct {
+	ID          string              `json:"id"`
+	ThreadID    string              `json:"thread_id"`
+	Mailbox     string              `json:"mailbox"` // 收到邮件的邮箱地址
+	Subject     string              `json:"subject"`
+	From        string              `json:"from"`
+	To          string              `json:"to"`
+	Date        time.Time           `json:"date"`
+	Link        string              `json:"link"` // 在Gmail网页版中打开原邮件的链接
+	Headers     []MessageHeader     `json:"headers"`
+	Text        string              `json:"text"`
+	HTML        string              `json:"html"`
+	Attachments []WebhookAttachment `json:"attachments"`
+}
+
+// WebhookAttachment 附件，url和content_base64二者有其一
+type WebhookAttachment struct {
+	Attachment
+	URL           string `json:"url,omitempty"`            // 下载地址，需要携带API密钥访问
+	ContentBase64 string `json:"content_base64,omitempty"` // 附件内容，标准base64编码
+}
+
+// WebhookRouting 路由结果：邮件标题解析出的关键字、目标名字以及最终匹配的转发目标
+type WebhookRouting struct {
+	Keyword    string `json:"keyword"`
+	TargetName string `json:"target_name"`
+	TargetID   uint   `json:"target_id"`
+	MailboxID  uint   `json:"mailbox_id"`
+	TenantID   uint   `json:"tenant_id"`
+}
+
+// WebhookDelivery 通用webhook，POST完整的邮件内容，设置了密钥时附加HMAC-SHA256签名
+type WebhookDelivery struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookDelivery 创建通用webhook投递
+func NewWebhookDelivery(client *http.Client) *WebhookDelivery {
+	return &WebhookDelivery{client: client, now: time.Now}
+}
+
+// Send 发送请求体，签名为 sha256=hex(HmacSHA256(secret, timestamp + "." + body))，timestamp为秒
+func (d *WebhookDelivery) Send(ctx context.Context, target *models.ForwardTarget, payload *WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return permanent(fmt.Errorf("webhook: 序列化消息失败: %v", err))
+	}
+
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	headers := map[string]string{
+		webhookVersionHeader:   payload.Version,
+		webhookDeliveryHeader:  payload.DeliveryID,
+		webhookTimestampHeader: timestamp,
+	}
+	if target.Secret != "" {
+		headers[webhookSignatureHeader] = "sha256=" + webhookSignature(target.Secret, timestamp, body)
+	}
+
+	if _, err := postBody(ctx, d.client, target.WebhookURL, body, headers); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
+
+// webhookSignature 计算通用webhook的签名
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookPayload 生成通用webhook的请求体
+// 附件按目标的attachment_mode以base64内嵌或提供下载链接，内嵌总大小超过上限后的附件改为下载链接
+func (es *EmailService) webhookPayload(ctx context.Context, mb mailbox, email *EmailMessage, keyword string, target *models.ForwardTarget, attempt int) (*WebhookPayload, error) {
+	_, targetName := es.parseEmailSubject(email.Subject)
+	payload := &WebhookPayload{
+		Version:    WebhookPayloadVersion,
+		Event:      "email.forward",
+		DeliveryID: email.ID,
+		Attempt:    attempt,
+		Timestamp:  time.Now(),
+		Message: WebhookMessage{
+			ID:          email.ID,
+			ThreadID:    email.ThreadID,
+			Mailbox:     mb.gmail.UserEmail(),
+			Subject:     email.Subject,
+			From:        email.From,
+			To:          email.To,
+			Date:        email.ReceivedAt,
+			Link:        gmailMessageLink(mb.gmail.UserEmail(), email.ID),
+			Headers:     email.Headers,
+			Text:        email.TextBody,
+			HTML:        email.HTMLBody,
+			Attachments: []WebhookAttachment{},
+		},
+		Routing: WebhookRouting{
+			Keyword:    keyword,
+			TargetName: targetName,
+			TargetID:   target.ID,
+			MailboxID:  mb.id,
+			TenantID:   mb.tenantID,
+		},
+	}
+
+	if payload.Message.Headers == nil {
+		payload.Message.Headers = []MessageHeader{}
+	}
+
+	var inlined int64
+	for _, attachment := range email.Attachments {
+		item := WebhookAttachment{Attachment: attachment}
+		if target.AttachmentMode == models.AttachmentModeBase64 && inlined+attachment.Size <= maxInlineAttachmentSize {
+			_, data, err := mb.gmail.GetAttachment(ctx, email.ID, attachment.PartID)
+			if err != nil {
+				return nil, err
+			}
+			item.ContentBase64 = base64.StdEncoding.EncodeToString(data)
+			inlined += attachment.Size
+		} else {
+			item.URL = es.attachmentURL(email.ID, attachment.PartID)
+		}
+		payload.Message.Attachments = append(payload.Message.Attachments, item)
+	}
+	return payload, nil
+}
+
+// attachmentURL 附件的下载地址，未配置 server.public_url 时为相对路径
+func (es *EmailService) attachmentURL(messageID, partID string) string {
+	es.mu.Lock()
+	publicURL := es.publicURL
+	es.mu.Unlock()
+
+	return strings.TrimRight(publicURL, "/") + "/api/v1/emails/messages/" + url.PathEscape(messageID) + "/attachments/" + url.PathEscape(partID)
+}
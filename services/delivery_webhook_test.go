@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"email-forwarding/models"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{"普通请求体", "secret", "1700000000", `{"event":"email.forward"}`, "3b9245972b0a41283d2ccae87dc213cb8be56b4661277acc65dba5762e25a093"},
+		{"密钥和时间戳不同", "s3cr3t", "1700000001", `{"event":"email.forward"}`, "f9d6553e0dd0612fd250f6245d8be1a8c6353dfc0211c446dafe29cd9c5c39c3"},
+		{"空请求体", "secret", "1700000000", "", "4bc5f74d868b97888288889c5d9d65df02526f94c1592a79fdf4fe8b26e311e5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhookSignature(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("webhookSignature = %s, 期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliverySignatureHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{"设置密钥", "secret"},
+		{"未设置密钥", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				body, _ = io.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+
+			d := NewWebhookDelivery(srv.Client())
+			d.now = func() time.Time { return testNow }
+			payload := &WebhookPayload{Version: WebhookPayloadVersion, Event: "email.forward", DeliveryID: "msg-1"}
+			if err := d.Send(context.Background(), &models.ForwardTarget{WebhookURL: srv.URL, Secret: tt.secret}, payload); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := header.Get(webhookTimestampHeader); got != "1700000000" {
+				t.Errorf("%s = %q", webhookTimestampHeader, got)
+			}
+			got := header.Get(webhookSignatureHeader)
+			if tt.secret == "" {
+				if got != "" {
+					t.Errorf("未设置密钥时不应签名: %q", got)
+				}
+				return
+			}
+			mac := hmac.New(sha256.New, []byte(tt.secret))
+			mac.Write([]byte("1700000000." + string(body)))
+			if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); got != want {
+				t.Errorf("%s = %q, 期望 %q", webhookSignatureHeader, got, want)
+			}
+		})
+	}
+}
@@ -1,129 +1,386 @@
 package services
 
 import (
+	"context"
 	"email-forwarding/database"
 	"email-forwarding/models"
 	"email-forwarding/utils"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrShuttingDown 服务正在关闭，不再接受新的处理任务
+	ErrShuttingDown = newError(KindUnavailable, "shutting_down", "服务正在关闭，不再接受新的处理任务", "service is shutting down")
+	// ErrTargetNotFound 转发目标不存在
+	ErrTargetNotFound = newError(KindNotFound, "target_not_found", "转发目标不存在", "forward target not found")
+	// ErrTargetEmailExists 转发目标邮箱已被使用
+	ErrTargetEmailExists = newError(KindConflict, "target_email_exists", "转发目标邮箱已存在", "forward target email already exists")
+	// ErrInvalidTarget 转发目标参数无效
+	ErrInvalidTarget = newError(KindValidation, "invalid_target", "转发目标参数无效", "invalid forward target")
+	// ErrAttachmentNotFound 邮件或附件不存在
+	ErrAttachmentNotFound = newError(KindNotFound, "attachment_not_found", "附件不存在", "attachment not found")
 )
 
 type EmailService struct {
 	gmailService *GmailService
+	keywords     []string            // 全局关键字白名单，为空时不限制
+	deliveries   map[string]Delivery // 群机器人的投递方式
+	webhook      *WebhookDelivery    // 通用webhook
+	events       *EventService       // 事件订阅，为nil时不发送事件
+	bus          *EventBus           // 实时事件总线
+
+	mu           sync.Mutex
+	closing      bool
+	inflight     sync.WaitGroup
+	maxAttempts  int           // 转发最多尝试的次数（含第一次）
+	retryBackoff time.Duration // 第一次重试前的等待时间
+	publicURL    string        // 对外访问地址，用于生成附件下载链接
 }
 
 // NewEmailService 创建邮件服务实例
-func NewEmailService(gmailService *GmailService) *EmailService {
+// keywords 为全局关键字白名单，非空时标题关键字必须包含其中之一才会转发
+func NewEmailService(gmailService *GmailService, keywords []string) *EmailService {
 	return &EmailService{
 		gmailService: gmailService,
+		keywords:     keywords,
+		deliveries:   newDeliveries(&http.Client{}),
+		webhook:      NewWebhookDelivery(&http.Client{}),
+		bus:          NewEventBus(),
+		maxAttempts:  1,
 	}
 }
 
-// ProcessEmails 处理邮件
-func (es *EmailService) ProcessEmails() error {
-	logger := utils.GetLogger()
-	
+// mailbox 正在处理的邮箱
+type mailbox struct {
+	id       uint // 默认邮箱为 models.DefaultMailboxID
+	tenantID uint // 默认邮箱属于 models.DefaultTenantID
+	gmail    *GmailService
+}
+
+// SetEventService 设置事件服务，之后处理邮件和修改转发目标时向订阅方发送事件
+func (es *EmailService) SetEventService(events *EventService) {
+	es.events = events
+}
+
+// EventBus 返回实时事件总线，处理邮件和修改转发目标时向其发布事件
+func (es *EmailService) EventBus() *EventBus {
+	return es.bus
+}
+
+// publishLog 向事件总线发布邮件处理记录的新增或状态变化，不含邮件正文
+func (es *EmailService) publishLog(mb mailbox, eventType string, emailLog *models.EmailLog, previousStatus string) {
+	data := LogEvent{Mailbox: mb.gmail.UserEmail(), PreviousStatus: previousStatus, Log: *emailLog}
+	data.Log.Content = ""
+	es.bus.Publish(emailLog.TenantID, eventType, emailLog.ForwardTarget, emailLog.ForwardStatus, data)
+}
+
+// publishRun 向事件总线发布一次邮箱处理的结果
+func (es *EmailService) publishRun(mb mailbox, runID string, fetched int, start time.Time, err error) {
+	data := RunEvent{
+		RunID:      runID,
+		MailboxID:  mb.id,
+		Mailbox:    mb.gmail.UserEmail(),
+		Status:     "success",
+		Fetched:    fetched,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		data.Status = "failed"
+		data.Error = err.Error()
+	}
+	es.bus.Publish(mb.tenantID, LiveRunCompleted, "", data.Status, data)
+}
+
+// emit 发送事件，未设置事件服务时忽略
+func (es *EmailService) emit(ctx context.Context, tenantID uint, eventType string, data interface{}) {
+	if es.events != nil {
+		es.events.Emit(ctx, tenantID, eventType, data)
+	}
+}
+
+// emitMessage 根据邮件日志发送 message.* 事件
+func (es *EmailService) emitMessage(ctx context.Context, mb mailbox, eventType string, emailLog *models.EmailLog) {
+	es.emit(ctx, emailLog.TenantID, eventType, newMessageEvent(mb.gmail.UserEmail(), emailLog))
+}
+
+// emitResult 根据转发结果发送 message.forwarded 或 message.failed 事件，等待重试的失败也会发送
+func (es *EmailService) emitResult(ctx context.Context, mb mailbox, emailLog *models.EmailLog) {
+	eventType := models.EventMessageFailed
+	if emailLog.ForwardStatus == models.StatusSuccess {
+		eventType = models.EventMessageForwarded
+	}
+	es.emitMessage(ctx, mb, eventType, emailLog)
+}
+
+// emitTarget 发送 target.changed 事件
+func (es *EmailService) emitTarget(ctx context.Context, action string, target *models.ForwardTarget) {
+	data := TargetEvent{Action: action, Actor: actorFromContext(ctx), Target: target}
+	es.emit(ctx, target.TenantID, models.EventTargetChanged, data)
+	es.bus.Publish(target.TenantID, LiveTargetChanged, target.Name, "", data)
+}
+
+// ProcessEmails 处理默认邮箱（配置文件中的邮箱）的邮件，默认邮箱属于默认租户
+// ctx被取消或服务开始关闭时，不再处理剩余邮件，未处理的邮件保持未读，下次再处理
+func (es *EmailService) ProcessEmails(ctx context.Context) error {
+	if !tenantAllowed(ctx, models.DefaultTenantID) {
+		return fmt.Errorf("%w: 默认邮箱不属于当前租户", ErrMailboxNotFound)
+	}
+	return es.processMailbox(ctx, mailbox{id: models.DefaultMailboxID, tenantID: models.DefaultTenantID, gmail: es.gmailService})
+}
+
+// processMailbox 拉取并处理一个邮箱的未读邮件，转发邮件也从该邮箱发出
+func (es *EmailService) processMailbox(ctx context.Context, mb mailbox) (err error) {
+	// 每次运行生成一个run_id，本次运行的所有日志都会带上它
+	runID := utils.NewID()
+	ctx = utils.ContextWithLogFields(ctx, logrus.Fields{"run_id": runID, "mailbox": mb.gmail.UserEmail()})
+	logger := utils.LoggerFromContext(ctx)
+
+	if !es.acquire() {
+		return ErrShuttingDown
+	}
+	defer es.inflight.Done()
+
+	ctx, span := tracer.Start(ctx, "email.process_run", trace.WithAttributes(
+		attribute.String("run_id", runID),
+		attribute.Int("mailbox.id", int(mb.id)),
+		attribute.Int("tenant.id", int(mb.tenantID)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	fetched := 0
+	defer func() { es.publishRun(mb, runID, fetched, start, err) }()
+
+	// 租户停用后不再处理其邮箱
+	tenant, err := loadTenant(database.GetDB().WithContext(ctx), mb.tenantID)
+	if err != nil {
+		return err
+	}
+	if !tenant.IsActive {
+		return fmt.Errorf("%w: %d", ErrTenantInactive, tenant.ID)
+	}
+
 	// 获取未读邮件（使用配置的数量限制）
-	emails, err := es.gmailService.GetUnreadEmails()
+	emails, err := mb.gmail.GetUnreadEmails(ctx)
 	if err != nil {
-		return fmt.Errorf("获取未读邮件失败: %v", err)
+		return fmt.Errorf("获取未读邮件失败: %w", err)
 	}
 
+	fetched = len(emails)
 	logger.Infof("获取到 %d 封未读邮件", len(emails))
+	messagesFetched.Add(float64(len(emails)))
+	span.SetAttributes(attribute.Int("messages.fetched", len(emails)))
+
+	for i, email := range emails {
+		if ctx.Err() != nil || es.isClosing() {
+			logger.Warnf("处理被中断，剩余 %d 封邮件将在下次处理", len(emails)-i)
+			break
+		}
+
+		msgCtx := utils.ContextWithLogFields(ctx, logrus.Fields{
+			"message_id":     email.ID,
+			"correlation_id": runID + "-" + email.ID,
+		})
+		if err := es.processEmail(msgCtx, mb, tenant, email); err != nil {
+			utils.LoggerFromContext(msgCtx).Errorf("处理邮件失败: %v", err)
+		}
+	}
 
-	for _, email := range emails {
-		if err := es.processEmail(email); err != nil {
-			logger.Errorf("处理邮件失败 [%s]: %v", email.ID, err)
+	// 之前转发失败的邮件到了重试时间后，在同一次运行中重试
+	if ctx.Err() == nil && !es.isClosing() {
+		if err := es.retryDeliveries(ctx, mb, tenant); err != nil {
+			logger.Errorf("重试转发失败: %v", err)
 		}
 	}
 
 	return nil
 }
 
-// processEmail 处理单封邮件
-func (es *EmailService) processEmail(email *EmailMessage) error {
-	logger := utils.GetLogger()
-	db := database.GetDB()
+// Shutdown 停止接受新的处理任务，并等待正在处理的邮件完成
+// 超过ctx的截止时间仍未完成时返回ctx的错误
+func (es *EmailService) Shutdown(ctx context.Context) error {
+	es.mu.Lock()
+	es.closing = true
+	es.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		es.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquire 登记一个处理任务，服务关闭后返回false
+func (es *EmailService) acquire() bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if es.closing {
+		return false
+	}
+	es.inflight.Add(1)
+	return true
+}
+
+// isClosing 服务是否正在关闭
+func (es *EmailService) isClosing() bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.closing
+}
+
+// processEmail 处理单封邮件，租户当天的转发数量达到配额时跳过邮件并保持未读，次日再处理
+func (es *EmailService) processEmail(ctx context.Context, mb mailbox, tenant *models.Tenant, email *EmailMessage) (err error) {
+	ctx, span := tracer.Start(ctx, "email.process", trace.WithAttributes(attribute.String("gmail.message_id", email.ID)))
+	defer func() { endSpan(span, err) }()
 
-	// 检查邮件是否已处理
+	// 日志和处理记录都带上trace_id，便于从日志或记录跳转到链路
+	var traceID string
+	if sc := span.SpanContext(); sc.IsValid() {
+		traceID = sc.TraceID().String()
+		ctx = utils.ContextWithLogFields(ctx, logrus.Fields{"trace_id": traceID})
+	}
+
+	logger := utils.LoggerFromContext(ctx)
+	db := database.GetDB().WithContext(ctx)
+	start := time.Now()
+
+	// 检查邮件是否已处理，同一封邮件投递到多个邮箱时每个邮箱各处理一次
 	var existingLog models.EmailLog
-	if err := db.Where("gmail_message_id = ?", email.ID).First(&existingLog).Error; err == nil {
-		logger.Infof("邮件 [%s] 已处理，跳过", email.ID)
+	if err := db.Where("mailbox_id = ? AND gmail_message_id = ?", mb.id, email.ID).First(&existingLog).Error; err == nil {
+		logger.Info("邮件已处理，跳过")
+		messagesSkipped.WithLabelValues(reasonAlreadyProcessed).Inc()
 		return nil
 	}
 
 	// 创建邮件日志记录
 	emailLog := models.EmailLog{
+		TenantID:       mb.tenantID,
+		MailboxID:      mb.id,
 		GmailMessageID: email.ID,
 		Subject:        email.Subject,
 		FromEmail:      email.From,
 		ToEmail:        email.To,
 		Content:        email.Body,
 		ForwardStatus:  models.StatusPending,
+		TraceID:        traceID,
 	}
+	// message.received 在保存处理记录或通过配额检查后才发送，因配额推迟处理的邮件不会每次运行都重复发送
+	received := newMessageEvent(mb.gmail.UserEmail(), &emailLog)
+	emitReceived := func() { es.emit(ctx, emailLog.TenantID, models.EventMessageReceived, received) }
 
 	// 解析邮件标题，提取关键字和转发目标
 	keyword, targetName := es.parseEmailSubject(email.Subject)
 	
-	if keyword == "" || targetName == "" {
+	if keyword == "" || targetName == "" || !es.allowKeyword(keyword) {
 		// 不符合转发规则，标记邮件为已读但不转发
 		emailLog.ForwardStatus = models.StatusFailed
 		emailLog.ErrorMessage = "邮件标题不符合转发规则"
+		if keyword != "" && targetName != "" {
+			emailLog.ErrorMessage = fmt.Sprintf("关键字 %s 不在全局关键字列表中", keyword)
+		}
 		
 		if err := db.Create(&emailLog).Error; err != nil {
 			return fmt.Errorf("保存邮件记录失败: %v", err)
 		}
 
+		es.publishLog(mb, LiveLogCreated, &emailLog, "")
+		emitReceived()
+		es.emitMessage(ctx, mb, models.EventMessageUnmatched, &emailLog)
+
 		// 标记为已读
-		if err := es.gmailService.MarkAsRead(email.ID); err != nil {
+		if err := mb.gmail.MarkAsRead(ctx, email.ID); err != nil {
 			logger.Errorf("标记邮件为已读失败: %v", err)
 		}
 		
-		logger.Infof("邮件 [%s] 不符合转发规则，已跳过", email.ID)
+		logger.Info("邮件不符合转发规则，已跳过")
+		messagesSkipped.WithLabelValues(reasonNoMatch).Inc()
 		return nil
 	}
 
 	emailLog.Keyword = keyword
 	emailLog.ForwardTarget = targetName
+	span.SetAttributes(attribute.String("email.keyword", keyword), attribute.String("email.target_name", targetName))
 
 	// 查找转发目标
-	target, err := es.findForwardTarget(keyword, targetName)
+	target, err := es.findForwardTarget(ctx, mb, keyword, targetName)
 	if err != nil {
 		emailLog.ForwardStatus = models.StatusFailed
 		emailLog.ErrorMessage = fmt.Sprintf("查找转发目标失败: %v", err)
+		// 目标名字来自邮件标题，不作为标签值，避免指标基数失控
+		messagesFailed.WithLabelValues("", reasonTargetNotFound).Inc()
 		
 		if err := db.Create(&emailLog).Error; err != nil {
 			return fmt.Errorf("保存邮件记录失败: %v", err)
 		}
+		es.publishLog(mb, LiveLogCreated, &emailLog, "")
+		emitReceived()
+		es.emitMessage(ctx, mb, models.EventMessageUnmatched, &emailLog)
 		
 		return fmt.Errorf("查找转发目标失败: %v", err)
 	}
 
 	emailLog.ForwardEmail = target.Email
+	emailLog.TargetType = target.Type
+	emailLog.TargetID = target.ID
 
-	// 转发邮件
-	if err := es.forwardEmail(email, target); err != nil {
-		emailLog.ForwardStatus = models.StatusFailed
-		emailLog.ErrorMessage = fmt.Sprintf("转发邮件失败: %v", err)
-		
-		logger.Errorf("转发邮件失败 [%s]: %v", email.ID, err)
-	} else {
-		emailLog.ForwardStatus = models.StatusSuccess
-		now := time.Now()
-		emailLog.ProcessedAt = &now
-		
-		logger.Infof("邮件 [%s] 转发成功到 %s", email.ID, target.Email)
+	if err := checkDailyForwardQuota(db, tenant); err != nil {
+		if !errors.Is(err, ErrQuotaExceeded) {
+			return fmt.Errorf("检查转发配额失败: %v", err)
+		}
+		logger.Warnf("%v，邮件保持未读", err)
+		messagesSkipped.WithLabelValues(reasonQuotaExceeded).Inc()
+		return nil
 	}
+	emitReceived()
+
+	// 转发邮件，失败时根据重试策略安排重试，邮件照常标记为已读，重试时重新读取原邮件
+	deliverErr := es.deliver(ctx, mb, email, keyword, target, 1)
+	es.recordAttempt(&emailLog, target, deliverErr)
+	switch emailLog.ForwardStatus {
+	case models.StatusSuccess:
+		logger.Infof("邮件转发成功到 %s", describeTarget(target))
+	case models.StatusRetrying:
+		logger.Warnf("转发邮件失败，将于 %s 重试: %v", emailLog.NextRetryAt.Format("2006-01-02 15:04:05"), deliverErr)
+	default:
+		logger.Errorf("转发邮件失败: %v", deliverErr)
+	}
+	if deliverErr != nil {
+		span.RecordError(deliverErr)
+	}
+	forwardDuration.WithLabelValues(emailLog.ForwardStatus).Observe(time.Since(start).Seconds())
+	span.SetAttributes(attribute.String("email.forward_status", emailLog.ForwardStatus))
 
 	// 保存处理记录
 	if err := db.Create(&emailLog).Error; err != nil {
+		messagesFailed.WithLabelValues(target.Name, reasonSaveFailed).Inc()
 		return fmt.Errorf("保存邮件记录失败: %v", err)
 	}
+	es.publishLog(mb, LiveLogCreated, &emailLog, "")
+	es.emitResult(ctx, mb, &emailLog)
 
 	// 标记邮件为已读
-	if err := es.gmailService.MarkAsRead(email.ID); err != nil {
+	if err := mb.gmail.MarkAsRead(ctx, email.ID); err != nil {
 		logger.Errorf("标记邮件为已读失败: %v", err)
 	}
 
@@ -145,33 +402,75 @@ func (es *EmailService) parseEmailSubject(subject string) (keyword, targetName s
 	return keyword, targetName
 }
 
-// findForwardTarget 查找转发目标
-func (es *EmailService) findForwardTarget(keyword, targetName string) (*models.ForwardTarget, error) {
-	db := database.GetDB()
-	
-	var target models.ForwardTarget
-	
-	// 首先根据名字精确匹配
-	if err := db.Where("name = ? AND is_active = ?", targetName, true).First(&target).Error; err == nil {
-		// 验证关键字是否匹配
-		if es.matchKeyword(keyword, target.Keywords) {
-			return &target, nil
+// findForwardTarget 查找转发目标，使用内存中缓存的启用目标，不再逐封查询数据库
+// 只在邮箱所属租户中适用于该邮箱的目标中查找，指定了该邮箱的目标优先于适用于所有邮箱的目标
+func (es *EmailService) findForwardTarget(ctx context.Context, mb mailbox, keyword, targetName string) (*models.ForwardTarget, error) {
+	cached, err := forwardTargets.get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询转发目标失败: %v", err)
+	}
+	targets := targetsForMailbox(cached, mb.tenantID, mb.id)
+
+	// 首先根据名字精确匹配（与数据库排序规则一致，不区分大小写）
+	for i := range targets {
+		if strings.EqualFold(targets[i].Name, targetName) {
+			// 验证关键字是否匹配
+			if es.matchKeyword(keyword, targets[i].Keywords) {
+				target := targets[i]
+				return &target, nil
+			}
+			break
 		}
 	}
 
 	// 如果名字匹配失败，尝试根据关键字模糊匹配
-	var targets []models.ForwardTarget
-	if err := db.Where("is_active = ?", true).Find(&targets).Error; err != nil {
-		return nil, fmt.Errorf("查询转发目标失败: %v", err)
+	for i := range targets {
+		if es.matchKeyword(keyword, targets[i].Keywords) && strings.Contains(strings.ToLower(targets[i].Name), strings.ToLower(targetName)) {
+			target := targets[i]
+			return &target, nil
+		}
 	}
 
+	return nil, fmt.Errorf("未找到匹配的转发目标，关键字: %s, 目标名字: %s", keyword, targetName)
+}
+
+// targetsForMailbox 筛选租户中适用于邮箱的转发目标，指定了该邮箱的目标排在前面
+// 返回新的切片，不修改缓存
+func targetsForMailbox(targets []models.ForwardTarget, tenantID, mailboxID uint) []models.ForwardTarget {
+	result := make([]models.ForwardTarget, 0, len(targets))
+	if mailboxID != models.DefaultMailboxID {
+		for _, t := range targets {
+			if t.TenantID == tenantID && t.MailboxID == mailboxID {
+				result = append(result, t)
+			}
+		}
+	}
 	for _, t := range targets {
-		if es.matchKeyword(keyword, t.Keywords) && strings.Contains(strings.ToLower(t.Name), strings.ToLower(targetName)) {
-			return &t, nil
+		if t.TenantID == tenantID && t.MailboxID == 0 {
+			result = append(result, t)
 		}
 	}
+	return result
+}
 
-	return nil, fmt.Errorf("未找到匹配的转发目标，关键字: %s, 目标名字: %s", keyword, targetName)
+// allowKeyword 检查关键字是否在全局关键字白名单中，未配置白名单时全部允许
+func (es *EmailService) allowKeyword(keyword string) bool {
+	es.mu.Lock()
+	keywords := es.keywords
+	es.mu.Unlock()
+
+	if len(keywords) == 0 {
+		return true
+	}
+	return es.matchKeyword(keyword, strings.Join(keywords, ","))
+}
+
+// SetKeywords 替换全局关键字白名单，正在处理的邮件之后的邮件立即使用新的白名单
+func (es *EmailService) SetKeywords(keywords []string) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.keywords = append([]string(nil), keywords...)
 }
 
 // matchKeyword 匹配关键字
@@ -191,12 +490,53 @@ func (es *EmailService) matchKeyword(keyword, targetKeywords string) bool {
 	return false
 }
 
+// deliver 按转发目标的投递方式发送邮件，email类型转发原邮件，通用webhook发送完整内容，群机器人发送摘要卡片
+// attempt为第几次尝试，从1开始
+func (es *EmailService) deliver(ctx context.Context, mb mailbox, email *EmailMessage, keyword string, target *models.ForwardTarget, attempt int) (err error) {
+	if target.Type == models.TargetTypeEmail || target.Type == "" {
+		return es.forwardEmail(ctx, mb, email, target)
+	}
+
+	ctx, span := tracer.Start(ctx, "delivery.send", trace.WithAttributes(
+		attribute.String("target.type", target.Type),
+		attribute.Int("delivery.attempt", attempt),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if target.Type == models.TargetTypeWebhook {
+		payload, err := es.webhookPayload(ctx, mb, email, keyword, target, attempt)
+		if err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+		return es.webhook.Send(ctx, target, payload)
+	}
+
+	delivery, ok := es.deliveries[target.Type]
+	if !ok {
+		return permanent(fmt.Errorf("不支持的投递方式: %s", target.Type))
+	}
+	return delivery.Deliver(ctx, target, newSummary(mb.gmail.UserEmail(), email, keyword, target))
+}
+
+// describeTarget 日志中显示的转发目标，email类型为邮箱，其他类型为投递方式和名字
+func describeTarget(target *models.ForwardTarget) string {
+	if target.Type == models.TargetTypeEmail || target.Type == "" {
+		return target.Email
+	}
+	return target.Type + ":" + target.Name
+}
+
 // forwardEmail 转发邮件
-func (es *EmailService) forwardEmail(email *EmailMessage, target *models.ForwardTarget) error {
-	// 构建转发邮件的主题和内容
-	forwardSubject := fmt.Sprintf("[转发] %s", email.Subject)
+func (es *EmailService) forwardEmail(ctx context.Context, mb mailbox, email *EmailMessage, target *models.ForwardTarget) error {
+	forwardSubject, forwardBody := forwardContent(email)
+	return mb.gmail.SendEmail(ctx, target.Email, forwardSubject, forwardBody)
+}
+
+// forwardContent 构建转发邮件的主题和HTML内容
+func forwardContent(email *EmailMessage) (subject, body string) {
+	subject = fmt.Sprintf("[转发] %s", email.Subject)
 	
-	forwardBody := fmt.Sprintf(`
+	body = fmt.Sprintf(`
 		<div style="border-left: 4px solid #ccc; padding-left: 10px; margin: 10px 0;">
 			<h3>原邮件信息</h3>
 			<p><strong>发件人:</strong> %s</p>
@@ -218,21 +558,47 @@ func (es *EmailService) forwardEmail(email *EmailMessage, target *models.Forward
 		email.Body,
 	)
 
-	return es.gmailService.SendEmail(target.Email, forwardSubject, forwardBody)
+	return subject, body
+}
+
+// EmailLogFilter 邮件日志查询条件
+type EmailLogFilter struct {
+	Status    string
+	MailboxID *uint      // 为nil时不限制邮箱
+	Search    string     // 对主题、发件人和转发目标名字做模糊匹配
+	From      *time.Time // 只包含此时间及之后记录的日志
+	To        *time.Time // 只包含此时间之前记录的日志
+}
+
+// scope 按查询条件筛选邮件日志，日志列表和导出共用
+func (f EmailLogFilter) scope(db *gorm.DB) *gorm.DB {
+	if f.Status != "" {
+		db = db.Where("forward_status = ?", f.Status)
+	}
+	if f.MailboxID != nil {
+		db = db.Where("mailbox_id = ?", *f.MailboxID)
+	}
+	if search := strings.TrimSpace(f.Search); search != "" {
+		like := "%" + search + "%"
+		db = db.Where("subject LIKE ? OR from_email LIKE ? OR forward_target LIKE ?", like, like, like)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+	return db
 }
 
-// GetEmailLogs 获取邮件处理日志
-func (es *EmailService) GetEmailLogs(page, pageSize int, status string) ([]models.EmailLog, int64, error) {
+// GetEmailLogs 获取调用方所属租户的邮件处理日志
+func (es *EmailService) GetEmailLogs(ctx context.Context, filter EmailLogFilter, page, pageSize int) ([]models.EmailLog, int64, error) {
 	db := database.GetDB()
 	
 	var logs []models.EmailLog
 	var total int64
 	
-	query := db.Model(&models.EmailLog{})
-	
-	if status != "" {
-		query = query.Where("forward_status = ?", status)
-	}
+	query := db.Model(&models.EmailLog{}).Scopes(tenantScope(ctx), filter.scope)
 	
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
@@ -248,41 +614,341 @@ func (es *EmailService) GetEmailLogs(page, pageSize int, status string) ([]model
 	return logs, total, nil
 }
 
-// GetForwardTargets 获取转发目标列表
-func (es *EmailService) GetForwardTargets() ([]models.ForwardTarget, error) {
+// ForwardTargetPatch 转发目标的部分更新，只有非nil的字段会被写入
+type ForwardTargetPatch struct {
+	Name           *string `json:"name"`
+	Type           *string `json:"type"`
+	Email          *string `json:"email"`
+	WebhookURL     *string `json:"webhook_url"`
+	Secret         *string `json:"secret"`
+	AttachmentMode *string `json:"attachment_mode"`
+	Keywords       *string `json:"keywords"`
+	IsActive       *bool   `json:"is_active"`
+	MailboxID      *uint   `json:"mailbox_id"` // 0表示适用于所有邮箱
+}
+
+// GetForwardTargets 获取调用方所属租户的转发目标列表
+// includeInactive为false时只返回启用的目标，search对名称、邮箱和关键字做模糊匹配
+// mailboxID不为nil时只返回适用于该邮箱的目标（包括适用于所有邮箱的目标）
+func (es *EmailService) GetForwardTargets(ctx context.Context, includeInactive bool, search string, mailboxID *uint) ([]models.ForwardTarget, error) {
 	db := database.GetDB()
 	
+	query := db.Model(&models.ForwardTarget{}).Scopes(tenantScope(ctx))
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	if mailboxID != nil {
+		query = query.Where("mailbox_id IN ?", []uint{models.DefaultMailboxID, *mailboxID})
+	}
+	if search = strings.TrimSpace(search); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("name LIKE ? OR email LIKE ? OR keywords LIKE ?", like, like, like)
+	}
+
 	var targets []models.ForwardTarget
-	if err := db.Where("is_active = ?", true).Find(&targets).Error; err != nil {
+	if err := query.Order("id").Find(&targets).Error; err != nil {
 		return nil, err
 	}
 	
 	return targets, nil
 }
 
-// CreateForwardTarget 创建转发目标
-func (es *EmailService) CreateForwardTarget(target *models.ForwardTarget) error {
+// GetForwardTarget 根据ID获取调用方所属租户的转发目标
+func (es *EmailService) GetForwardTarget(ctx context.Context, id uint) (*models.ForwardTarget, error) {
 	db := database.GetDB()
-	
-	// 检查邮箱是否已存在
-	var existingTarget models.ForwardTarget
-	if err := db.Where("email = ?", target.Email).First(&existingTarget).Error; err == nil {
-		return fmt.Errorf("邮箱 %s 已存在", target.Email)
+
+	var target models.ForwardTarget
+	if err := db.Scopes(tenantScope(ctx)).First(&target, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrTargetNotFound, id)
+		}
+		return nil, err
 	}
-	
-	return db.Create(target).Error
+
+	return &target, nil
 }
 
-// UpdateForwardTarget 更新转发目标
-func (es *EmailService) UpdateForwardTarget(id uint, target *models.ForwardTarget) error {
+// CreateForwardTarget 在调用方所属租户下创建转发目标
+func (es *EmailService) CreateForwardTarget(ctx context.Context, target *models.ForwardTarget) error {
+	// 结束后使转发目标缓存失效，下次匹配时重新加载
+	defer forwardTargets.invalidate()
+
 	db := database.GetDB()
+
+	if err := normalizeForwardTarget(target); err != nil {
+		return err
+	}
+	target.TenantID = tenantFromContext(ctx)
 	
-	return db.Model(&models.ForwardTarget{}).Where("id = ?", id).Updates(target).Error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTargetQuota(tx, target.TenantID); err != nil {
+			return err
+		}
+		if target.Type == models.TargetTypeEmail {
+			if err := checkTargetEmailUnique(tx, target.TenantID, target.Email, 0); err != nil {
+				return err
+			}
+		}
+		if err := checkTargetMailbox(tx, target.TenantID, target.MailboxID); err != nil {
+			return err
+		}
+
+		if err := tx.Create(target).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, target.TenantID, models.AuditActionCreate, models.EntityForwardTarget, target.ID, nil, target)
+	})
+	if err != nil {
+		return err
+	}
+
+	es.emitTarget(ctx, models.AuditActionCreate, target)
+	return nil
+}
+
+// UpdateForwardTarget 整体更新转发目标，所有字段（包括is_active=false）都会被写入
+// 密钥不会在接口中返回，为空时保持原值，避免先查询再整体更新时被清空
+func (es *EmailService) UpdateForwardTarget(ctx context.Context, id uint, target *models.ForwardTarget) (*models.ForwardTarget, error) {
+	patch := ForwardTargetPatch{
+		Name:           &target.Name,
+		Type:           &target.Type,
+		Email:          &target.Email,
+		WebhookURL:     &target.WebhookURL,
+		AttachmentMode: &target.AttachmentMode,
+		Keywords:       &target.Keywords,
+		IsActive:       &target.IsActive,
+		MailboxID:      &target.MailboxID,
+	}
+	if target.Secret != "" {
+		patch.Secret = &target.Secret
+	}
+	return es.PatchForwardTarget(ctx, id, patch)
+}
+
+// SetForwardTargetActive 启用或停用转发目标
+func (es *EmailService) SetForwardTargetActive(ctx context.Context, id uint, active bool) (*models.ForwardTarget, error) {
+	return es.PatchForwardTarget(ctx, id, ForwardTargetPatch{IsActive: &active})
+}
+
+// PatchForwardTarget 按字段掩码更新转发目标
+func (es *EmailService) PatchForwardTarget(ctx context.Context, id uint, patch ForwardTargetPatch) (*models.ForwardTarget, error) {
+	// 结束后使转发目标缓存失效，下次匹配时重新加载
+	defer forwardTargets.invalidate()
+
+	db := database.GetDB()
+
+	var after models.ForwardTarget
+	var changed bool // 没有需要修改的字段时不发送事件
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before models.ForwardTarget
+		if err := tx.Scopes(tenantScope(ctx)).First(&before, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %d", ErrTargetNotFound, id)
+			}
+			return err
+		}
+
+		after = before
+		var columns []string
+		if patch.Name != nil {
+			after.Name = *patch.Name
+			columns = append(columns, "name")
+		}
+		if patch.Type != nil {
+			after.Type = *patch.Type
+			columns = append(columns, "type")
+		}
+		if patch.Email != nil {
+			after.Email = *patch.Email
+			columns = append(columns, "email")
+		}
+		if patch.WebhookURL != nil {
+			after.WebhookURL = *patch.WebhookURL
+			columns = append(columns, "webhook_url")
+		}
+		if patch.Secret != nil {
+			after.Secret = *patch.Secret
+			columns = append(columns, "secret")
+		}
+		if patch.AttachmentMode != nil {
+			after.AttachmentMode = *patch.AttachmentMode
+			columns = append(columns, "attachment_mode")
+		}
+		if patch.Keywords != nil {
+			after.Keywords = *patch.Keywords
+			columns = append(columns, "keywords")
+		}
+		if patch.IsActive != nil {
+			after.IsActive = *patch.IsActive
+			columns = append(columns, "is_active")
+		}
+		if patch.MailboxID != nil {
+			after.MailboxID = *patch.MailboxID
+			columns = append(columns, "mailbox_id")
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+
+		if err := normalizeForwardTarget(&after); err != nil {
+			return err
+		}
+		// 修改投递方式时，规范化会清空或补全与投递方式相关的字段，需要一并写入
+		if after.Type != before.Type {
+			columns = append(columns, "email", "webhook_url", "secret", "attachment_mode")
+		}
+		if after.Type == models.TargetTypeEmail && (after.Email != before.Email || before.Type != models.TargetTypeEmail) {
+			if err := checkTargetEmailUnique(tx, before.TenantID, after.Email, id); err != nil {
+				return err
+			}
+		}
+		if after.MailboxID != before.MailboxID {
+			if err := checkTargetMailbox(tx, before.TenantID, after.MailboxID); err != nil {
+				return err
+			}
+		}
+
+		// 用Select指定字段，确保false、空字符串等零值也能写入
+		columns = append(columns, "updated_at")
+		if err := tx.Model(&before).Select(columns).Updates(&after).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&after, id).Error; err != nil {
+			return err
+		}
+		changed = true
+		return recordAudit(ctx, tx, before.TenantID, models.AuditActionUpdate, models.EntityForwardTarget, id, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		es.emitTarget(ctx, models.AuditActionUpdate, &after)
+	}
+	return &after, nil
 }
 
 // DeleteForwardTarget 删除转发目标
-func (es *EmailService) DeleteForwardTarget(id uint) error {
+func (es *EmailService) DeleteForwardTarget(ctx context.Context, id uint) error {
+	// 结束后使转发目标缓存失效，下次匹配时重新加载
+	defer forwardTargets.invalidate()
+
 	db := database.GetDB()
 	
-	return db.Delete(&models.ForwardTarget{}, id).Error
-}
\ No newline at end of file
+	var before models.ForwardTarget
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(tenantScope(ctx)).First(&before, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %d", ErrTargetNotFound, id)
+			}
+			return err
+		}
+
+		if err := tx.Delete(&before).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, before.TenantID, models.AuditActionDelete, models.EntityForwardTarget, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	es.emitTarget(ctx, models.AuditActionDelete, &before)
+	return nil
+}
+
+// normalizeForwardTarget 校验并规范化转发目标的字段，未设置投递方式时为email
+// email类型必须有邮箱，其他类型必须有webhook地址，不使用的字段会被清空
+func normalizeForwardTarget(target *models.ForwardTarget) error {
+	target.Name = strings.TrimSpace(target.Name)
+	if target.Name == "" {
+		return fmt.Errorf("%w: 名称不能为空", ErrInvalidTarget)
+	}
+
+	target.Type = strings.ToLower(strings.TrimSpace(target.Type))
+	if target.Type == "" {
+		target.Type = models.TargetTypeEmail
+	}
+	if !models.IsValidTargetType(target.Type) {
+		return fmt.Errorf("%w: 不支持的投递方式 %q，应为 email/dingtalk/wecom/feishu/slack/webhook 之一", ErrInvalidTarget, target.Type)
+	}
+
+	if target.Type == models.TargetTypeEmail {
+		email := strings.TrimSpace(target.Email)
+		if email == "" {
+			return fmt.Errorf("%w: 邮箱不能为空", ErrInvalidTarget)
+		}
+		addr, err := mail.ParseAddress(email)
+		if err != nil {
+			return fmt.Errorf("%w: 无效的邮箱地址 %s", ErrInvalidTarget, email)
+		}
+		target.Email = strings.ToLower(addr.Address)
+		target.WebhookURL = ""
+		target.Secret = ""
+		target.AttachmentMode = ""
+	} else {
+		webhookURL := strings.TrimSpace(target.WebhookURL)
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %s 类型需要有效的 webhook_url", ErrInvalidTarget, target.Type)
+		}
+		target.WebhookURL = webhookURL
+		target.Email = ""
+	}
+
+	// 附件的传递方式只对通用webhook有意义
+	target.AttachmentMode = strings.ToLower(strings.TrimSpace(target.AttachmentMode))
+	switch {
+	case target.Type != models.TargetTypeWebhook:
+		target.AttachmentMode = ""
+	case target.AttachmentMode == "":
+		target.AttachmentMode = models.AttachmentModeURL
+	case target.AttachmentMode != models.AttachmentModeURL && target.AttachmentMode != models.AttachmentModeBase64:
+		return fmt.Errorf("%w: attachment_mode 无效 %q，应为 url 或 base64", ErrInvalidTarget, target.AttachmentMode)
+	}
+
+	// 去掉关键字两侧的空白和空项
+	var keywords []string
+	for _, k := range strings.Split(target.Keywords, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	target.Keywords = strings.Join(keywords, ",")
+
+	return nil
+}
+
+// checkTargetEmailUnique 检查邮箱是否已被同一租户的其他转发目标使用，excludeID为当前更新的目标
+func checkTargetEmailUnique(tx *gorm.DB, tenantID uint, email string, excludeID uint) error {
+	var count int64
+	query := tx.Model(&models.ForwardTarget{}).Where("tenant_id = ? AND email = ?", tenantID, email)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrTargetEmailExists, email)
+	}
+	return nil
+}
+
+// checkTargetMailbox 检查转发目标指定的邮箱是否存在且属于同一租户，0表示适用于租户的所有邮箱
+func checkTargetMailbox(tx *gorm.DB, tenantID, mailboxID uint) error {
+	if mailboxID == models.DefaultMailboxID {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&models.Mailbox{}).Where("id = ? AND tenant_id = ?", mailboxID, tenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: 邮箱 %d 不存在", ErrInvalidTarget, mailboxID)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateForwardTargetInactive(t *testing.T) {
+	mock := useMockDB(t)
+	inserts := captureInserts(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `tenants`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_active"}).AddRow(models.DefaultTenantID, true))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `forward_targets`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO `forward_targets`").WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec("INSERT INTO `audit_logs`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	target := &models.ForwardTarget{Name: "ops", Type: models.TargetTypeEmail, Email: "ops@example.com", IsActive: false}
+	if err := NewEmailService(nil, nil).CreateForwardTarget(context.Background(), target); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := inserts["forward_targets"]["is_active"]; !ok || got != false {
+		t.Errorf("写入的 is_active = %v（列存在: %v），期望 false", got, ok)
+	}
+	if target.ID != 5 || target.TenantID != models.DefaultTenantID {
+		t.Errorf("target = %+v", target)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// topTargetsLimit 统计中返回转发量最多的目标数量
+const topTargetsLimit = 10
+
+// EmailStats 调用方所属租户的邮件处理统计
+type EmailStats struct {
+	Days           int              `json:"days"`
+	Total          int64            `json:"total"`     // 统计范围内处理的邮件数
+	ByStatus       map[string]int64 `json:"by_status"` // 按转发状态统计
+	ForwardedToday int64            `json:"forwarded_today"`
+	Daily          []DailyStats     `json:"daily"`       // 每天一条，从最早的一天开始，没有邮件的日期也会返回
+	TopTargets     []TargetCount    `json:"top_targets"` // 转发成功最多的目标
+}
+
+// DailyStats 一天内的邮件处理数量
+type DailyStats struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Total    int64  `json:"total"`
+	Success  int64  `json:"success"`
+	Failed   int64  `json:"failed"`
+	Retrying int64  `json:"retrying"`
+}
+
+// TargetCount 转发到一个目标的邮件数量
+type TargetCount struct {
+	Target string `json:"target"`
+	Count  int64  `json:"count"`
+}
+
+// GetStats 统计调用方所属租户最近days天（含今天）的邮件处理情况
+func (es *EmailService) GetStats(ctx context.Context, days int) (*EmailStats, error) {
+	db := database.GetDB().WithContext(ctx)
+	now := time.Now()
+	since := startOfDay(now).AddDate(0, 0, 1-days)
+
+	logs := func() *gorm.DB {
+		return db.Model(&models.EmailLog{}).Scopes(tenantScope(ctx)).Where("created_at >= ?", since)
+	}
+
+	var statusRows []struct {
+		Day           string
+		ForwardStatus string
+		Count         int64
+	}
+	err := logs().
+		Select("DATE_FORMAT(created_at, '%Y-%m-%d') AS day, forward_status, COUNT(*) AS count").
+		Group("day, forward_status").
+		Scan(&statusRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &EmailStats{
+		Days:       days,
+		ByStatus:   make(map[string]int64),
+		Daily:      make([]DailyStats, 0, days),
+		TopTargets: []TargetCount{},
+	}
+	index := make(map[string]int, days)
+	for d := since; !d.After(now); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		index[date] = len(stats.Daily)
+		stats.Daily = append(stats.Daily, DailyStats{Date: date})
+	}
+
+	for _, row := range statusRows {
+		stats.Total += row.Count
+		stats.ByStatus[row.ForwardStatus] += row.Count
+
+		i, ok := index[row.Day]
+		if !ok {
+			continue
+		}
+		daily := &stats.Daily[i]
+		daily.Total += row.Count
+		switch row.ForwardStatus {
+		case models.StatusSuccess:
+			daily.Success += row.Count
+		case models.StatusFailed:
+			daily.Failed += row.Count
+		case models.StatusRetrying:
+			daily.Retrying += row.Count
+		}
+	}
+
+	err = db.Model(&models.EmailLog{}).Scopes(tenantScope(ctx)).
+		Where("forward_status = ? AND processed_at >= ?", models.StatusSuccess, startOfDay(now)).
+		Count(&stats.ForwardedToday).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = logs().
+		Select("forward_target AS target, COUNT(*) AS count").
+		Where("forward_status = ?", models.StatusSuccess).
+		Group("forward_target").
+		Order("count DESC").
+		Limit(topTargetsLimit).
+		Scan(&stats.TopTargets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"gorm.io/gorm"
+)
+
+// ErrorKind 业务错误的类别，决定接口返回的HTTP状态码
+type ErrorKind int
+
+const (
+	KindInternal         ErrorKind = iota // 内部错误
+	KindValidation                        // 请求参数无效
+	KindUnauthorized                      // 未认证
+	KindForbidden                         // 无权操作
+	KindNotFound                          // 资源不存在
+	KindMethodNotAllowed                  // 不支持的请求方法
+	KindConflict                          // 与现有数据或当前状态冲突
+	KindUnprocessable                     // 内容格式正确但无法处理
+	KindQuotaExceeded                     // 超出配额
+	KindUpstream                          // 上游服务（Gmail、Google OAuth等）出错或不可达
+	KindUnavailable                       // 服务暂时不可用
+	KindTimeout                           // 处理超时
+)
+
+// HTTPStatus 返回错误类别对应的HTTP状态码
+func (k ErrorKind) HTTPStatus() int {
+	switch k {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case KindQuotaExceeded:
+		return http.StatusTooManyRequests
+	case KindUpstream:
+		return http.StatusBadGateway
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error 带类别和错误码的业务错误，作为哨兵错误使用，具体原因用 fmt.Errorf("%w: ...") 包装
+type Error struct {
+	Kind    ErrorKind
+	Code    string // 机器可读的错误码，例如 target_not_found
+	Message string // 中文说明
+	English string // 英文说明
+}
+
+// newError 创建业务错误
+func newError(kind ErrorKind, code, message, english string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, English: english}
+}
+
+// Error 返回中文说明，日志和命令行输出保持中文
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Localize 返回指定语言的说明，目前支持中文和英文，其他语言返回中文
+func (e *Error) Localize(lang string) string {
+	if lang == "en" && e.English != "" {
+		return e.English
+	}
+	return e.Message
+}
+
+// Detail 返回err中除该错误说明以外的具体原因，err就是该错误本身时返回空字符串
+func (e *Error) Detail(err error) string {
+	detail := err.Error()
+	if rest, ok := strings.CutPrefix(detail, e.Message); ok {
+		return strings.TrimPrefix(rest, ": ")
+	}
+	return detail
+}
+
+// 通用的业务错误，各模块的专有错误在各自的文件中定义
+var (
+	// ErrInvalidRequest 请求参数无效
+	ErrInvalidRequest = newError(KindValidation, "invalid_request", "请求参数无效", "invalid request")
+	// ErrForbidden 权限不足
+	ErrForbidden = newError(KindForbidden, "forbidden", "权限不足", "permission denied")
+	// ErrNotFound 资源不存在
+	ErrNotFound = newError(KindNotFound, "not_found", "资源不存在", "resource not found")
+	// ErrMethodNotAllowed 不支持的请求方法
+	ErrMethodNotAllowed = newError(KindMethodNotAllowed, "method_not_allowed", "不支持的请求方法", "method not allowed")
+	// ErrDuplicate 与现有数据重复
+	ErrDuplicate = newError(KindConflict, "duplicate", "数据已存在", "resource already exists")
+	// ErrUpstreamUnavailable 上游服务出错或不可达
+	ErrUpstreamUnavailable = newError(KindUpstream, "upstream_unavailable", "上游服务不可用", "upstream service unavailable")
+	// ErrTimeout 处理超时
+	ErrTimeout = newError(KindTimeout, "timeout", "处理超时", "request timed out")
+	// ErrInternal 内部错误
+	ErrInternal = newError(KindInternal, "internal_error", "服务内部错误", "internal server error")
+)
+
+// AsError 返回err对应的业务错误：未用业务错误包装时按错误类型归类，无法识别的归为内部错误
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var apiErr *googleapi.Error
+	var urlErr *url.Error
+	var retrieveErr *oauth2.RetrieveError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.As(err, &apiErr), errors.As(err, &urlErr), errors.As(err, &retrieveErr):
+		return ErrUpstreamUnavailable
+	}
+	return ErrInternal
+}
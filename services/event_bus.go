@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/models"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 实时事件类型
+const (
+	// LiveLogCreated 新增了一条邮件处理记录
+	LiveLogCreated = "log.created"
+	// LiveLogUpdated 邮件处理记录的状态变化（重试后）
+	LiveLogUpdated = "log.updated"
+	// LiveRunCompleted 一次邮箱处理（定时调度或手动触发）结束
+	LiveRunCompleted = "run.completed"
+	// LiveTargetChanged 转发目标被创建、修改或删除
+	LiveTargetChanged = "target.changed"
+)
+
+// LiveEventTypes 全部实时事件类型
+var LiveEventTypes = []string{LiveLogCreated, LiveLogUpdated, LiveRunCompleted, LiveTargetChanged}
+
+// eventBusHistory 保留最近多少条事件，用于客户端断线重连后补发
+const eventBusHistory = 256
+
+// LiveEvent 事件总线上的一条实时事件
+type LiveEvent struct {
+	ID       uint64      `json:"id"` // 进程内递增的序号，重启后从1开始
+	Type     string      `json:"type"`
+	TenantID uint        `json:"tenant_id"`
+	Time     time.Time   `json:"time"`
+	Data     interface{} `json:"data"`
+
+	target string // 转发目标名字，用于按目标筛选
+	status string // 转发或运行状态，用于按状态筛选
+}
+
+// LogEvent log.created 和 log.updated 事件的数据，不含邮件正文
+type LogEvent struct {
+	Mailbox        string          `json:"mailbox"`
+	PreviousStatus string          `json:"previous_status,omitempty"` // 只在 log.updated 中有值
+	Log            models.EmailLog `json:"log"`
+}
+
+// RunEvent run.completed 事件的数据
+type RunEvent struct {
+	RunID      string `json:"run_id"`
+	MailboxID  uint   `json:"mailbox_id"`
+	Mailbox    string `json:"mailbox"`
+	Status     string `json:"status"` // success/failed
+	Fetched    int    `json:"fetched"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// LiveFilter 实时事件筛选条件，各字段为空时不限制
+type LiveFilter struct {
+	Types    []string
+	Targets  []string // 转发目标名字，指定后不带目标的事件（如 run.completed）不再推送
+	Statuses []string
+}
+
+// match 判断事件是否满足筛选条件
+func (f LiveFilter) match(e LiveEvent) bool {
+	return matchAny(f.Types, e.Type) && matchAny(f.Targets, e.target) && matchAny(f.Statuses, e.status)
+}
+
+// matchAny values为空或包含value时返回true
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// EventBus 进程内的事件总线，EmailService 发布处理事件，实时事件流订阅
+// 发布不会阻塞：订阅方的缓冲区满时丢弃事件并计数
+type EventBus struct {
+	mu      sync.Mutex
+	seq     uint64
+	closed  bool
+	subs    map[*LiveSubscription]struct{}
+	history []LiveEvent // 最近的事件，按ID递增
+}
+
+// LiveSubscription 事件总线的一个订阅
+type LiveSubscription struct {
+	bus     *EventBus
+	events  chan LiveEvent
+	match   func(LiveEvent) bool
+	dropped uint64
+	once    sync.Once
+}
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: make(map[*LiveSubscription]struct{}),
+	}
+}
+
+// Publish 发布一条事件，总线关闭后忽略
+func (b *EventBus) Publish(tenantID uint, eventType, target, status string, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.seq++
+	e := LiveEvent{
+		ID:       b.seq,
+		Type:     eventType,
+		TenantID: tenantID,
+		Time:     time.Now(),
+		Data:     data,
+		target:   target,
+		status:   status,
+	}
+
+	if len(b.history) == eventBusHistory {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:eventBusHistory-1]
+	}
+	b.history = append(b.history, e)
+
+	for sub := range b.subs {
+		sub.deliver(e)
+	}
+}
+
+// Subscribe 订阅当前租户满足筛选条件的事件，buffer为订阅方的缓冲区大小
+// lastEventID大于0时先补发总线保留的、ID更大的事件
+// 订阅方用完后必须调用 Close，总线关闭后返回 ErrShuttingDown
+func (b *EventBus) Subscribe(ctx context.Context, filter LiveFilter, buffer int, lastEventID uint64) (*LiveSubscription, error) {
+	sub := &LiveSubscription{
+		bus:    b,
+		events: make(chan LiveEvent, buffer),
+		match: func(e LiveEvent) bool {
+			return tenantAllowed(ctx, e.TenantID) && filter.match(e)
+		},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrShuttingDown
+	}
+
+	if lastEventID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastEventID {
+				sub.deliver(e)
+			}
+		}
+	}
+
+	b.subs[sub] = struct{}{}
+	streamSubscribers.Inc()
+	return sub, nil
+}
+
+// Close 关闭事件总线，所有订阅的事件通道随之关闭
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// remove 移除订阅并关闭其事件通道，调用方需持有b.mu
+func (b *EventBus) remove(sub *LiveSubscription) {
+	sub.once.Do(func() {
+		delete(b.subs, sub)
+		close(sub.events)
+		streamSubscribers.Dec()
+	})
+}
+
+// deliver 将事件放入订阅的缓冲区，缓冲区满时丢弃，调用方需持有总线的锁
+func (s *LiveSubscription) deliver(e LiveEvent) {
+	if !s.match(e) {
+		return
+	}
+	select {
+	case s.events <- e:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+		streamEventsDropped.Inc()
+	}
+}
+
+// Events 返回事件通道，总线关闭或订阅关闭后通道被关闭
+func (s *LiveSubscription) Events() <-chan LiveEvent {
+	return s.events
+}
+
+// TakeDropped 返回上次调用以来丢弃的事件数量并清零
+func (s *LiveSubscription) TakeDropped() uint64 {
+	return atomic.SwapUint64(&s.dropped, 0)
+}
+
+// Close 取消订阅
+func (s *LiveSubscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
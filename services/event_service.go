@@ -0,0 +1,664 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"email-forwarding/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSubscriptionNotFound 事件订阅不存在
+	ErrSubscriptionNotFound = newError(KindNotFound, "subscription_not_found", "事件订阅不存在", "event subscription not found")
+	// ErrInvalidSubscription 事件订阅参数无效
+	ErrInvalidSubscription = newError(KindValidation, "invalid_subscription", "事件订阅参数无效", "invalid event subscription")
+	// ErrEventDeliveryNotFound 推送记录不存在
+	ErrEventDeliveryNotFound = newError(KindNotFound, "delivery_not_found", "推送记录不存在", "event delivery not found")
+)
+
+const (
+	// eventQueueSize 等待保存和等待推送的事件数量上限，推送队列满时推送记录保持pending，由定期检查重新放入队列
+	eventQueueSize = 1000
+	// eventSweepInterval 检查遗留的pending推送记录的间隔
+	eventSweepInterval = time.Minute
+	// eventStaleAfter pending推送记录超过该时间仍不在队列中时重新放入队列
+	eventStaleAfter = time.Minute
+	// eventWorkers 并发推送事件的数量
+	eventWorkers = 4
+	// maxResponseBodyLength 推送记录中保存的响应内容的最大字数
+	maxResponseBodyLength = 1000
+)
+
+// 事件推送的状态
+const (
+	EventDeliveryPending = "pending"
+	EventDeliverySuccess = "success"
+	EventDeliveryFailed  = "failed"
+)
+
+// webhookEventHeader 事件推送的请求头，其余请求头和签名方式与通用webhook转发目标相同
+const webhookEventHeader = "X-Webhook-Event"
+
+// Event 推送给订阅方的事件
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	TenantID  uint        `json:"tenant_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// MessageEvent message.* 事件的数据
+type MessageEvent struct {
+	LogID       uint       `json:"log_id,omitempty"` // 邮件日志ID，message.received 时还没有日志
+	MessageID   string     `json:"message_id"`
+	MailboxID   uint       `json:"mailbox_id"`
+	Mailbox     string     `json:"mailbox"`
+	Subject     string     `json:"subject"`
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	Keyword     string     `json:"keyword,omitempty"`
+	TargetName  string     `json:"target_name,omitempty"`
+	TargetID    uint       `json:"target_id,omitempty"`
+	TargetType  string     `json:"target_type,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	Attempts    int        `json:"attempts,omitempty"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// newMessageEvent 根据邮件日志生成事件数据
+func newMessageEvent(mailbox string, emailLog *models.EmailLog) MessageEvent {
+	return MessageEvent{
+		LogID:       emailLog.ID,
+		MessageID:   emailLog.GmailMessageID,
+		MailboxID:   emailLog.MailboxID,
+		Mailbox:     mailbox,
+		Subject:     emailLog.Subject,
+		From:        emailLog.FromEmail,
+		To:          emailLog.ToEmail,
+		Keyword:     emailLog.Keyword,
+		TargetName:  emailLog.ForwardTarget,
+		TargetID:    emailLog.TargetID,
+		TargetType:  emailLog.TargetType,
+		Status:      emailLog.ForwardStatus,
+		Attempts:    emailLog.Attempts,
+		NextRetryAt: emailLog.NextRetryAt,
+		Error:       emailLog.ErrorMessage,
+	}
+}
+
+// TargetEvent target.changed 事件的数据，删除时target为删除前的目标
+type TargetEvent struct {
+	Action string                `json:"action"` // create/update/delete
+	Actor  string                `json:"actor"`
+	Target *models.ForwardTarget `json:"target"`
+}
+
+// SubscriptionPatch 事件订阅的部分更新，只有非nil的字段会被写入
+type SubscriptionPatch struct {
+	Name     *string `json:"name"`
+	URL      *string `json:"url"`
+	Secret   *string `json:"secret"`
+	Events   *string `json:"events"` // 逗号分隔，为空表示所有事件
+	IsActive *bool   `json:"is_active"`
+}
+
+// EventDeliveryFilter 推送记录查询条件
+type EventDeliveryFilter struct {
+	Event  string
+	Status string
+}
+
+// EventService 事件订阅服务：管理订阅，在后台向订阅方推送签名的事件并记录每次推送的结果
+type EventService struct {
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	closing     bool              // 不再接受新事件
+	queueClosed bool              // 推送队列已关闭，不再放入推送记录
+	events      chan pendingEvent // 等待保存推送记录的事件
+	queue       chan uint         // 等待推送的推送记录ID
+	active      map[uint]bool     // 在队列中或正在推送的推送记录，定期检查时跳过
+	stop        chan struct{}
+	dispatched  chan struct{} // dispatch保存完所有已发出的事件后关闭
+	workers     sync.WaitGroup
+}
+
+// pendingEvent 等待保存推送记录的事件，ctx 带有发出事件时的日志字段
+// 发出时即序列化，之后调用方修改事件数据不会影响推送的内容
+type pendingEvent struct {
+	ctx     context.Context
+	event   Event
+	payload []byte
+}
+
+// NewEventService 创建事件服务实例并启动后台推送
+func NewEventService() *EventService {
+	es := &EventService{
+		client:     &http.Client{},
+		now:        time.Now,
+		events:     make(chan pendingEvent, eventQueueSize),
+		queue:      make(chan uint, eventQueueSize),
+		active:     make(map[uint]bool),
+		stop:       make(chan struct{}),
+		dispatched: make(chan struct{}),
+	}
+	for i := 0; i < eventWorkers; i++ {
+		es.workers.Add(1)
+		go es.work()
+	}
+	es.workers.Add(1)
+	go es.dispatch()
+	go es.sweep()
+	return es
+}
+
+// Shutdown 停止接受新事件，等待已发出的事件保存推送记录并放入队列后关闭推送队列，再等待队列中的事件推送完成
+// 超过ctx的截止时间仍未完成时返回ctx的错误，未推送的记录保持pending，下次启动后重新推送
+func (es *EventService) Shutdown(ctx context.Context) error {
+	es.mu.Lock()
+	if !es.closing {
+		es.closing = true
+		close(es.events)
+		close(es.stop)
+		go es.closeQueue()
+	}
+	es.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		es.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeQueue 等待dispatch保存完所有已发出的事件后关闭推送队列，推送协程处理完队列中的记录后退出
+func (es *EventService) closeQueue() {
+	<-es.dispatched
+
+	es.mu.Lock()
+	es.queueClosed = true
+	close(es.queue)
+	es.mu.Unlock()
+}
+
+// work 后台推送队列中的事件
+func (es *EventService) work() {
+	defer es.workers.Done()
+
+	for id := range es.queue {
+		es.deliver(context.Background(), id)
+		es.release(id)
+	}
+}
+
+// deliver 推送一条pending的推送记录，订阅已删除时把记录标记为失败，不再重新推送
+func (es *EventService) deliver(ctx context.Context, id uint) {
+	db := database.GetDB().WithContext(ctx)
+
+	var delivery models.EventDelivery
+	if err := db.First(&delivery, id).Error; err != nil {
+		utils.GetLogger().Errorf("读取推送记录 %d 失败: %v", id, err)
+		return
+	}
+	if delivery.Status != EventDeliveryPending {
+		return
+	}
+
+	var sub models.WebhookSubscription
+	if err := db.First(&sub, delivery.SubscriptionID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.GetLogger().Errorf("读取事件订阅 %d 失败: %v", delivery.SubscriptionID, err)
+			return
+		}
+		err = db.Model(&delivery).Updates(map[string]interface{}{
+			"status":        EventDeliveryFailed,
+			"error_message": "事件订阅已删除",
+		}).Error
+		if err != nil {
+			utils.GetLogger().Errorf("保存推送记录 %d 失败: %v", id, err)
+		}
+		return
+	}
+	es.send(ctx, &sub, &delivery)
+}
+
+// enqueue 把推送记录放入队列，已在队列中或正在推送时不重复放入；推送队列已关闭或已满时返回false
+func (es *EventService) enqueue(id uint) bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if es.queueClosed {
+		return false
+	}
+	if es.active[id] {
+		return true
+	}
+	select {
+	case es.queue <- id:
+		es.active[id] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// release 推送记录处理完成，之后如果仍为pending可以由定期检查重新放入队列
+func (es *EventService) release(id uint) {
+	es.mu.Lock()
+	delete(es.active, id)
+	es.mu.Unlock()
+}
+
+// sweep 定期把遗留的pending推送记录重新放入队列：发出时推送队列已满、服务在推送前关闭或进程退出的记录
+func (es *EventService) sweep() {
+	defer es.workers.Done()
+
+	ticker := time.NewTicker(eventSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-es.stop:
+			return
+		case <-ticker.C:
+			es.requeueStale()
+		}
+	}
+}
+
+// requeueStale 把超过 eventStaleAfter 仍为pending且不在队列中的推送记录重新放入队列
+func (es *EventService) requeueStale() {
+	var ids []uint
+	err := database.GetDB().Model(&models.EventDelivery{}).
+		Where("status = ? AND created_at < ?", EventDeliveryPending, es.now().Add(-eventStaleAfter)).
+		Order("id").Limit(eventQueueSize).Pluck("id", &ids).Error
+	if err != nil {
+		utils.GetLogger().Errorf("查询待推送的记录失败: %v", err)
+		return
+	}
+
+	requeued := 0
+	for _, id := range ids {
+		if !es.enqueue(id) {
+			break
+		}
+		requeued++
+	}
+	if requeued > 0 {
+		utils.GetLogger().Infof("重新放入推送队列 %d 条pending推送记录", requeued)
+	}
+}
+
+// Emit 向租户中订阅了该事件的所有启用的订阅推送事件
+// 查询订阅、保存推送记录和推送都在后台进行，调用方不会等待数据库或订阅方；
+// 待保存的事件过多时才在调用方同步保存推送记录，确保事件不会丢失。失败只记录日志
+func (es *EventService) Emit(ctx context.Context, tenantID uint, eventType string, data interface{}) {
+	event := Event{
+		ID:        "evt_" + utils.NewID(),
+		Type:      eventType,
+		TenantID:  tenantID,
+		CreatedAt: es.now(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Errorf("序列化事件 %s 失败: %v", eventType, err)
+		return
+	}
+	// 调用方（例如HTTP请求）结束后仍要保存推送记录，只保留ctx中的日志字段
+	pending := pendingEvent{ctx: context.WithoutCancel(ctx), event: event, payload: payload}
+
+	es.mu.Lock()
+	if es.closing {
+		es.mu.Unlock()
+		utils.LoggerFromContext(ctx).Warnf("服务正在关闭，忽略事件 %s", eventType)
+		return
+	}
+	select {
+	case es.events <- pending:
+		es.mu.Unlock()
+		return
+	default:
+	}
+	es.mu.Unlock()
+
+	utils.LoggerFromContext(ctx).Warnf("待保存的事件过多，同步保存事件 %s 的推送记录", eventType)
+	es.save(pending)
+}
+
+// dispatch 后台保存事件的推送记录并放入推送队列
+// 不计入workers：推送协程在dispatch结束、推送队列关闭后才会退出
+func (es *EventService) dispatch() {
+	defer close(es.dispatched)
+
+	for pending := range es.events {
+		es.save(pending)
+	}
+}
+
+// save 为订阅了该事件的启用订阅各保存一条推送记录并放入推送队列
+func (es *EventService) save(pending pendingEvent) {
+	ctx, event := pending.ctx, pending.event
+	logger := utils.LoggerFromContext(ctx)
+	db := database.GetDB().WithContext(ctx)
+
+	var subs []models.WebhookSubscription
+	if err := db.Where("tenant_id = ? AND is_active = ?", event.TenantID, true).Find(&subs).Error; err != nil {
+		logger.Errorf("读取事件订阅失败: %v", err)
+		return
+	}
+
+	var matched []models.WebhookSubscription
+	for _, sub := range subs {
+		if subscribes(sub.Events, event.Type) {
+			matched = append(matched, sub)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	for _, sub := range matched {
+		delivery := models.EventDelivery{
+			TenantID:       event.TenantID,
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			Event:          event.Type,
+			Payload:        pending.payload,
+			Status:         EventDeliveryPending,
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			logger.Errorf("保存推送记录失败: %v", err)
+			continue
+		}
+		if !es.enqueue(delivery.ID) {
+			logger.Warnf("事件推送队列已满或服务正在关闭，推送记录 %d 保持pending，稍后重新推送", delivery.ID)
+		}
+	}
+}
+
+// subscribes 判断订阅的事件列表是否包含eventType，列表为空表示订阅所有事件
+func subscribes(events, eventType string) bool {
+	if strings.TrimSpace(events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(events, ",") {
+		if strings.TrimSpace(e) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// send 推送一条记录并保存结果，签名方式与通用webhook转发目标相同
+func (es *EventService) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.EventDelivery) {
+	timestamp := strconv.FormatInt(es.now().Unix(), 10)
+	headers := map[string]string{
+		webhookEventHeader:     delivery.Event,
+		webhookDeliveryHeader:  delivery.EventID,
+		webhookTimestampHeader: timestamp,
+	}
+	if sub.Secret != "" {
+		headers[webhookSignatureHeader] = "sha256=" + webhookSignature(sub.Secret, timestamp, delivery.Payload)
+	}
+
+	start := time.Now()
+	status, respBody, err := sendJSON(ctx, es.client, sub.URL, delivery.Payload, headers)
+	now := time.Now()
+
+	delivery.DurationMs = now.Sub(start).Milliseconds()
+	delivery.DeliveredAt = &now
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = truncateRunes(string(respBody), maxResponseBodyLength)
+	switch {
+	case err != nil:
+		delivery.Status = EventDeliveryFailed
+		delivery.ErrorMessage = err.Error()
+	case status < 200 || status >= 300:
+		delivery.Status = EventDeliveryFailed
+		delivery.ErrorMessage = fmt.Sprintf("HTTP %d", status)
+	default:
+		delivery.Status = EventDeliverySuccess
+	}
+
+	err = database.GetDB().WithContext(ctx).Model(delivery).
+		Select("status", "response_status", "response_body", "error_message", "duration_ms", "delivered_at").
+		Updates(delivery).Error
+	if err != nil {
+		utils.GetLogger().Errorf("保存推送记录 %d 失败: %v", delivery.ID, err)
+	}
+	if delivery.Status == EventDeliveryFailed {
+		utils.GetLogger().WithField("subscription_id", sub.ID).Warnf("事件 %s(%s) 推送失败: %s", delivery.Event, delivery.EventID, delivery.ErrorMessage)
+	}
+}
+
+// GetSubscriptions 获取调用方所属租户的事件订阅
+func (es *EventService) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	if err := database.GetDB().Scopes(tenantScope(ctx)).Order("id").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// GetSubscription 获取调用方所属租户的单个事件订阅
+func (es *EventService) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	return loadSubscription(database.GetDB().Scopes(tenantScope(ctx)), id)
+}
+
+// loadSubscription 在tx中读取事件订阅
+func loadSubscription(tx *gorm.DB, id uint) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := tx.First(&sub, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrSubscriptionNotFound, id)
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// CreateSubscription 在调用方所属租户下创建事件订阅
+func (es *EventService) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	if err := normalizeSubscription(sub); err != nil {
+		return err
+	}
+	sub.TenantID = tenantFromContext(ctx)
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(sub).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, sub.TenantID, models.AuditActionCreate, models.EntityWebhook, sub.ID, nil, sub)
+	})
+}
+
+// PatchSubscription 按字段掩码更新事件订阅
+func (es *EventService) PatchSubscription(ctx context.Context, id uint, patch SubscriptionPatch) (*models.WebhookSubscription, error) {
+	var after models.WebhookSubscription
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		before, err := loadSubscription(tx.Scopes(tenantScope(ctx)), id)
+		if err != nil {
+			return err
+		}
+
+		after = *before
+		var columns []string
+		for column, field := range map[string]struct {
+			dst   *string
+			value *string
+		}{
+			"name":   {&after.Name, patch.Name},
+			"url":    {&after.URL, patch.URL},
+			"secret": {&after.Secret, patch.Secret},
+			"events": {&after.Events, patch.Events},
+		} {
+			if field.value != nil {
+				*field.dst = *field.value
+				columns = append(columns, column)
+			}
+		}
+		if patch.IsActive != nil {
+			after.IsActive = *patch.IsActive
+			columns = append(columns, "is_active")
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+
+		if err := normalizeSubscription(&after); err != nil {
+			return err
+		}
+
+		// 用Select指定字段，确保false、空字符串等零值也能写入
+		columns = append(columns, "updated_at")
+		if err := tx.Model(before).Select(columns).Updates(&after).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&after, id).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, after.TenantID, models.AuditActionUpdate, models.EntityWebhook, id, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &after, nil
+}
+
+// DeleteSubscription 删除事件订阅，已有的推送记录保留
+func (es *EventService) DeleteSubscription(ctx context.Context, id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		before, err := loadSubscription(tx.Scopes(tenantScope(ctx)), id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(before).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, before.TenantID, models.AuditActionDelete, models.EntityWebhook, id, before, nil)
+	})
+}
+
+// GetDeliveries 获取事件订阅的推送记录，按时间倒序
+func (es *EventService) GetDeliveries(ctx context.Context, subscriptionID uint, filter EventDeliveryFilter, page, pageSize int) ([]models.EventDelivery, int64, error) {
+	db := database.GetDB()
+	if _, err := loadSubscription(db.Scopes(tenantScope(ctx)), subscriptionID); err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.EventDelivery
+	var total int64
+
+	query := db.Model(&models.EventDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("id desc").Offset(offset).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver 按订阅当前的地址和密钥重新推送一条记录，新建一条推送记录并同步等待结果
+// 停用的订阅也可以重新推送，便于修复接收方后补发
+func (es *EventService) Redeliver(ctx context.Context, subscriptionID, deliveryID uint) (*models.EventDelivery, error) {
+	db := database.GetDB().WithContext(ctx)
+	sub, err := loadSubscription(db.Scopes(tenantScope(ctx)), subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var original models.EventDelivery
+	if err := db.Where("subscription_id = ?", sub.ID).First(&original, deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrEventDeliveryNotFound, deliveryID)
+		}
+		return nil, err
+	}
+
+	delivery := models.EventDelivery{
+		TenantID:       original.TenantID,
+		SubscriptionID: sub.ID,
+		EventID:        original.EventID,
+		Event:          original.Event,
+		Payload:        original.Payload,
+		Status:         EventDeliveryPending,
+		RedeliveryOf:   original.ID,
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+
+	utils.LoggerFromContext(ctx).WithField("actor", actorFromContext(ctx)).Infof("重新推送事件 %s(%s) 到订阅 %d", delivery.Event, delivery.EventID, sub.ID)
+	// 同步推送期间标记为正在推送，避免定期检查重复推送
+	es.mu.Lock()
+	es.active[delivery.ID] = true
+	es.mu.Unlock()
+	defer es.release(delivery.ID)
+	es.send(ctx, sub, &delivery)
+	return &delivery, nil
+}
+
+// normalizeSubscription 校验事件订阅的字段，事件列表去重并按 models.Events 的顺序排列
+func normalizeSubscription(sub *models.WebhookSubscription) error {
+	sub.Name = strings.TrimSpace(sub.Name)
+	if sub.Name == "" {
+		return fmt.Errorf("%w: 名称不能为空", ErrInvalidSubscription)
+	}
+
+	sub.URL = strings.TrimSpace(sub.URL)
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: 无效的url %q", ErrInvalidSubscription, sub.URL)
+	}
+
+	selected := make(map[string]bool)
+	for _, e := range strings.Split(sub.Events, ",") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		if !models.IsValidEvent(e) {
+			return fmt.Errorf("%w: 不支持的事件 %q，应为 %s 之一", ErrInvalidSubscription, e, strings.Join(models.Events, "/"))
+		}
+		selected[e] = true
+	}
+	var events []string
+	for _, e := range models.Events {
+		if selected[e] {
+			events = append(events, e)
+		}
+	}
+	sub.Events = strings.Join(events, ",")
+	return nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateSubscriptionInactive(t *testing.T) {
+	mock := useMockDB(t)
+	inserts := captureInserts(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `webhook_subscriptions`").WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT INTO `audit_logs`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	es := NewEventService()
+	defer es.Shutdown(context.Background())
+
+	sub := &models.WebhookSubscription{Name: "ops", URL: "https://example.com/hook", IsActive: false}
+	if err := es.CreateSubscription(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := inserts["webhook_subscriptions"]["is_active"]; !ok || got != false {
+		t.Errorf("写入的 is_active = %v（列存在: %v），期望 false", got, ok)
+	}
+}
+
+func TestShutdownDeliversEmittedEvents(t *testing.T) {
+	mock := useMockDB(t)
+
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer srv.Close()
+
+	subColumns := []string{"id", "tenant_id", "name", "url", "events", "is_active"}
+	// 延迟返回订阅，确保Shutdown时事件的推送记录还没有保存
+	mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions` WHERE \\(tenant_id = \\? AND is_active = \\?\\)").
+		WithArgs(models.DefaultTenantID, true).
+		WillDelayFor(50 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows(subColumns).AddRow(7, models.DefaultTenantID, "ops", srv.URL, "", true))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `event_deliveries`").WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `event_deliveries` WHERE `event_deliveries`.`id` = \\?").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "subscription_id", "event_id", "event", "payload", "status"}).
+			AddRow(9, models.DefaultTenantID, 7, "evt_1", models.EventTargetChanged, []byte(`{"id":"evt_1"}`), EventDeliveryPending))
+	mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions` WHERE `webhook_subscriptions`.`id` = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(subColumns).AddRow(7, models.DefaultTenantID, "ops", srv.URL, "", true))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `event_deliveries` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	es := NewEventService()
+	es.client = srv.Client()
+	es.Emit(context.Background(), models.DefaultTenantID, models.EventTargetChanged, map[string]string{"name": "ops"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := es.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case body := <-received:
+		if body != `{"id":"evt_1"}` {
+			t.Errorf("推送的请求体 = %s", body)
+		}
+	default:
+		t.Fatal("Shutdown返回前应推送已发出的事件")
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// oauthStateTTL 授权链接的有效期，超时后需要重新发起授权
+const oauthStateTTL = 10 * time.Minute
+
+var (
+	// ErrGmailNotAuthorized Gmail尚未完成OAuth授权
+	ErrGmailNotAuthorized = newError(KindUnavailable, "gmail_not_authorized",
+		"Gmail尚未授权，请通过 /api/v1/auth/google/start 或 go run . auth google 完成授权",
+		"Gmail is not authorized, complete authorization via /api/v1/auth/google/start or go run . auth google")
+	// ErrInvalidOAuthState 授权回调的state不存在、已使用或已过期
+	ErrInvalidOAuthState = newError(KindValidation, "invalid_oauth_state", "授权请求无效或已过期，请重新发起授权", "authorization request is invalid or expired, please start again")
+	// ErrOAuthNotSupported 使用服务账号认证时不需要OAuth授权
+	ErrOAuthNotSupported = newError(KindValidation, "oauth_not_supported", "当前使用服务账号认证，不需要OAuth授权", "OAuth authorization is not needed when using a service account")
+)
+
+// pendingAuthorization 已发起但尚未完成的授权
+type pendingAuthorization struct {
+	verifier    string // PKCE的code_verifier
+	redirectURL string
+	expiresAt   time.Time
+}
+
+// StartAuthorization 发起OAuth授权，返回需要在浏览器中打开的授权链接
+// redirectURL 为授权完成后Google回调的地址，必须在OAuth客户端中登记过
+// （桌面应用类型的客户端允许任意端口的 http://127.0.0.1 和 http://localhost）
+func (gs *GmailService) StartAuthorization(redirectURL string) (string, error) {
+	if gs.oauthConfig == nil {
+		return "", ErrOAuthNotSupported
+	}
+
+	state, err := newOAuthState()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	gs.pendingMu.Lock()
+	now := time.Now()
+	for s, pending := range gs.pendingAuth {
+		if now.After(pending.expiresAt) {
+			delete(gs.pendingAuth, s)
+		}
+	}
+	gs.pendingAuth[state] = &pendingAuthorization{
+		verifier:    verifier,
+		redirectURL: redirectURL,
+		expiresAt:   now.Add(oauthStateTTL),
+	}
+	gs.pendingMu.Unlock()
+
+	config := *gs.oauthConfig
+	config.RedirectURL = redirectURL
+	// prompt=consent 确保每次授权都返回refresh token
+	return config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// CompleteAuthorization 校验state后用授权码换取token，保存到token文件并启用Gmail服务
+// 每个state只能使用一次
+func (gs *GmailService) CompleteAuthorization(ctx context.Context, state, code string) error {
+	if gs.oauthConfig == nil {
+		return ErrOAuthNotSupported
+	}
+
+	gs.pendingMu.Lock()
+	pending, ok := gs.pendingAuth[state]
+	delete(gs.pendingAuth, state)
+	gs.pendingMu.Unlock()
+
+	if !ok || time.Now().After(pending.expiresAt) {
+		return ErrInvalidOAuthState
+	}
+	if code == "" {
+		return fmt.Errorf("%w: 缺少授权码", ErrInvalidOAuthState)
+	}
+
+	config := *gs.oauthConfig
+	config.RedirectURL = pending.redirectURL
+
+	// 使用配置了代理的客户端进行token交换
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, gs.httpClient)
+	tok, err := config.Exchange(ctx, code, oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		return fmt.Errorf("%w: 无法获取token: %v", ErrUpstreamUnavailable, err)
+	}
+
+	if err := saveToken(gs.tokenFile, tok); err != nil {
+		return err
+	}
+	return gs.useToken(tok)
+}
+
+// hasPendingAuthorization 是否有state对应的进行中的授权
+func (gs *GmailService) hasPendingAuthorization(state string) bool {
+	gs.pendingMu.Lock()
+	defer gs.pendingMu.Unlock()
+
+	_, ok := gs.pendingAuth[state]
+	return ok
+}
+
+// newOAuthState 生成随机的state参数
+func newOAuthState() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成state失败: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
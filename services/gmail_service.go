@@ -2,41 +2,89 @@ package services
 
 import (
 	"context"
+	"email-forwarding/config"
+	"email-forwarding/utils"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
-// 全局代理配置
-var (
-	ProxyURL string = "http://127.0.0.1:10810" // 在这里设置您的代理地址，例如: "http://127.0.0.1:7890"
-)
-
 type GmailService struct {
-	service   *gmail.Service
-	userEmail string
+	authMode    string // 认证方式：GmailAuthOAuth 或 GmailAuthServiceAccount
+	userEmail   string
+	oauthConfig *oauth2.Config // 仅OAuth方式使用
+	httpClient  *http.Client   // 配置了代理的HTTP客户端，授权、token刷新和API调用共用
+	tokenFile   string
+
+	// 完成授权前或refresh token被撤销后 service 为nil，服务处于“需要授权”状态
+	authMu      sync.RWMutex
+	service     *gmail.Service
+	tokenSource oauth2.TokenSource
+	authErr     error // 需要重新授权的原因
+	// authGen 授权状态每变化一次加一，健康检查缓存只在授权状态未变化时有效
+	authGen atomic.Uint64
+
+	// 进行中的OAuth授权，见 gmail_auth.go
+	pendingMu   sync.Mutex
+	pendingAuth map[string]*pendingAuthorization
+
+	// 拉取邮件的参数，见 SetFetchOptions
+	fetchMu    sync.Mutex
+	query      string
+	batchSize  int64
+	maxBatches int
+
+	healthMu        sync.Mutex
+	healthCheckedAt time.Time
+	healthGen       uint64
+	healthErr       error
 }
 
-// SetProxy 设置代理地址
-func SetProxy(proxyURL string) {
-	ProxyURL = proxyURL
-	log.Printf("已设置代理: %s", proxyURL)
+// gmailHealthCacheTTL 健康检查结果的缓存时间，避免探针频繁调用Gmail API
+const gmailHealthCacheTTL = time.Minute
+
+// Gmail认证方式
+const (
+	GmailAuthOAuth          = "oauth"           // 已安装应用的OAuth授权（credentials.json + token.json）
+	GmailAuthServiceAccount = "service_account" // 服务账号 + Google Workspace域范围委派
+)
+
+// gmailScopes 访问Gmail需要的权限范围
+var gmailScopes = []string{gmail.GmailReadonlyScope, gmail.GmailSendScope, gmail.GmailModifyScope}
+
+// newGmailService 创建尚未设置凭据的Gmail服务实例
+func newGmailService(authMode, userEmail string, proxy func(*http.Request) (*url.URL, error)) *GmailService {
+	gmailAuthorized.WithLabelValues(userEmail).Set(0)
+	return &GmailService{
+		authMode:    authMode,
+		userEmail:   userEmail,
+		httpClient:  createHTTPClientWithProxy(proxy),
+		pendingAuth: make(map[string]*pendingAuthorization),
+		query:       defaultGmailQuery,
+		batchSize:   50,
+		maxBatches:  10,
+	}
 }
 
 // NewGmailService 创建Gmail服务实例
-func NewGmailService(credentialsFile, tokenFile, userEmail string) (*GmailService, error) {
+// proxy 为代理选择函数（见 config.ProxyConfig.ProxyFunc），OAuth授权、token刷新和API调用都会使用它，为nil时不使用代理
+// token文件不存在或已失效时不会阻塞等待授权，服务以“需要授权”状态启动，通过 StartAuthorization 完成授权
+func NewGmailService(credentialsFile, tokenFile, userEmail string, proxy func(*http.Request) (*url.URL, error)) (*GmailService, error) {
 	// 检查凭据文件是否存在
 	if _, err := os.Stat(credentialsFile); os.IsNotExist(err) {
 		return nil, fmt.Errorf("Gmail凭据文件不存在: %s\n请按照以下步骤获取凭据：\n1. 访问 https://console.cloud.google.com/\n2. 创建OAuth 2.0客户端ID（桌面应用）\n3. 下载JSON文件并重命名为 credentials.json", credentialsFile)
@@ -48,132 +96,220 @@ func NewGmailService(credentialsFile, tokenFile, userEmail string) (*GmailServic
 	}
 
 	// 解析OAuth 2.0配置
-	config, err := google.ConfigFromJSON(b, gmail.GmailReadonlyScope, gmail.GmailSendScope, gmail.GmailModifyScope)
+	config, err := google.ConfigFromJSON(b, gmailScopes...)
 	if err != nil {
 		return nil, fmt.Errorf("无法解析OAuth 2.0配置: %v\n请确保下载的是OAuth 2.0客户端ID，而不是API密钥", err)
 	}
 
+	gs := newGmailService(GmailAuthOAuth, userEmail, proxy)
+	gs.oauthConfig = config
+	gs.tokenFile = tokenFile
+
 	// 获取OAuth2 token
 	tok, err := tokenFromFile(tokenFile)
 	if err != nil {
-		// 创建支持代理的HTTP客户端用于token获取
-		proxyClient := createHTTPClientWithProxy()
-		tok = getTokenFromWeb(config, proxyClient)
-		saveToken(tokenFile, tok)
-	} else {
-		// 检查token是否有效，如果无效则重新获取
-		if !isTokenValid(tok) {
-			log.Printf("Token已过期，重新获取...")
-			proxyClient := createHTTPClientWithProxy()
-			tok = getTokenFromWeb(config, proxyClient)
-			saveToken(tokenFile, tok)
-		}
+		utils.GetLogger().Warnf("无法读取token文件 %s，Gmail服务需要授权: %v", tokenFile, err)
+		return gs, nil
 	}
-	
-	// 创建支持代理的HTTP客户端
-	proxyClient := createHTTPClientWithProxy()
-	
-	// 创建OAuth2客户端，并确保代理配置正确应用
-	var oauthClient *http.Client
-	if proxyClient.Transport != nil {
-		// 如果设置了代理，创建一个新的Transport，同时保持OAuth2认证
-		oauthClient = &http.Client{
-			Transport: &oauth2.Transport{
-				Base:   proxyClient.Transport,
-				Source: config.TokenSource(context.Background(), tok),
-			},
-		}
-	} else {
-		// 如果没有代理，使用标准的OAuth2客户端
-		oauthClient = config.Client(context.Background(), tok)
+	if !isTokenValid(tok) {
+		utils.GetLogger().Warn("Token已失效且无法刷新，Gmail服务需要重新授权")
+		return gs, nil
 	}
-	
-	srv, err := gmail.NewService(context.Background(), option.WithHTTPClient(oauthClient))
+
+	if err := gs.useToken(tok); err != nil {
+		return nil, err
+	}
+	return gs, nil
+}
+
+// NewGmailServiceFromConfig 根据 gmail.auth_mode 选择认证方式创建Gmail服务实例
+func NewGmailServiceFromConfig(cfg config.GmailConfig, proxy func(*http.Request) (*url.URL, error)) (*GmailService, error) {
+	if cfg.AuthMode == GmailAuthServiceAccount {
+		return NewServiceAccountGmailService(cfg.ServiceAccountFile, cfg.UserEmail, proxy)
+	}
+	return NewGmailService(cfg.CredentialsFile, cfg.TokenFile, cfg.UserEmail, proxy)
+}
+
+// NewServiceAccountGmailService 使用服务账号通过域范围委派创建Gmail服务实例，以 userEmail 的身份访问其邮箱
+// 服务账号需要在Google Workspace管理后台的“域范围委派”中授权Gmail的权限范围；同一个密钥可以用于域内的任意邮箱
+func NewServiceAccountGmailService(keyFile, userEmail string, proxy func(*http.Request) (*url.URL, error)) (*GmailService, error) {
+	b, err := ioutil.ReadFile(keyFile)
 	if err != nil {
-		return nil, fmt.Errorf("无法创建Gmail服务: %v", err)
+		return nil, fmt.Errorf("无法读取服务账号密钥文件: %v", err)
 	}
 
-	return &GmailService{
-		service:   srv,
-		userEmail: userEmail,
-	}, nil
+	jwtConfig, err := google.JWTConfigFromJSON(b, gmailScopes...)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析服务账号密钥: %v\n请确保下载的是服务账号的JSON密钥", err)
+	}
+	// 通过域范围委派模拟目标邮箱的用户
+	jwtConfig.Subject = userEmail
+
+	gs := newGmailService(GmailAuthServiceAccount, userEmail, proxy)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, gs.httpClient)
+	if err := gs.useTokenSource(ctx, jwtConfig.TokenSource(ctx)); err != nil {
+		return nil, err
+	}
+	return gs, nil
 }
 
+// useToken 使用token创建Gmail客户端，access token过期时通过代理自动刷新，刷新后的token写回token文件
+func (gs *GmailService) useToken(tok *oauth2.Token) error {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, gs.httpClient)
+	var tokenSource *persistingTokenSource
+	tokenSource = newPersistingTokenSource(gs.oauthConfig.TokenSource(ctx, tok), gs.tokenFile, tok, func(err error) {
+		gs.revoke(tokenSource, err)
+	})
+	return gs.useTokenSource(ctx, tokenSource)
+}
 
+// useTokenSource 使用tokenSource创建Gmail客户端，ctx 中带有配置了代理的HTTP客户端
+func (gs *GmailService) useTokenSource(ctx context.Context, tokenSource oauth2.TokenSource) error {
+	oauthClient := oauth2.NewClient(ctx, tokenSource)
 
-// createHTTPClientWithProxy 创建支持代理的HTTP客户端
-func createHTTPClientWithProxy() *http.Client {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	srv, err := gmail.NewService(context.Background(), option.WithHTTPClient(oauthClient))
+	if err != nil {
+		return fmt.Errorf("无法创建Gmail服务: %v", err)
 	}
-	
-	// 优先使用代码中设置的代理
-	if ProxyURL != "" {
-		proxyURL, err := url.Parse(ProxyURL)
-		if err != nil {
-			log.Printf("警告: 无法解析代理URL %s: %v", ProxyURL, err)
-		} else {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyURL),
-			}
-			log.Printf("已配置代理: %s", ProxyURL)
+
+	gs.authMu.Lock()
+	gs.service = srv
+	gs.tokenSource = tokenSource
+	gs.authErr = nil
+	gs.authMu.Unlock()
+
+	gmailAuthorized.WithLabelValues(gs.userEmail).Set(1)
+	gs.authGen.Add(1)
+	return nil
+}
+
+// revoke refresh token被撤销或过期时切换到需要授权的状态，之后的调用都返回 ErrGmailNotAuthorized
+// source 不是当前使用的TokenSource时（例如已重新授权）忽略
+func (gs *GmailService) revoke(source oauth2.TokenSource, err error) {
+	gs.authMu.Lock()
+	if gs.service == nil || gs.tokenSource != source {
+		gs.authMu.Unlock()
+		return
+	}
+	gs.service = nil
+	gs.tokenSource = nil
+	gs.authErr = err
+	gs.authMu.Unlock()
+
+	gmailAuthorized.WithLabelValues(gs.userEmail).Set(0)
+	gs.authGen.Add(1)
+	utils.GetLogger().Errorf("refresh token已被撤销或过期，Gmail服务需要重新授权: %v", err)
+}
+
+// UserEmail 返回访问的邮箱地址
+func (gs *GmailService) UserEmail() string {
+	return gs.userEmail
+}
+
+// AuthMode 返回认证方式
+func (gs *GmailService) AuthMode() string {
+	return gs.authMode
+}
+
+// Authorized 返回是否已完成OAuth授权
+func (gs *GmailService) Authorized() bool {
+	gs.authMu.RLock()
+	defer gs.authMu.RUnlock()
+
+	return gs.service != nil
+}
+
+// api 返回Gmail API客户端，未完成授权时返回 ErrGmailNotAuthorized
+func (gs *GmailService) api() (*gmail.Service, error) {
+	gs.authMu.RLock()
+	defer gs.authMu.RUnlock()
+
+	if gs.service == nil {
+		if gs.authErr != nil {
+			return nil, fmt.Errorf("%w（refresh token已失效: %v）", ErrGmailNotAuthorized, gs.authErr)
 		}
-		return client
+		return nil, ErrGmailNotAuthorized
 	}
-	
-	// 如果没有设置代理，检查环境变量
-	httpProxy := os.Getenv("HTTP_PROXY")
-	httpsProxy := os.Getenv("HTTPS_PROXY")
-	
-	// 如果没有设置HTTPS代理，尝试使用HTTP代理
-	if httpsProxy == "" {
-		httpsProxy = httpProxy
+	return gs.service, nil
+}
+
+// defaultGmailQuery 默认的未读邮件搜索条件
+const defaultGmailQuery = "is:unread -in:trash -in:spam"
+
+// SetFetchOptions 设置拉取邮件的搜索条件、每批数量和最大批次数，零值表示保持原设置
+func (gs *GmailService) SetFetchOptions(query string, batchSize int64, maxBatches int) {
+	gs.fetchMu.Lock()
+	defer gs.fetchMu.Unlock()
+
+	if query != "" {
+		gs.query = query
 	}
-	
-	// 如果没有设置HTTP代理，尝试使用小写的环境变量
-	if httpProxy == "" {
-		httpProxy = os.Getenv("http_proxy")
+	if batchSize > 0 {
+		gs.batchSize = batchSize
 	}
-	if httpsProxy == "" {
-		httpsProxy = os.Getenv("https_proxy")
+	if maxBatches > 0 {
+		gs.maxBatches = maxBatches
 	}
-	
-	// 如果设置了代理，配置代理
-	if httpsProxy != "" {
-		proxyURL, err := url.Parse(httpsProxy)
-		if err != nil {
-			log.Printf("警告: 无法解析代理URL %s: %v", httpsProxy, err)
-		} else {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyURL),
-			}
-			log.Printf("已配置代理: %s", httpsProxy)
-		}
-	} else {
-		log.Println("未配置代理")
+}
+
+// fetchOptions 返回当前的拉取参数
+func (gs *GmailService) fetchOptions() (query string, batchSize int64, maxBatches int) {
+	gs.fetchMu.Lock()
+	defer gs.fetchMu.Unlock()
+
+	return gs.query, gs.batchSize, gs.maxBatches
+}
+
+// CheckHealth 检查Gmail是否可用：确认token可以刷新，并请求一次用户资料
+// 结果缓存一分钟，授权状态变化后缓存立即失效
+func (gs *GmailService) CheckHealth(ctx context.Context) error {
+	gs.healthMu.Lock()
+	defer gs.healthMu.Unlock()
+
+	generation := gs.authGen.Load()
+	if !gs.healthCheckedAt.IsZero() && gs.healthGen == generation && time.Since(gs.healthCheckedAt) < gmailHealthCacheTTL {
+		return gs.healthErr
 	}
-	
-	return client
+
+	gs.healthErr = gs.checkHealth(ctx)
+	gs.healthCheckedAt = time.Now()
+	gs.healthGen = generation
+	return gs.healthErr
 }
 
-// getTokenFromWeb 从Web获取token
-func getTokenFromWeb(config *oauth2.Config, client *http.Client) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("在浏览器中打开以下链接进行授权: \n%v\n", authURL)
+// checkHealth 执行实际的Gmail健康检查
+func (gs *GmailService) checkHealth(ctx context.Context) error {
+	srv, err := gs.api()
+	if err != nil {
+		return err
+	}
 
-	var authCode string
-	fmt.Print("粘贴授权码: ")
-	if _, err := fmt.Scan(&authCode); err != nil {
-		log.Fatalf("无法读取授权码: %v", err)
+	gs.authMu.RLock()
+	tokenSource := gs.tokenSource
+	gs.authMu.RUnlock()
+	if _, err := tokenSource.Token(); err != nil {
+		return fmt.Errorf("无法刷新OAuth token: %w", err)
 	}
 
-	// 使用配置了代理的客户端进行token交换
-	ctx := context.WithValue(context.TODO(), oauth2.HTTPClient, client)
-	tok, err := config.Exchange(ctx, authCode)
+	spanCtx, done := startGmailCall(ctx, "profile")
+	_, err = srv.Users.GetProfile("me").Context(spanCtx).Do()
+	done(err)
 	if err != nil {
-		log.Fatalf("无法获取token: %v", err)
+		return fmt.Errorf("无法访问Gmail API: %w", err)
+	}
+	return nil
+}
+
+
+// createHTTPClientWithProxy 创建支持代理的HTTP客户端
+func createHTTPClientWithProxy(proxy func(*http.Request) (*url.URL, error)) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
 	}
-	return tok
 }
 
 // tokenFromFile 从文件读取token
@@ -188,45 +324,29 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 	return tok, err
 }
 
-// isTokenValid 检查token是否有效
+// isTokenValid 检查token是否可用：有refresh token时过期后可以自动刷新，否则access token必须未过期
 func isTokenValid(token *oauth2.Token) bool {
 	if token == nil {
 		return false
 	}
-	
-	// 检查token是否过期
-	if token.Expiry.Before(time.Now()) {
-		return false
-	}
-	
-	// 检查是否有访问令牌
-	if token.AccessToken == "" {
-		return false
-	}
-	
-	return true
-}
 
-// saveToken 保存token到文件
-func saveToken(path string, token *oauth2.Token) {
-	fmt.Printf("保存凭证文件到: %s\n", path)
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalf("无法缓存oauth token: %v", err)
+	if token.RefreshToken != "" {
+		return true
 	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
+
+	return token.AccessToken != "" && token.Expiry.After(time.Now())
 }
 
 // GetUnreadEmails 获取未读邮件（分批处理）
-func (gs *GmailService) GetUnreadEmails() ([]*EmailMessage, error) {
+func (gs *GmailService) GetUnreadEmails(ctx context.Context) ([]*EmailMessage, error) {
 	// 使用分批处理，确保处理完所有邮件
-	// 每批50封，最多10批（总共500封）
-	return gs.GetUnreadEmailsBatch(50, 10)
+	// 每批数量和最大批次数由配置决定，默认每批50封，最多10批（总共500封）
+	_, batchSize, maxBatches := gs.fetchOptions()
+	return gs.GetUnreadEmailsBatch(ctx, batchSize, maxBatches)
 }
 
 // GetUnreadEmailsWithLimit 获取指定数量的未读邮件
-func (gs *GmailService) GetUnreadEmailsWithLimit(maxResults int64) ([]*EmailMessage, error) {
+func (gs *GmailService) GetUnreadEmailsWithLimit(ctx context.Context, maxResults int64) ([]*EmailMessage, error) {
 	if maxResults <= 0 {
 		maxResults = 50 // 默认限制
 	}
@@ -234,16 +354,23 @@ func (gs *GmailService) GetUnreadEmailsWithLimit(maxResults int64) ([]*EmailMess
 		maxResults = 500 // 最大限制
 	}
 
-	query := "is:unread -in:trash -in:spam"
-	
-	req := gs.service.Users.Messages.List("me").Q(query).MaxResults(maxResults)
+	srv, err := gs.api()
+	if err != nil {
+		return nil, err
+	}
+
+	query, _, _ := gs.fetchOptions()
+	req := srv.Users.Messages.List("me").Q(query).MaxResults(maxResults)
 	
-	r, err := req.Do()
+	listCtx, done := startGmailCall(ctx, "list")
+	r, err := req.Context(listCtx).Do()
+	done(err)
 	if err != nil {
-		return nil, fmt.Errorf("无法获取邮件列表: %v", err)
+		return nil, fmt.Errorf("无法获取邮件列表: %w", err)
 	}
+	unreadBacklog.WithLabelValues(gs.userEmail).Set(float64(r.ResultSizeEstimate))
 
-	log.Printf("获取到 %d 封未读邮件（最大限制: %d）", len(r.Messages), maxResults)
+	utils.GetLogger().Infof("获取到 %d 封未读邮件（最大限制: %d）", len(r.Messages), maxResults)
 
 	var emails []*EmailMessage
 	
@@ -259,9 +386,11 @@ func (gs *GmailService) GetUnreadEmailsWithLimit(maxResults int64) ([]*EmailMess
 			semaphore <- struct{}{} // 获取信号量
 			defer func() { <-semaphore }() // 释放信号量
 			
-			msg, err := gs.service.Users.Messages.Get("me", messageID).Do()
+			getCtx, done := startGmailCall(ctx, "get")
+			msg, err := srv.Users.Messages.Get("me", messageID).Context(getCtx).Do()
+			done(err)
 			if err != nil {
-				log.Printf("无法获取邮件详情 %s: %v", messageID, err)
+				utils.GetLogger().Warnf("无法获取邮件详情 %s: %v", messageID, err)
 				errorChan <- err
 				return
 			}
@@ -281,16 +410,21 @@ func (gs *GmailService) GetUnreadEmailsWithLimit(maxResults int64) ([]*EmailMess
 		case email := <-emailChan:
 			emails = append(emails, email)
 		case err := <-errorChan:
-			log.Printf("处理邮件时出错: %v", err)
+			utils.GetLogger().Warnf("处理邮件时出错: %v", err)
 		}
 	}
 
-	log.Printf("成功处理 %d 封邮件", len(emails))
+	utils.GetLogger().Infof("成功处理 %d 封邮件", len(emails))
 	return emails, nil
 }
 
 // GetUnreadEmailsBatch 分批获取所有未读邮件
-func (gs *GmailService) GetUnreadEmailsBatch(batchSize int64, maxBatches int) ([]*EmailMessage, error) {
+func (gs *GmailService) GetUnreadEmailsBatch(ctx context.Context, batchSize int64, maxBatches int) ([]*EmailMessage, error) {
+	logger := utils.LoggerFromContext(ctx)
+	srv, err := gs.api()
+	if err != nil {
+		return nil, err
+	}
 	if batchSize <= 0 {
 			batchSize = 50
 	}
@@ -301,21 +435,26 @@ func (gs *GmailService) GetUnreadEmailsBatch(batchSize int64, maxBatches int) ([
 	pageToken := ""
 	batchCount := 0
 	seenIDs := make(map[string]bool) // 用于去重
+	query, _, _ := gs.fetchOptions()
 
 	for batchCount < maxBatches {
-			query := "is:unread -in:trash -in:spam"
-			req := gs.service.Users.Messages.List("me").Q(query).MaxResults(batchSize).IncludeSpamTrash(false)
+			req := srv.Users.Messages.List("me").Q(query).MaxResults(batchSize).IncludeSpamTrash(false)
 			
 			if pageToken != "" {
 					req = req.PageToken(pageToken)
 			}
 
-			r, err := req.Do()
+			listCtx, done := startGmailCall(ctx, "list")
+			r, err := req.Context(listCtx).Do()
+			done(err)
 			if err != nil {
-					return allEmails, fmt.Errorf("failed to list batch %d: %v", batchCount+1, err)
+					return allEmails, fmt.Errorf("failed to list batch %d: %w", batchCount+1, err)
+			}
+			if batchCount == 0 {
+					unreadBacklog.WithLabelValues(gs.userEmail).Set(float64(r.ResultSizeEstimate))
 			}
 
-			log.Printf("Processing batch %d: %d messages", batchCount+1, len(r.Messages))
+			logger.Infof("Processing batch %d: %d messages", batchCount+1, len(r.Messages))
 
 			// 批量获取邮件详情（可改用 goroutine 并发，控制并发数）
 			for _, m := range r.Messages {
@@ -324,9 +463,11 @@ func (gs *GmailService) GetUnreadEmailsBatch(batchSize int64, maxBatches int) ([
 					}
 					seenIDs[m.Id] = true
 
-					msg, err := gs.service.Users.Messages.Get("me", m.Id).Format("full").Do()
+					getCtx, done := startGmailCall(ctx, "get")
+					msg, err := srv.Users.Messages.Get("me", m.Id).Format("full").Context(getCtx).Do()
+					done(err)
 					if err != nil {
-							log.Printf("Failed to get message %s (will retry): %v", m.Id, err)
+							logger.WithField("message_id", m.Id).Warnf("Failed to get message (will retry): %v", err)
 							// 可加入重试逻辑
 							continue
 					}
@@ -346,13 +487,18 @@ func (gs *GmailService) GetUnreadEmailsBatch(batchSize int64, maxBatches int) ([
 	}
 
 	if batchCount >= maxBatches {
-			log.Printf("Stopped after reaching max batches (%d), total emails: %d", maxBatches, len(allEmails))
+			logger.Infof("Stopped after reaching max batches (%d), total emails: %d", maxBatches, len(allEmails))
 	}
 	return allEmails, nil
 }
 
 // SendEmail 发送邮件
-func (gs *GmailService) SendEmail(to, subject, body string) error {
+func (gs *GmailService) SendEmail(ctx context.Context, to, subject, body string) error {
+	srv, err := gs.api()
+	if err != nil {
+		return err
+	}
+
 	var message gmail.Message
 
 	// 对邮件标题进行UTF-8编码处理
@@ -365,9 +511,11 @@ func (gs *GmailService) SendEmail(to, subject, body string) error {
 
 	message.Raw = base64.URLEncoding.EncodeToString(msg)
 
-	_, err := gs.service.Users.Messages.Send("me", &message).Do()
+	sendCtx, done := startGmailCall(ctx, "send")
+	_, err = srv.Users.Messages.Send("me", &message).Context(sendCtx).Do()
+	done(err)
 	if err != nil {
-		return fmt.Errorf("无法发送邮件: %v", err)
+		return fmt.Errorf("无法发送邮件: %w", err)
 	}
 
 	return nil
@@ -393,37 +541,144 @@ func encodeSubject(subject string) string {
 }
 
 // MarkAsRead 标记邮件为已读
-func (gs *GmailService) MarkAsRead(messageID string) error {
+func (gs *GmailService) MarkAsRead(ctx context.Context, messageID string) error {
+	srv, err := gs.api()
+	if err != nil {
+		return err
+	}
+
 	req := &gmail.ModifyMessageRequest{
 		RemoveLabelIds: []string{"UNREAD"},
 	}
 
-	_, err := gs.service.Users.Messages.Modify("me", messageID, req).Do()
+	modifyCtx, done := startGmailCall(ctx, "modify")
+	_, err = srv.Users.Messages.Modify("me", messageID, req).Context(modifyCtx).Do()
+	done(err)
 	if err != nil {
-		return fmt.Errorf("无法标记邮件为已读: %v", err)
+		return fmt.Errorf("无法标记邮件为已读: %w", err)
 	}
 
 	return nil
 }
 
+// GetMessage 按ID获取单封邮件，重试转发时重新读取原邮件
+func (gs *GmailService) GetMessage(ctx context.Context, messageID string) (*EmailMessage, error) {
+	srv, err := gs.api()
+	if err != nil {
+		return nil, err
+	}
+
+	getCtx, done := startGmailCall(ctx, "get")
+	msg, err := srv.Users.Messages.Get("me", messageID).Format("full").Context(getCtx).Do()
+	done(err)
+	if err != nil {
+		// 原邮件已被删除时重试也不会成功
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, permanent(fmt.Errorf("原邮件 %s 已不存在", messageID))
+		}
+		return nil, fmt.Errorf("无法获取邮件 %s: %w", messageID, err)
+	}
+	return parseEmailMessage(msg), nil
+}
+
+// GetAttachment 获取邮件中partID对应附件的内容
+// Gmail的附件ID每次读取邮件都可能变化，所以按稳定的partID定位后再用当前的附件ID下载
+func (gs *GmailService) GetAttachment(ctx context.Context, messageID, partID string) (*Attachment, []byte, error) {
+	srv, err := gs.api()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	getCtx, done := startGmailCall(ctx, "get")
+	msg, err := srv.Users.Messages.Get("me", messageID).Format("full").Context(getCtx).Do()
+	done(err)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, nil, fmt.Errorf("%w: 原邮件 %s 已不存在", ErrAttachmentNotFound, messageID)
+		}
+		return nil, nil, fmt.Errorf("无法获取邮件 %s: %w", messageID, err)
+	}
+
+	part := findPart(msg.Payload, partID)
+	if part == nil || part.Filename == "" || part.Body == nil {
+		return nil, nil, fmt.Errorf("%w: %s/%s", ErrAttachmentNotFound, messageID, partID)
+	}
+	attachment := &Attachment{PartID: part.PartId, Filename: part.Filename, MimeType: part.MimeType, Size: part.Body.Size}
+
+	encoded := part.Body.Data
+	if encoded == "" && part.Body.AttachmentId != "" {
+		attCtx, done := startGmailCall(ctx, "attachment")
+		body, err := srv.Users.Messages.Attachments.Get("me", messageID, part.Body.AttachmentId).Context(attCtx).Do()
+		done(err)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无法下载附件 %s: %w", part.Filename, err)
+		}
+		encoded = body.Data
+	}
+	data, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("附件 %s 解码失败: %v", part.Filename, err)
+	}
+	return attachment, data, nil
+}
+
+// findPart 在邮件结构中查找partID对应的部分
+func findPart(part *gmail.MessagePart, partID string) *gmail.MessagePart {
+	if part == nil {
+		return nil
+	}
+	if part.PartId == partID {
+		return part
+	}
+	for _, child := range part.Parts {
+		if found := findPart(child, partID); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 // EmailMessage 邮件消息结构
 type EmailMessage struct {
 	ID          string
+	ThreadID    string
 	Subject     string
 	From        string
 	To          string
 	Body        string
+	TextBody    string          // text/plain 正文
+	HTMLBody    string          // text/html 正文
+	Headers     []MessageHeader // 原始邮件头，保持原有顺序
+	Attachments []Attachment
 	ReceivedAt  time.Time
 }
 
+// MessageHeader 邮件头
+type MessageHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Attachment 附件信息，PartID在同一封邮件中稳定，用于下载附件
+type Attachment struct {
+	PartID   string `json:"part_id"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+}
+
 // parseEmailMessage 解析邮件消息
 func parseEmailMessage(msg *gmail.Message) *EmailMessage {
 	email := &EmailMessage{
-		ID: msg.Id,
+		ID:       msg.Id,
+		ThreadID: msg.ThreadId,
 	}
 
 	// 解析头部信息
 	for _, header := range msg.Payload.Headers {
+		email.Headers = append(email.Headers, MessageHeader{Name: header.Name, Value: header.Value})
 		switch header.Name {
 		case "Subject":
 			email.Subject = header.Value
@@ -440,10 +695,40 @@ func parseEmailMessage(msg *gmail.Message) *EmailMessage {
 
 	// 解析邮件正文
 	email.Body = extractBody(msg.Payload)
+	collectParts(msg.Payload, email)
 
 	return email
 }
 
+// collectParts 分别提取纯文本正文、HTML正文和附件信息
+func collectParts(part *gmail.MessagePart, email *EmailMessage) {
+	if part == nil {
+		return
+	}
+	if part.Filename != "" && part.Body != nil {
+		email.Attachments = append(email.Attachments, Attachment{
+			PartID:   part.PartId,
+			Filename: part.Filename,
+			MimeType: part.MimeType,
+			Size:     part.Body.Size,
+		})
+		return
+	}
+	if part.Body != nil && part.Body.Data != "" {
+		if data, err := base64.URLEncoding.DecodeString(part.Body.Data); err == nil {
+			switch part.MimeType {
+			case "text/plain":
+				email.TextBody += string(data)
+			case "text/html":
+				email.HTMLBody += string(data)
+			}
+		}
+	}
+	for _, child := range part.Parts {
+		collectParts(child, email)
+	}
+}
+
 // extractBody 提取邮件正文
 func extractBody(payload *gmail.MessagePart) string {
 	var body string
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/utils"
+	"fmt"
+	"time"
+)
+
+// 健康状态常量
+const (
+	HealthUp       = "up"
+	HealthDegraded = "degraded" // 可以接收请求，但部分功能不可用，例如Gmail尚未授权
+	HealthDown     = "down"
+)
+
+// healthCheckTimeout 单个组件检查的超时时间
+const healthCheckTimeout = 5 * time.Second
+
+// ComponentHealth 单个组件的健康状态
+type ComponentHealth struct {
+	Status    string      `json:"status"`
+	Message   string      `json:"message,omitempty"`
+	LatencyMs int64       `json:"latency_ms"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// HealthReport 整体健康报告，任一组件不可用时整体为down，否则任一组件降级时整体为degraded
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+	Timestamp  int64                      `json:"timestamp"`
+}
+
+// HealthService 检查数据库、Gmail、定时任务和其他邮箱的状态
+type HealthService struct {
+	gmailService   *GmailService
+	scheduler      *Scheduler
+	mailboxService *MailboxService
+	startedAt      time.Time
+}
+
+// NewHealthService 创建健康检查服务实例
+func NewHealthService(gmailService *GmailService, scheduler *Scheduler, mailboxService *MailboxService) *HealthService {
+	return &HealthService{
+		gmailService:   gmailService,
+		scheduler:      scheduler,
+		mailboxService: mailboxService,
+		startedAt:      time.Now(),
+	}
+}
+
+// Uptime 进程运行时长
+func (hs *HealthService) Uptime() time.Duration {
+	return time.Since(hs.startedAt)
+}
+
+// Readiness 检查所有依赖组件
+func (hs *HealthService) Readiness(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Status:     HealthUp,
+		Components: map[string]ComponentHealth{},
+		Timestamp:  time.Now().Unix(),
+	}
+
+	report.Components["database"] = checkComponent(ctx, "database", "数据库不可用", database.Ping)
+	report.Components["gmail"] = hs.gmailHealth(ctx)
+	report.Components["scheduler"] = hs.schedulerHealth()
+	if statuses := hs.mailboxService.Statuses(); len(statuses) > 0 {
+		report.Components["mailboxes"] = mailboxesHealth(statuses)
+	}
+
+	for _, component := range report.Components {
+		switch {
+		case component.Status == HealthDown:
+			report.Status = HealthDown
+		case component.Status == HealthDegraded && report.Status == HealthUp:
+			report.Status = HealthDegraded
+		}
+	}
+
+	return report
+}
+
+// gmailHealth 检查Gmail，未完成授权时为degraded而不是down，
+// 以便降级启动的服务保持就绪，可以通过授权接口完成授权
+func (hs *HealthService) gmailHealth(ctx context.Context) ComponentHealth {
+	details := map[string]interface{}{
+		"auth_mode":  hs.gmailService.AuthMode(),
+		"authorized": hs.gmailService.Authorized(),
+	}
+	if _, err := hs.gmailService.api(); err != nil {
+		return ComponentHealth{Status: HealthDegraded, Message: "Gmail尚未授权或授权已失效，详情见 /api/v1/auth/google/status", Details: details}
+	}
+
+	health := checkComponent(ctx, "gmail", "Gmail API不可用", hs.gmailService.CheckHealth)
+	health.Details = details
+	return health
+}
+
+// schedulerHealth 定时任务停滞时视为不可用，等待Gmail授权时为degraded
+// 不返回最近一次的错误信息，详情通过需要认证的 /api/v1/schedulers 查看
+func (hs *HealthService) schedulerHealth() ComponentHealth {
+	status := hs.scheduler.Status()
+	status.LastError = ""
+
+	health := ComponentHealth{
+		Status:  HealthUp,
+		Details: status,
+	}
+	switch {
+	case status.Stale:
+		health.Status = HealthDown
+		health.Message = "定时任务长时间没有成功运行"
+	case status.WaitingAuth:
+		health.Status = HealthDegraded
+		health.Message = "Gmail尚未授权，定时任务跳过检查"
+	}
+	return health
+}
+
+// mailboxesHealth 汇总数据库中的邮箱状态，有邮箱启动失败、等待授权或定时任务停滞时为degraded
+// 就绪检查不需要认证，因此只返回数量，不返回邮箱地址和错误信息，详情通过需要认证的 /api/v1/mailboxes 查看
+func mailboxesHealth(statuses []MailboxStatus) ComponentHealth {
+	failed, unauthorized, stale := 0, 0, 0
+	for _, s := range statuses {
+		switch {
+		case s.Error != "":
+			failed++
+		case s.Scheduler != nil && s.Scheduler.Stale:
+			stale++
+		case s.Scheduler != nil && s.Scheduler.WaitingAuth:
+			unauthorized++
+		}
+	}
+
+	health := ComponentHealth{
+		Status: HealthUp,
+		Details: map[string]int{
+			"total":        len(statuses),
+			"failed":       failed,
+			"unauthorized": unauthorized,
+			"stale":        stale,
+		},
+	}
+	if n := failed + unauthorized + stale; n > 0 {
+		health.Status = HealthDegraded
+		health.Message = fmt.Sprintf("%d 个邮箱启动失败、尚未授权或长时间没有成功检查，详情见 /api/v1/mailboxes", n)
+	}
+	return health
+}
+
+// checkComponent 带超时执行检查函数，并记录耗时
+// 就绪检查不需要认证，失败时只返回固定的message，完整的错误写入日志
+func checkComponent(ctx context.Context, name, message string, check func(context.Context) error) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+
+	health := ComponentHealth{
+		Status:    HealthUp,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		utils.LoggerFromContext(ctx).Warnf("就绪检查 %s 失败: %v", name, err)
+		health.Status = HealthDown
+		health.Message = message
+	}
+	return health
+}
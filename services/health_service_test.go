@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// assertNotExposed 检查公开的健康状态中不包含内部错误信息
+func assertNotExposed(t *testing.T, health ComponentHealth, secret string) {
+	t.Helper()
+	body, err := json.Marshal(health)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), secret) {
+		t.Errorf("就绪检查暴露了内部错误: %s", body)
+	}
+}
+
+func TestCheckComponentHidesError(t *testing.T) {
+	secret := "Error 1045: Access denied for user 'root'@'10.0.0.3'"
+	health := checkComponent(context.Background(), "database", "数据库不可用", func(context.Context) error {
+		return errors.New(secret)
+	})
+
+	if health.Status != HealthDown || health.Message != "数据库不可用" {
+		t.Errorf("health = %+v", health)
+	}
+	assertNotExposed(t, health, "Access denied")
+}
+
+func TestGmailHealthUnauthorizedHidesError(t *testing.T) {
+	gs := newGmailService(GmailAuthOAuth, "me@example.com", nil)
+	gs.authErr = errors.New(`oauth2: "invalid_grant" "Token has been expired or revoked."`)
+
+	health := (&HealthService{gmailService: gs}).gmailHealth(context.Background())
+	if health.Status != HealthDegraded {
+		t.Errorf("status = %s, 期望 degraded", health.Status)
+	}
+	assertNotExposed(t, health, "invalid_grant")
+}
+
+func TestSchedulerHealthHidesLastError(t *testing.T) {
+	s := newScheduler("me@example.com", nil, time.Minute)
+	s.startedAt = time.Now()
+	s.recordRun(errors.New("dial tcp 10.0.0.3:3306: connect: connection refused"))
+
+	health := (&HealthService{scheduler: s}).schedulerHealth()
+	if health.Status != HealthUp {
+		t.Errorf("status = %s, 期望 up", health.Status)
+	}
+	assertNotExposed(t, health, "10.0.0.3")
+	if s.Status().LastError == "" {
+		t.Error("需要认证的调度器状态中应保留错误信息")
+	}
+}
+
+func TestSchedulerHealthWaitingAuth(t *testing.T) {
+	s := newScheduler("me@example.com", nil, time.Minute)
+	s.startedAt = time.Now().Add(-10 * time.Minute)
+	s.recordRun(ErrGmailNotAuthorized)
+
+	health := (&HealthService{scheduler: s}).schedulerHealth()
+	if health.Status != HealthDegraded {
+		t.Errorf("等待授权时 status = %s, 期望 degraded", health.Status)
+	}
+
+	mailboxes := mailboxesHealth([]MailboxStatus{{Scheduler: &SchedulerStatus{Running: true, WaitingAuth: true}}})
+	if mailboxes.Status != HealthDegraded || mailboxes.Details.(map[string]int)["unauthorized"] != 1 {
+		t.Errorf("mailboxes = %+v", mailboxes)
+	}
+}
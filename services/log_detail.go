@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrEmailLogNotFound 邮件日志不存在
+var ErrEmailLogNotFound = newError(KindNotFound, "email_log_not_found", "邮件日志不存在", "email log not found")
+
+// 转发内容预览的格式
+const (
+	PreviewFormatHTML     = "html"
+	PreviewFormatMarkdown = "markdown"
+)
+
+// ForwardPreview 根据邮件日志还原的转发内容，供管理界面对照原邮件查看
+type ForwardPreview struct {
+	Type    string `json:"type"`   // 转发目标的投递方式
+	Format  string `json:"format"` // html/markdown
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+// EmailLogDetail 邮件日志及转发内容预览
+type EmailLogDetail struct {
+	models.EmailLog
+	Mailbox   string          `json:"mailbox"`             // 收到邮件的邮箱地址
+	Forwarded *ForwardPreview `json:"forwarded,omitempty"` // 没有匹配到转发目标或通用webhook（推送完整原邮件）时为空
+}
+
+// GetEmailLog 根据ID获取调用方所属租户的邮件日志，并还原转发出去的内容
+func (es *EmailService) GetEmailLog(ctx context.Context, id uint) (*EmailLogDetail, error) {
+	db := database.GetDB().WithContext(ctx)
+
+	var emailLog models.EmailLog
+	if err := db.Scopes(tenantScope(ctx)).First(&emailLog, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrEmailLogNotFound, id)
+		}
+		return nil, err
+	}
+
+	detail := &EmailLogDetail{EmailLog: emailLog, Mailbox: es.gmailService.UserEmail()}
+	if emailLog.MailboxID != models.DefaultMailboxID {
+		var mb models.Mailbox
+		if err := db.Unscoped().Select("address").First(&mb, emailLog.MailboxID).Error; err == nil {
+			detail.Mailbox = mb.Address
+		}
+	}
+	detail.Forwarded = forwardPreview(detail.Mailbox, &emailLog)
+	return detail, nil
+}
+
+// forwardPreview 按日志中记录的投递方式还原转发内容，转发时的邮件正文即日志中保存的内容
+func forwardPreview(mailboxAddress string, emailLog *models.EmailLog) *ForwardPreview {
+	// 没有匹配到转发目标的日志不会记录目标邮箱和投递方式
+	matched := emailLog.ForwardEmail != "" || emailLog.TargetType != ""
+	if !matched || emailLog.TargetType == models.TargetTypeWebhook {
+		return nil
+	}
+
+	email := &EmailMessage{
+		ID:         emailLog.GmailMessageID,
+		Subject:    emailLog.Subject,
+		From:       emailLog.FromEmail,
+		To:         emailLog.ToEmail,
+		Body:       emailLog.Content,
+		ReceivedAt: emailLog.CreatedAt,
+	}
+
+	if emailLog.TargetType == models.TargetTypeEmail || emailLog.TargetType == "" {
+		subject, body := forwardContent(email)
+		return &ForwardPreview{Type: models.TargetTypeEmail, Format: PreviewFormatHTML, Subject: subject, Body: body}
+	}
+
+	target := &models.ForwardTarget{ID: emailLog.TargetID, Name: emailLog.ForwardTarget, Type: emailLog.TargetType}
+	summary := newSummary(mailboxAddress, email, emailLog.Keyword, target)
+	return &ForwardPreview{Type: emailLog.TargetType, Format: PreviewFormatMarkdown, Subject: summary.Subject, Body: markdownSummary(summary)}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FormatXLSX Excel工作簿格式，CSV使用 FormatCSV
+const FormatXLSX = "xlsx"
+
+const (
+	// MaxExportExcerpt 导出时正文摘录的最大字数
+	MaxExportExcerpt = 1000
+	// exportBatchSize 导出时每批从数据库读取的日志条数，内存占用与导出的总条数无关
+	exportBatchSize = 500
+	// exportContentLimit 生成摘录时最多从数据库读取的正文长度，避免读取完整的大邮件
+	exportContentLimit = 20000
+	// exportTimeLayout 导出文件中时间的格式
+	exportTimeLayout = "2006-01-02 15:04:05"
+)
+
+// logColumn 可导出的一列
+type logColumn struct {
+	name    string // 列名，即 columns 参数中的取值，与数据库字段同名
+	header  string // 中文表头
+	english string // 英文表头
+	value   func(log *models.EmailLog) interface{}
+}
+
+// logColumns 全部可导出的列，正文摘录由 excerpt 参数控制，不在此列出
+var logColumns = []logColumn{
+	{"id", "ID", "ID", func(l *models.EmailLog) interface{} { return int64(l.ID) }},
+	{"created_at", "记录时间", "Created at", func(l *models.EmailLog) interface{} { return l.CreatedAt }},
+	{"processed_at", "处理时间", "Processed at", func(l *models.EmailLog) interface{} { return l.ProcessedAt }},
+	{"mailbox_id", "邮箱ID", "Mailbox ID", func(l *models.EmailLog) interface{} { return int64(l.MailboxID) }},
+	{"from_email", "发件人", "From", func(l *models.EmailLog) interface{} { return l.FromEmail }},
+	{"to_email", "收件人", "To", func(l *models.EmailLog) interface{} { return l.ToEmail }},
+	{"subject", "主题", "Subject", func(l *models.EmailLog) interface{} { return l.Subject }},
+	{"keyword", "关键字", "Keyword", func(l *models.EmailLog) interface{} { return l.Keyword }},
+	{"forward_target", "转发目标", "Target", func(l *models.EmailLog) interface{} { return l.ForwardTarget }},
+	{"forward_email", "转发地址", "Forwarded to", func(l *models.EmailLog) interface{} { return l.ForwardEmail }},
+	{"target_type", "投递方式", "Target type", func(l *models.EmailLog) interface{} { return l.TargetType }},
+	{"forward_status", "转发状态", "Status", func(l *models.EmailLog) interface{} { return l.ForwardStatus }},
+	{"attempts", "尝试次数", "Attempts", func(l *models.EmailLog) interface{} { return int64(l.Attempts) }},
+	{"error_message", "错误信息", "Error", func(l *models.EmailLog) interface{} { return l.ErrorMessage }},
+	{"trace_id", "追踪ID", "Trace ID", func(l *models.EmailLog) interface{} { return l.TraceID }},
+	{"gmail_message_id", "Gmail消息ID", "Gmail message ID", func(l *models.EmailLog) interface{} { return l.GmailMessageID }},
+}
+
+// DefaultLogExportColumns 未指定 columns 时导出的列
+var DefaultLogExportColumns = []string{"id", "created_at", "from_email", "subject", "keyword", "forward_target", "forward_email", "forward_status", "error_message"}
+
+// LogExportColumns 返回全部可导出的列名
+func LogExportColumns() []string {
+	names := make([]string, len(logColumns))
+	for i, col := range logColumns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// LogExport 一次邮件日志导出的参数，创建时完成校验，之后写出时不会再因参数出错
+type LogExport struct {
+	format  string
+	columns []logColumn
+	excerpt int
+	lang    string
+}
+
+// NewLogExport 校验导出参数：columns 为空时导出默认列，excerpt 大于0时在最后追加该长度的正文摘录，
+// lang 为 en 时使用英文表头
+func NewLogExport(format string, columns []string, excerpt int, lang string) (*LogExport, error) {
+	if format != FormatCSV && format != FormatXLSX {
+		return nil, fmt.Errorf("%w: 不支持的格式 %s", ErrInvalidRequest, format)
+	}
+	if excerpt < 0 || excerpt > MaxExportExcerpt {
+		return nil, fmt.Errorf("%w: excerpt 必须在0到%d之间", ErrInvalidRequest, MaxExportExcerpt)
+	}
+	if len(columns) == 0 {
+		columns = DefaultLogExportColumns
+	}
+
+	export := &LogExport{format: format, excerpt: excerpt, lang: lang}
+	seen := make(map[string]bool)
+	for _, name := range columns {
+		col, ok := findLogColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: 未知的列 %s，可选值: %s", ErrInvalidRequest, name, strings.Join(LogExportColumns(), ", "))
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		export.columns = append(export.columns, col)
+	}
+	return export, nil
+}
+
+// findLogColumn 按列名查找可导出的列
+func findLogColumn(name string) (logColumn, bool) {
+	for _, col := range logColumns {
+		if col.name == name {
+			return col, true
+		}
+	}
+	return logColumn{}, false
+}
+
+// Format 返回导出格式
+func (e *LogExport) Format() string {
+	return e.format
+}
+
+// headers 返回表头
+func (e *LogExport) headers() []string {
+	headers := make([]string, 0, len(e.columns)+1)
+	for _, col := range e.columns {
+		if e.lang == "en" {
+			headers = append(headers, col.english)
+		} else {
+			headers = append(headers, col.header)
+		}
+	}
+	if e.excerpt > 0 {
+		if e.lang == "en" {
+			headers = append(headers, "Content excerpt")
+		} else {
+			headers = append(headers, "正文摘录")
+		}
+	}
+	return headers
+}
+
+// selectFields 返回需要从数据库读取的字段，分批读取依赖主键，因此总是包含id
+func (e *LogExport) selectFields() []string {
+	fields := []string{"id"}
+	for _, col := range e.columns {
+		if col.name != "id" {
+			fields = append(fields, col.name)
+		}
+	}
+	return fields
+}
+
+// row 返回一条日志导出的各列的值
+func (e *LogExport) row(log *models.EmailLog) []interface{} {
+	values := make([]interface{}, 0, len(e.columns)+1)
+	for _, col := range e.columns {
+		values = append(values, col.value(log))
+	}
+	if e.excerpt > 0 {
+		values = append(values, excerpt(log.Content, e.excerpt))
+	}
+	return values
+}
+
+// rowWriter 按导出格式逐行写出
+type rowWriter interface {
+	writeRow(values []interface{}) error
+	close() error
+}
+
+// ExportEmailLogs 按查询条件把调用方所属租户的邮件日志写入w，按ID从小到大分批读取并逐行写出，
+// 不会把全部日志载入内存；ctx 取消（例如客户端断开）时停止导出
+func (es *EmailService) ExportEmailLogs(ctx context.Context, filter EmailLogFilter, export *LogExport, w io.Writer) error {
+	var rw rowWriter
+	var err error
+	switch export.format {
+	case FormatXLSX:
+		sheetName := "邮件日志"
+		if export.lang == "en" {
+			sheetName = "Email logs"
+		}
+		rw, err = newXLSXWriter(w, sheetName, export.headers())
+	default:
+		rw, err = newCSVRowWriter(w, export.headers())
+	}
+	if err != nil {
+		return err
+	}
+
+	query := database.GetDB().WithContext(ctx).Model(&models.EmailLog{}).Scopes(tenantScope(ctx), filter.scope)
+	if export.excerpt > 0 {
+		// 只读取正文开头的一段用于生成摘录
+		query = query.Select(strings.Join(export.selectFields(), ", ")+", LEFT(content, ?) AS content", exportContentLimit)
+	} else {
+		query = query.Select(export.selectFields())
+	}
+
+	var batch []models.EmailLog
+	result := query.FindInBatches(&batch, exportBatchSize, func(_ *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := rw.writeRow(export.row(&batch[i])); err != nil {
+				return err
+			}
+		}
+		return ctx.Err()
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	return rw.close()
+}
+
+// csvRowWriter 写出CSV，带UTF-8 BOM以便Excel正确识别中文
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+// newCSVRowWriter 写出BOM和表头
+func newCSVRowWriter(w io.Writer, headers []string) (*csvRowWriter, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	cw := &csvRowWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(headers); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvRowWriter) writeRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case time.Time:
+			record[i] = v.Local().Format(exportTimeLayout)
+		case *time.Time:
+			if v != nil {
+				record[i] = v.Local().Format(exportTimeLayout)
+			}
+		case string:
+			record[i] = csvSafe(v)
+		}
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvRowWriter) close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// csvSafe 以 = + - @ 等开头的文本加上单引号，防止邮件主题等内容在表格软件中被当作公式执行
+func csvSafe(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
@@ -0,0 +1,770 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/config"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"email-forwarding/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMailboxNotFound 邮箱不存在
+	ErrMailboxNotFound = newError(KindNotFound, "mailbox_not_found", "邮箱不存在", "mailbox not found")
+	// ErrMailboxAddressExists 邮箱地址已被使用
+	ErrMailboxAddressExists = newError(KindConflict, "mailbox_address_exists", "邮箱地址已存在", "mailbox address already exists")
+	// ErrInvalidMailbox 邮箱参数无效
+	ErrInvalidMailbox = newError(KindValidation, "invalid_mailbox", "邮箱参数无效", "invalid mailbox")
+	// ErrMailboxInUse 邮箱仍被转发目标引用
+	ErrMailboxInUse = newError(KindConflict, "mailbox_in_use", "邮箱仍被转发目标使用", "mailbox is still used by forward targets")
+	// ErrMailboxInactive 邮箱已停用或启动失败
+	ErrMailboxInactive = newError(KindConflict, "mailbox_inactive", "邮箱未在运行", "mailbox is not running")
+)
+
+// MailboxPatch 邮箱的部分更新，只有非nil的字段会被写入
+type MailboxPatch struct {
+	Name               *string `json:"name"`
+	Address            *string `json:"address"`
+	Provider           *string `json:"provider"`
+	AuthMode           *string `json:"auth_mode"`
+	CredentialsFile    *string `json:"credentials_file"`
+	TokenFile          *string `json:"token_file"`
+	ServiceAccountFile *string `json:"service_account_file"`
+	Query              *string `json:"query"`
+	CheckInterval      *string `json:"check_interval"`
+	IsActive           *bool   `json:"is_active"`
+}
+
+// MailboxStatus 邮箱及其运行状态
+type MailboxStatus struct {
+	models.Mailbox
+	Running    bool             `json:"running"`
+	Authorized bool             `json:"authorized"`
+	Scheduler  *SchedulerStatus `json:"scheduler,omitempty"`
+	Error      string           `json:"error,omitempty"` // 启动失败的原因
+}
+
+// SchedulerInfo 一个邮箱的定时任务状态
+type SchedulerInfo struct {
+	MailboxID uint   `json:"mailbox_id"`
+	Mailbox   string `json:"mailbox"`
+	SchedulerStatus
+}
+
+// mailboxRunner 一个邮箱的Gmail服务和定时任务
+type mailboxRunner struct {
+	mailbox   models.Mailbox
+	gmail     *GmailService
+	scheduler *Scheduler
+	cancel    context.CancelFunc
+}
+
+// MailboxService 管理数据库中的邮箱，每个启用的邮箱有独立的Gmail服务和定时任务
+// 配置文件中的默认邮箱由 EmailService 和 Scheduler 直接处理，不在这里管理
+type MailboxService struct {
+	emailService *EmailService
+	scheduler    *Scheduler // 默认邮箱的定时任务，由main创建
+	proxy        func(*http.Request) (*url.URL, error)
+
+	// restartMu 串行化邮箱的重启，停止旧的定时任务时不持有mu，避免查询状态等操作被长时间阻塞
+	restartMu sync.Mutex
+
+	mu      sync.Mutex
+	ctx     context.Context // Start传入的ctx，邮箱的定时任务随它退出
+	gmail   config.GmailConfig
+	app     config.AppConfig
+	runners map[uint]*mailboxRunner
+	failed  map[uint]mailboxFailure
+}
+
+// mailboxFailure 启动失败的邮箱及原因
+type mailboxFailure struct {
+	mailbox models.Mailbox
+	err     error
+}
+
+// NewMailboxService 创建邮箱服务实例，邮箱未配置的凭据、搜索条件和检查间隔使用cfg中的全局配置
+func NewMailboxService(cfg *config.Config, emailService *EmailService, proxy func(*http.Request) (*url.URL, error)) *MailboxService {
+	return &MailboxService{
+		emailService: emailService,
+		proxy:        proxy,
+		gmail:        cfg.Gmail,
+		app:          cfg.App,
+		runners:      make(map[uint]*mailboxRunner),
+		failed:       make(map[uint]mailboxFailure),
+	}
+}
+
+// Start 为所有启用的邮箱启动定时任务，ctx被取消时全部停止
+// 加载失败时之后通过接口创建或修改的邮箱仍会启动
+func (ms *MailboxService) Start(ctx context.Context) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.ctx = ctx
+
+	var mailboxes []models.Mailbox
+	if err := database.GetDB().WithContext(ctx).Where("is_active = ?", true).Order("id").Find(&mailboxes).Error; err != nil {
+		return fmt.Errorf("加载邮箱失败: %v", err)
+	}
+	for _, mb := range mailboxes {
+		ms.startRunner(mb)
+	}
+	if len(mailboxes) > 0 {
+		utils.GetLogger().Infof("已启动 %d 个邮箱的定时任务", len(mailboxes))
+	}
+	return nil
+}
+
+// Wait 等待所有邮箱的定时任务退出，超过ctx的截止时间时返回ctx的错误
+func (ms *MailboxService) Wait(ctx context.Context) error {
+	ms.mu.Lock()
+	runners := make([]*mailboxRunner, 0, len(ms.runners))
+	for _, r := range ms.runners {
+		runners = append(runners, r)
+	}
+	ms.mu.Unlock()
+
+	for _, r := range runners {
+		select {
+		case <-r.scheduler.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ApplyDefaults 更新全局配置，未单独设置检查间隔、搜索条件的邮箱立即使用新的配置
+func (ms *MailboxService) ApplyDefaults(cfg *config.Config) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.gmail = cfg.Gmail
+	ms.app = cfg.App
+	for _, r := range ms.runners {
+		r.scheduler.SetInterval(ms.checkInterval(r.mailbox))
+		r.gmail.SetFetchOptions(ms.query(r.mailbox), ms.app.MaxEmailsPerBatch, ms.app.MaxBatches)
+	}
+}
+
+// startRunner 为邮箱创建Gmail服务并启动定时任务，调用方需持有ms.mu
+// 创建失败时记录原因，不影响其他邮箱
+func (ms *MailboxService) startRunner(mb models.Mailbox) {
+	logger := utils.GetLogger().WithField("mailbox", mb.Address)
+
+	gmailService, err := ms.newGmailService(mb)
+	if err != nil {
+		ms.failed[mb.ID] = mailboxFailure{mailbox: mb, err: err}
+		logger.Errorf("邮箱启动失败: %v", err)
+		return
+	}
+	delete(ms.failed, mb.ID)
+	if !gmailService.Authorized() {
+		logger.Warnf("邮箱尚未授权：请调用 GET /api/v1/auth/google/start?mailbox_id=%d 完成授权", mb.ID)
+	}
+	gmailService.SetFetchOptions(ms.query(mb), ms.app.MaxEmailsPerBatch, ms.app.MaxBatches)
+
+	source := mailbox{id: mb.ID, tenantID: mb.TenantID, gmail: gmailService}
+	scheduler := newScheduler(mb.Address, func(ctx context.Context) error {
+		return ms.emailService.processMailbox(ctx, source)
+	}, ms.checkInterval(mb))
+
+	runCtx, cancel := context.WithCancel(ms.ctx)
+	ms.runners[mb.ID] = &mailboxRunner{
+		mailbox:   mb,
+		gmail:     gmailService,
+		scheduler: scheduler,
+		cancel:    cancel,
+	}
+	go scheduler.Run(runCtx)
+}
+
+// detachRunner 从运行列表中移除邮箱并返回其定时任务，调用方需持有ms.mu
+func (ms *MailboxService) detachRunner(id uint) *mailboxRunner {
+	delete(ms.failed, id)
+
+	r, ok := ms.runners[id]
+	if !ok {
+		return nil
+	}
+	delete(ms.runners, id)
+	return r
+}
+
+// stop 停止定时任务并等待正在进行的检查结束，可能需要等待一次完整的邮件处理，调用方不能持有ms.mu
+func (r *mailboxRunner) stop() {
+	r.cancel()
+	<-r.scheduler.Done()
+	deleteMailboxMetrics(r.mailbox.Address)
+}
+
+// restartRunner 邮箱修改后按新配置重启定时任务，停用的邮箱只停止
+// 等待旧的定时任务结束时不持有ms.mu，期间邮箱状态中已不包含该邮箱
+func (ms *MailboxService) restartRunner(id uint, mb *models.Mailbox) {
+	ms.restartMu.Lock()
+	defer ms.restartMu.Unlock()
+
+	ms.mu.Lock()
+	r := ms.detachRunner(id)
+	ms.mu.Unlock()
+	if r != nil {
+		r.stop()
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.ctx != nil && mb != nil && mb.IsActive {
+		ms.startRunner(*mb)
+	}
+}
+
+// newGmailService 按邮箱的认证方式创建Gmail服务，未设置的凭据文件使用全局配置
+func (ms *MailboxService) newGmailService(mb models.Mailbox) (*GmailService, error) {
+	if mb.AuthMode == GmailAuthServiceAccount {
+		keyFile := mb.ServiceAccountFile
+		if keyFile == "" {
+			keyFile = ms.gmail.ServiceAccountFile
+		}
+		return NewServiceAccountGmailService(keyFile, mb.Address, ms.proxy)
+	}
+
+	credentialsFile := mb.CredentialsFile
+	if credentialsFile == "" {
+		credentialsFile = ms.gmail.CredentialsFile
+	}
+	return NewGmailService(credentialsFile, mailboxTokenFile(mb), mb.Address, ms.proxy)
+}
+
+// mailboxTokenFile 邮箱的token文件，未设置时为 token-<邮箱地址>.json，默认租户以外的邮箱放在租户目录中
+func mailboxTokenFile(mb models.Mailbox) string {
+	if mb.TokenFile != "" {
+		return mb.TokenFile
+	}
+	name := "token-" + mb.Address + ".json"
+	if mb.TenantID != models.DefaultTenantID {
+		return filepath.Join(tenantFilesDir(mb.TenantID), name)
+	}
+	return name
+}
+
+// tenantFilesDir 租户的凭据和token文件所在的目录，非超级管理员设置的文件路径都限定在该目录中
+func tenantFilesDir(tenantID uint) string {
+	return filepath.Join("tenants", strconv.FormatUint(uint64(tenantID), 10))
+}
+
+// checkMailboxFiles 非超级管理员只能使用租户目录中的凭据和token文件，也不能用全局服务账号密钥模拟任意邮箱
+// before 为修改前的邮箱，创建时为nil；只检查本次修改的字段，超级管理员之前的设置保持有效
+func checkMailboxFiles(ctx context.Context, before, after *models.Mailbox) error {
+	if isSuperAdmin(ctx) {
+		return nil
+	}
+
+	var prev models.Mailbox
+	if before != nil {
+		prev = *before
+	}
+	for _, f := range []struct {
+		name   string
+		before string
+		path   *string
+	}{
+		{"credentials_file", prev.CredentialsFile, &after.CredentialsFile},
+		{"token_file", prev.TokenFile, &after.TokenFile},
+		{"service_account_file", prev.ServiceAccountFile, &after.ServiceAccountFile},
+	} {
+		if before != nil && *f.path == f.before {
+			continue
+		}
+		path, err := confineTenantPath(after.TenantID, *f.path)
+		if err != nil {
+			return fmt.Errorf("%w: %s %v", ErrInvalidMailbox, f.name, err)
+		}
+		*f.path = path
+	}
+
+	// 全局服务账号密钥可以模拟域内任意邮箱，只有超级管理员可以为邮箱启用或改变其模拟的地址
+	usesGlobalKey := func(mb *models.Mailbox) bool {
+		return mb.AuthMode == GmailAuthServiceAccount && mb.ServiceAccountFile == ""
+	}
+	if usesGlobalKey(after) && (before == nil || !usesGlobalKey(before) || before.Address != after.Address) {
+		return fmt.Errorf("%w: 只有超级管理员可以使用全局服务账号密钥，请设置租户目录 %s 中的 service_account_file",
+			ErrForbidden, tenantFilesDir(after.TenantID))
+	}
+	return nil
+}
+
+// confineTenantPath 把文件路径限定在租户目录中：不允许绝对路径和 ..，相对路径视为相对于租户目录
+func confineTenantPath(tenantID uint, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("不能是绝对路径: %s", path)
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("不能包含 ..: %s", path)
+		}
+	}
+
+	dir := tenantFilesDir(tenantID)
+	path = filepath.Clean(path)
+	if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return path, nil
+	}
+	return filepath.Join(dir, path), nil
+}
+
+// query 邮箱的搜索条件，未设置时使用全局配置，调用方需持有ms.mu
+func (ms *MailboxService) query(mb models.Mailbox) string {
+	if mb.Query != "" {
+		return mb.Query
+	}
+	return ms.gmail.Query
+}
+
+// checkInterval 邮箱的检查间隔，未设置时使用全局配置，调用方需持有ms.mu
+// 升级前保存的小于下限的间隔按下限处理
+func (ms *MailboxService) checkInterval(mb models.Mailbox) time.Duration {
+	if d, err := time.ParseDuration(mb.CheckInterval); err == nil && d > 0 {
+		return max(d, config.MinCheckInterval)
+	}
+	return ms.app.CheckInterval
+}
+
+// GetMailboxes 获取调用方所属租户的邮箱及运行状态
+func (ms *MailboxService) GetMailboxes(ctx context.Context) ([]MailboxStatus, error) {
+	var mailboxes []models.Mailbox
+	if err := database.GetDB().WithContext(ctx).Scopes(tenantScope(ctx)).Order("id").Find(&mailboxes).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]MailboxStatus, 0, len(mailboxes))
+	for _, mb := range mailboxes {
+		result = append(result, ms.status(mb))
+	}
+	return result, nil
+}
+
+// GetMailbox 根据ID获取调用方所属租户的邮箱及运行状态
+func (ms *MailboxService) GetMailbox(ctx context.Context, id uint) (*MailboxStatus, error) {
+	var mb models.Mailbox
+	if err := database.GetDB().WithContext(ctx).Scopes(tenantScope(ctx)).First(&mb, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrMailboxNotFound, id)
+		}
+		return nil, err
+	}
+
+	status := ms.status(mb)
+	return &status, nil
+}
+
+// status 组合邮箱记录和运行状态
+func (ms *MailboxService) status(mb models.Mailbox) MailboxStatus {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	status := MailboxStatus{Mailbox: mb}
+	if r, ok := ms.runners[mb.ID]; ok {
+		schedulerStatus := r.scheduler.Status()
+		status.Running = true
+		status.Authorized = r.gmail.Authorized()
+		status.Scheduler = &schedulerStatus
+	}
+	if failure, ok := ms.failed[mb.ID]; ok {
+		status.Error = failure.err.Error()
+	}
+	return status
+}
+
+// Statuses 返回所有运行中或启动失败的邮箱的状态，按ID排序
+func (ms *MailboxService) Statuses() []MailboxStatus {
+	ms.mu.Lock()
+	mailboxes := make([]models.Mailbox, 0, len(ms.runners)+len(ms.failed))
+	for _, r := range ms.runners {
+		mailboxes = append(mailboxes, r.mailbox)
+	}
+	for _, failure := range ms.failed {
+		mailboxes = append(mailboxes, failure.mailbox)
+	}
+	ms.mu.Unlock()
+
+	sort.Slice(mailboxes, func(i, j int) bool { return mailboxes[i].ID < mailboxes[j].ID })
+	result := make([]MailboxStatus, 0, len(mailboxes))
+	for _, mb := range mailboxes {
+		result = append(result, ms.status(mb))
+	}
+	return result
+}
+
+// GmailService 返回调用方所属租户中邮箱的Gmail服务，id为 models.DefaultMailboxID 时返回默认邮箱的服务
+func (ms *MailboxService) GmailService(ctx context.Context, id uint) (*GmailService, error) {
+	if id == models.DefaultMailboxID {
+		if !tenantAllowed(ctx, models.DefaultTenantID) {
+			return nil, fmt.Errorf("%w: 默认邮箱不属于当前租户", ErrMailboxNotFound)
+		}
+		return ms.emailService.gmailService, nil
+	}
+
+	r, err := ms.runner(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.gmail, nil
+}
+
+// runner 返回调用方所属租户中运行中的邮箱
+func (ms *MailboxService) runner(ctx context.Context, id uint) (*mailboxRunner, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	r, ok := ms.runners[id]
+	if !ok {
+		if failure, failed := ms.failed[id]; failed && tenantAllowed(ctx, failure.mailbox.TenantID) {
+			return nil, fmt.Errorf("%w: 邮箱 %d 启动失败: %v", ErrMailboxInactive, id, failure.err)
+		}
+		return nil, fmt.Errorf("%w: %d", ErrMailboxInactive, id)
+	}
+	if !tenantAllowed(ctx, r.mailbox.TenantID) {
+		return nil, fmt.Errorf("%w: %d", ErrMailboxNotFound, id)
+	}
+	return r, nil
+}
+
+// CompleteAuthorization 完成OAuth授权，根据state找到发起授权的邮箱
+func (ms *MailboxService) CompleteAuthorization(ctx context.Context, state, code string) error {
+	gmailService := ms.emailService.gmailService
+	ms.mu.Lock()
+	for _, r := range ms.runners {
+		if r.gmail.hasPendingAuthorization(state) {
+			gmailService = r.gmail
+			break
+		}
+	}
+	ms.mu.Unlock()
+
+	return gmailService.CompleteAuthorization(ctx, state, code)
+}
+
+// ProcessMailbox 立即检查一次邮箱，id为 models.DefaultMailboxID 时检查默认邮箱
+func (ms *MailboxService) ProcessMailbox(ctx context.Context, id uint) error {
+	if id == models.DefaultMailboxID {
+		return ms.emailService.ProcessEmails(ctx)
+	}
+
+	r, err := ms.runner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return ms.emailService.processMailbox(ctx, mailbox{id: id, tenantID: r.mailbox.TenantID, gmail: r.gmail})
+}
+
+// SetDefaultScheduler 设置默认邮箱的定时任务，之后可以通过接口查看和暂停
+func (ms *MailboxService) SetDefaultScheduler(scheduler *Scheduler) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.scheduler = scheduler
+}
+
+// GetSchedulers 获取调用方所属租户中运行中邮箱的定时任务状态，默认邮箱排在最前，其余按ID排序
+func (ms *MailboxService) GetSchedulers(ctx context.Context) []SchedulerInfo {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	result := make([]SchedulerInfo, 0, len(ms.runners)+1)
+	if ms.scheduler != nil && tenantAllowed(ctx, models.DefaultTenantID) {
+		result = append(result, SchedulerInfo{
+			MailboxID:       models.DefaultMailboxID,
+			Mailbox:         ms.emailService.gmailService.UserEmail(),
+			SchedulerStatus: ms.scheduler.Status(),
+		})
+	}
+
+	ids := make([]uint, 0, len(ms.runners))
+	for id, r := range ms.runners {
+		if tenantAllowed(ctx, r.mailbox.TenantID) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		r := ms.runners[id]
+		result = append(result, SchedulerInfo{
+			MailboxID:       id,
+			Mailbox:         r.mailbox.Address,
+			SchedulerStatus: r.scheduler.Status(),
+		})
+	}
+	return result
+}
+
+// PauseScheduler 暂停或恢复邮箱的定时任务，id为 models.DefaultMailboxID 时操作默认邮箱
+// 暂停状态只保存在内存中，服务重启或邮箱被修改后恢复运行
+func (ms *MailboxService) PauseScheduler(ctx context.Context, id uint, paused bool) (*SchedulerInfo, error) {
+	info := SchedulerInfo{MailboxID: id}
+
+	var scheduler *Scheduler
+	if id == models.DefaultMailboxID {
+		if !tenantAllowed(ctx, models.DefaultTenantID) {
+			return nil, fmt.Errorf("%w: 默认邮箱不属于当前租户", ErrMailboxNotFound)
+		}
+		ms.mu.Lock()
+		scheduler = ms.scheduler
+		ms.mu.Unlock()
+		if scheduler == nil {
+			return nil, fmt.Errorf("%w: 默认邮箱的定时任务未启动", ErrMailboxInactive)
+		}
+		info.Mailbox = ms.emailService.gmailService.UserEmail()
+	} else {
+		r, err := ms.runner(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		scheduler = r.scheduler
+		info.Mailbox = r.mailbox.Address
+	}
+
+	scheduler.SetPaused(paused)
+	info.SchedulerStatus = scheduler.Status()
+	return &info, nil
+}
+
+// GetAttachment 下载已处理邮件中的附件，邮件必须属于调用方所属租户，供通用webhook中的附件链接使用
+func (ms *MailboxService) GetAttachment(ctx context.Context, messageID, partID string) (*Attachment, []byte, error) {
+	var emailLog models.EmailLog
+	err := database.GetDB().WithContext(ctx).Scopes(tenantScope(ctx)).
+		Where("gmail_message_id = ?", messageID).First(&emailLog).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrAttachmentNotFound, messageID)
+		}
+		return nil, nil, err
+	}
+
+	gmailService, err := ms.GmailService(ctx, emailLog.MailboxID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gmailService.GetAttachment(ctx, messageID, partID)
+}
+
+// CreateMailbox 在调用方所属租户下创建邮箱，启用的邮箱立即开始定时检查
+func (ms *MailboxService) CreateMailbox(ctx context.Context, mb *models.Mailbox) error {
+	if err := ms.normalizeMailbox(mb); err != nil {
+		return err
+	}
+	mb.TenantID = tenantFromContext(ctx)
+	if err := checkMailboxFiles(ctx, nil, mb); err != nil {
+		return err
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := checkMailboxQuota(tx, mb.TenantID); err != nil {
+			return err
+		}
+		if err := checkMailboxAddressUnique(tx, mb.Address, 0); err != nil {
+			return err
+		}
+
+		if err := tx.Create(mb).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, mb.TenantID, models.AuditActionCreate, models.EntityMailbox, mb.ID, nil, mb)
+	})
+	if err != nil {
+		return err
+	}
+
+	ms.restartRunner(mb.ID, mb)
+	return nil
+}
+
+// PatchMailbox 按字段掩码更新邮箱，之后按新配置重启该邮箱的定时任务
+func (ms *MailboxService) PatchMailbox(ctx context.Context, id uint, patch MailboxPatch) (*models.Mailbox, error) {
+	var after models.Mailbox
+	changed := false
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var before models.Mailbox
+		if err := tx.Scopes(tenantScope(ctx)).First(&before, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %d", ErrMailboxNotFound, id)
+			}
+			return err
+		}
+
+		after = before
+		var columns []string
+		set := func(column string, dst *string, value *string) {
+			if value != nil {
+				*dst = *value
+				columns = append(columns, column)
+			}
+		}
+		set("name", &after.Name, patch.Name)
+		set("address", &after.Address, patch.Address)
+		set("provider", &after.Provider, patch.Provider)
+		set("auth_mode", &after.AuthMode, patch.AuthMode)
+		set("credentials_file", &after.CredentialsFile, patch.CredentialsFile)
+		set("token_file", &after.TokenFile, patch.TokenFile)
+		set("service_account_file", &after.ServiceAccountFile, patch.ServiceAccountFile)
+		set("query", &after.Query, patch.Query)
+		set("check_interval", &after.CheckInterval, patch.CheckInterval)
+		if patch.IsActive != nil {
+			after.IsActive = *patch.IsActive
+			columns = append(columns, "is_active")
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+
+		if err := ms.normalizeMailbox(&after); err != nil {
+			return err
+		}
+		if err := checkMailboxFiles(ctx, &before, &after); err != nil {
+			return err
+		}
+		if after.Address != before.Address {
+			if err := checkMailboxAddressUnique(tx, after.Address, id); err != nil {
+				return err
+			}
+		}
+
+		// 用Select指定字段，确保false、空字符串等零值也能写入
+		columns = append(columns, "updated_at")
+		if err := tx.Model(&before).Select(columns).Updates(&after).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&after, id).Error; err != nil {
+			return err
+		}
+		changed = true
+		return recordAudit(ctx, tx, before.TenantID, models.AuditActionUpdate, models.EntityMailbox, id, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		ms.restartRunner(id, &after)
+	}
+	return &after, nil
+}
+
+// DeleteMailbox 删除邮箱并停止其定时任务，仍有转发目标指定该邮箱时不允许删除
+func (ms *MailboxService) DeleteMailbox(ctx context.Context, id uint) error {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var before models.Mailbox
+		if err := tx.Scopes(tenantScope(ctx)).First(&before, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %d", ErrMailboxNotFound, id)
+			}
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.ForwardTarget{}).Where("mailbox_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: 还有 %d 个转发目标指定了该邮箱", ErrMailboxInUse, count)
+		}
+
+		if err := tx.Delete(&before).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, before.TenantID, models.AuditActionDelete, models.EntityMailbox, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	ms.restartRunner(id, nil)
+	return nil
+}
+
+// normalizeMailbox 校验并规范化邮箱的字段，未设置的提供方和认证方式使用默认值
+func (ms *MailboxService) normalizeMailbox(mb *models.Mailbox) error {
+	mb.Name = strings.TrimSpace(mb.Name)
+	if mb.Name == "" {
+		return fmt.Errorf("%w: 名称不能为空", ErrInvalidMailbox)
+	}
+
+	address := strings.TrimSpace(mb.Address)
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return fmt.Errorf("%w: 无效的邮箱地址 %q", ErrInvalidMailbox, address)
+	}
+	mb.Address = strings.ToLower(addr.Address)
+
+	ms.mu.Lock()
+	defaultAddress := ms.gmail.UserEmail
+	ms.mu.Unlock()
+	if strings.EqualFold(mb.Address, defaultAddress) {
+		return fmt.Errorf("%w: %s 是配置文件中的默认邮箱", ErrMailboxAddressExists, mb.Address)
+	}
+
+	if mb.Provider == "" {
+		mb.Provider = models.ProviderGmail
+	}
+	if mb.Provider != models.ProviderGmail {
+		return fmt.Errorf("%w: 不支持的提供方 %q，目前只支持 gmail", ErrInvalidMailbox, mb.Provider)
+	}
+
+	if mb.AuthMode == "" {
+		mb.AuthMode = GmailAuthOAuth
+	}
+	if mb.AuthMode != GmailAuthOAuth && mb.AuthMode != GmailAuthServiceAccount {
+		return fmt.Errorf("%w: 认证方式 %q 无效，应为 oauth 或 service_account", ErrInvalidMailbox, mb.AuthMode)
+	}
+
+	mb.CredentialsFile = strings.TrimSpace(mb.CredentialsFile)
+	mb.TokenFile = strings.TrimSpace(mb.TokenFile)
+	mb.ServiceAccountFile = strings.TrimSpace(mb.ServiceAccountFile)
+	mb.Query = strings.TrimSpace(mb.Query)
+
+	mb.CheckInterval = strings.TrimSpace(mb.CheckInterval)
+	if mb.CheckInterval != "" {
+		d, err := time.ParseDuration(mb.CheckInterval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%w: 检查间隔 %q 无效，应为 30s、5m 这样的格式", ErrInvalidMailbox, mb.CheckInterval)
+		}
+		if d < config.MinCheckInterval {
+			return fmt.Errorf("%w: 检查间隔 %s 不能小于%s", ErrInvalidMailbox, mb.CheckInterval, config.MinCheckInterval)
+		}
+	}
+
+	return nil
+}
+
+// checkMailboxAddressUnique 检查邮箱地址是否已被其他邮箱使用，excludeID为当前更新的邮箱
+func checkMailboxAddressUnique(tx *gorm.DB, address string, excludeID uint) error {
+	var count int64
+	query := tx.Model(&models.Mailbox{}).Where("address = ?", address)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrMailboxAddressExists, address)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/config"
+	"email-forwarding/models"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newTestMailboxService() *MailboxService {
+	ms := NewMailboxService(config.Default(), NewEmailService(nil, nil), nil)
+	ms.ctx = context.Background()
+	return ms
+}
+
+func TestCreateMailboxInactive(t *testing.T) {
+	mock := useMockDB(t)
+	inserts := captureInserts(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `tenants`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_active"}).AddRow(models.DefaultTenantID, true))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `mailboxes`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO `mailboxes`").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO `audit_logs`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ms := newTestMailboxService()
+	mb := &models.Mailbox{Name: "ops", Address: "ops@example.com", IsActive: false}
+	if err := ms.CreateMailbox(context.Background(), mb); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := inserts["mailboxes"]["is_active"]; !ok || got != false {
+		t.Errorf("写入的 is_active = %v（列存在: %v），期望 false", got, ok)
+	}
+	if len(ms.runners) != 0 || len(ms.failed) != 0 {
+		t.Errorf("停用的邮箱不应启动定时任务: runners = %d, failed = %d", len(ms.runners), len(ms.failed))
+	}
+}
+
+func TestNormalizeMailboxCheckInterval(t *testing.T) {
+	tests := []struct {
+		interval string
+		valid    bool
+	}{
+		{"", true},
+		{"30s", true},
+		{"5m", true},
+		{"1ms", false},
+		{"29s", false},
+		{"-1m", false},
+		{"soon", false},
+	}
+
+	ms := newTestMailboxService()
+	for _, tt := range tests {
+		mb := &models.Mailbox{Name: "ops", Address: "ops@example.com", CheckInterval: tt.interval}
+		err := ms.normalizeMailbox(mb)
+		if tt.valid && err != nil {
+			t.Errorf("%q: %v", tt.interval, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidMailbox) {
+			t.Errorf("%q: 期望 ErrInvalidMailbox，实际 %v", tt.interval, err)
+		}
+	}
+}
+
+func TestCheckIntervalFloor(t *testing.T) {
+	ms := newTestMailboxService()
+	if got := ms.checkInterval(models.Mailbox{CheckInterval: "1ms"}); got != config.MinCheckInterval {
+		t.Errorf("已保存的过小间隔 = %s, 期望按下限 %s 处理", got, config.MinCheckInterval)
+	}
+	if got := ms.checkInterval(models.Mailbox{}); got != config.Default().App.CheckInterval {
+		t.Errorf("未设置时 = %s, 期望全局配置", got)
+	}
+}
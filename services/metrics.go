@@ -0,0 +1,128 @@
+package services
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsNamespace 指标名称前缀
+const metricsNamespace = "email_forwarding"
+
+// 跳过和失败的原因标签
+const (
+	reasonAlreadyProcessed = "already_processed"
+	reasonNoMatch          = "no_match"
+	reasonTargetNotFound   = "target_not_found"
+	reasonSendFailed       = "send_failed"
+	reasonSaveFailed       = "save_failed"
+	reasonQuotaExceeded    = "quota_exceeded"
+)
+
+var (
+	messagesFetched = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_fetched_total",
+		Help:      "从Gmail拉取的邮件数量",
+	})
+
+	messagesForwarded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_forwarded_total",
+		Help:      "转发成功的邮件数量",
+	}, []string{"target"})
+
+	messagesSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_skipped_total",
+		Help:      "跳过的邮件数量",
+	}, []string{"reason"})
+
+	deliveryRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "delivery_retries_total",
+		Help:      "转发失败后安排的重试次数",
+	}, []string{"type"})
+
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_failed_total",
+		Help:      "处理失败的邮件数量",
+	}, []string{"target", "reason"})
+
+	gmailAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "gmail_api_duration_seconds",
+		Help:      "Gmail API调用耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "status"})
+
+	forwardDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "forward_duration_seconds",
+		Help:      "单封邮件从开始处理到转发完成的耗时",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"status"})
+
+	// 以下按邮箱区分的指标使用邮箱地址作为mailbox标签，邮箱数量有限，不会导致基数失控
+	unreadBacklog = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unread_backlog",
+		Help:      "Gmail估算的未读邮件数量",
+	}, []string{"mailbox"})
+
+	schedulerLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "scheduler_last_success_timestamp_seconds",
+		Help:      "定时任务最后一次成功完成的时间",
+	}, []string{"mailbox"})
+
+	schedulerRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "scheduler_runs_total",
+		Help:      "定时任务执行次数",
+	}, []string{"mailbox", "status"})
+
+	gmailAuthorized = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gmail_authorized",
+		Help:      "Gmail是否已授权，1为已授权，0为需要重新授权",
+	}, []string{"mailbox"})
+
+	tokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "gmail_token_refreshes_total",
+		Help:      "OAuth access token刷新次数",
+	}, []string{"status"})
+
+	streamSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_stream_subscribers",
+		Help:      "当前连接的实时事件流客户端数量",
+	})
+
+	streamEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_stream_dropped_total",
+		Help:      "客户端消费过慢而丢弃的实时事件数量",
+	})
+)
+
+// deleteMailboxMetrics 删除邮箱的指标，邮箱被删除或停用后不再输出
+func deleteMailboxMetrics(mailbox string) {
+	unreadBacklog.DeleteLabelValues(mailbox)
+	schedulerLastSuccess.DeleteLabelValues(mailbox)
+	schedulerRuns.DeleteLabelValues(mailbox, "success")
+	schedulerRuns.DeleteLabelValues(mailbox, "failed")
+	gmailAuthorized.DeleteLabelValues(mailbox)
+}
+
+// observeGmailCall 记录一次Gmail API调用的耗时
+func observeGmailCall(operation string, start time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	gmailAPIDuration.WithLabelValues(operation, status).Observe(time.Since(start).Seconds())
+}
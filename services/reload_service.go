@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/config"
+	"email-forwarding/utils"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrInvalidConfigFile 重新加载时配置文件或环境变量无效，原配置保持不变
+var ErrInvalidConfigFile = newError(KindUnprocessable, "invalid_config_file", "配置文件无效", "invalid configuration file")
+
+// ReloadResult 一次重新加载的结果
+type ReloadResult struct {
+	Targets         int      `json:"targets"`                    // 已加载的启用转发目标数量
+	Applied         []string `json:"applied"`                    // 已生效的配置项
+	RestartRequired []string `json:"restart_required,omitempty"` // 已修改但需要重启才能生效的配置项
+}
+
+// ReloadService 在不重启进程的情况下重新加载配置和转发目标
+// 新配置校验通过、转发目标加载成功后才会替换，任何一步失败都保持原状态
+type ReloadService struct {
+	emailService   *EmailService
+	gmailService   *GmailService
+	scheduler      *Scheduler
+	mailboxService *MailboxService
+
+	mu  sync.Mutex
+	cfg *config.Config
+}
+
+// NewReloadService 创建热加载服务实例，cfg 为启动时加载的配置
+func NewReloadService(cfg *config.Config, emailService *EmailService, gmailService *GmailService, scheduler *Scheduler, mailboxService *MailboxService) *ReloadService {
+	return &ReloadService{
+		emailService:   emailService,
+		gmailService:   gmailService,
+		scheduler:      scheduler,
+		mailboxService: mailboxService,
+		cfg:            cfg,
+	}
+}
+
+// Reload 重新读取配置文件和环境变量并重新加载转发目标
+// 日志级别、检查间隔、全局关键字、拉取参数、重试策略会立即生效（也适用于数据库中未单独设置的邮箱）；
+// 其余配置项的修改需要重启，会在结果中列出
+func (rs *ReloadService) Reload(ctx context.Context) (*ReloadResult, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	logger := utils.LoggerFromContext(ctx)
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfigFile, err)
+	}
+
+	generation := forwardTargets.snapshot()
+	targets, err := loadActiveTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以下只做替换，不会再失败
+	forwardTargets.set(targets, generation)
+
+	old := rs.cfg
+	result := &ReloadResult{
+		Targets: len(targets),
+		Applied: []string{},
+	}
+
+	if cfg.Log.Level != old.Log.Level {
+		if err := utils.SetLogLevel(cfg.Log.Level); err == nil {
+			result.Applied = append(result.Applied, "log.level")
+		}
+	}
+	if cfg.App.CheckInterval != old.App.CheckInterval {
+		rs.scheduler.SetInterval(cfg.App.CheckInterval)
+		result.Applied = append(result.Applied, "app.check_interval")
+	}
+	if !reflect.DeepEqual(cfg.App.Keywords, old.App.Keywords) {
+		rs.emailService.SetKeywords(cfg.App.Keywords)
+		result.Applied = append(result.Applied, "app.keywords")
+	}
+	if cfg.App.MaxAttempts != old.App.MaxAttempts || cfg.App.RetryBackoff != old.App.RetryBackoff {
+		rs.emailService.SetRetryPolicy(cfg.App.MaxAttempts, cfg.App.RetryBackoff)
+		if cfg.App.MaxAttempts != old.App.MaxAttempts {
+			result.Applied = append(result.Applied, "app.max_attempts")
+		}
+		if cfg.App.RetryBackoff != old.App.RetryBackoff {
+			result.Applied = append(result.Applied, "app.retry_backoff")
+		}
+	}
+	appliedBefore := len(result.Applied)
+	if cfg.Gmail.Query != old.Gmail.Query {
+		result.Applied = append(result.Applied, "gmail.query")
+	}
+	if cfg.App.MaxEmailsPerBatch != old.App.MaxEmailsPerBatch {
+		result.Applied = append(result.Applied, "app.max_emails_per_batch")
+	}
+	if cfg.App.MaxBatches != old.App.MaxBatches {
+		result.Applied = append(result.Applied, "app.max_batches")
+	}
+	if len(result.Applied) > appliedBefore {
+		rs.gmailService.SetFetchOptions(cfg.Gmail.Query, cfg.App.MaxEmailsPerBatch, cfg.App.MaxBatches)
+	}
+	// 数据库中的邮箱未单独设置的检查间隔和拉取参数跟随全局配置
+	rs.mailboxService.ApplyDefaults(cfg)
+
+	result.RestartRequired = restartRequired(old, cfg)
+	rs.cfg = cfg
+
+	logger.WithField("actor", actorFromContext(ctx)).Infof("配置已重新加载，启用的转发目标 %d 个，生效的配置项: %v", result.Targets, result.Applied)
+	if len(result.RestartRequired) > 0 {
+		logger.Warnf("以下配置项已修改，需要重启才能生效: %v", result.RestartRequired)
+	}
+
+	return result, nil
+}
+
+// restartRequired 列出已修改但不支持热加载的配置项
+func restartRequired(old, cfg *config.Config) []string {
+	var changed []string
+	check := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, name)
+		}
+	}
+
+	check("log.format", old.Log.Format, cfg.Log.Format)
+	check("log.output", old.Log.Output, cfg.Log.Output)
+	check("tracing", old.Tracing, cfg.Tracing)
+	check("database", old.Database, cfg.Database)
+	check("gmail.auth_mode", old.Gmail.AuthMode, cfg.Gmail.AuthMode)
+	check("gmail.service_account_file", old.Gmail.ServiceAccountFile, cfg.Gmail.ServiceAccountFile)
+	check("gmail.credentials_file", old.Gmail.CredentialsFile, cfg.Gmail.CredentialsFile)
+	check("gmail.token_file", old.Gmail.TokenFile, cfg.Gmail.TokenFile)
+	check("gmail.user_email", old.Gmail.UserEmail, cfg.Gmail.UserEmail)
+	check("gmail.redirect_url", old.Gmail.RedirectURL, cfg.Gmail.RedirectURL)
+	check("server", old.Server, cfg.Server)
+	check("auth", old.Auth, cfg.Auth)
+	check("proxy", old.Proxy, cfg.Proxy)
+
+	return changed
+}
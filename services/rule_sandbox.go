@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// RuleTestResult 用邮件标题测试转发规则的结果
+type RuleTestResult struct {
+	Subject    string                `json:"subject"`
+	MailboxID  uint                  `json:"mailbox_id"`
+	Keyword    string                `json:"keyword"`
+	TargetName string                `json:"target_name"`
+	Matched    bool                  `json:"matched"`
+	Reason     string                `json:"reason,omitempty"` // 未匹配的原因，与邮件日志中的错误信息一致
+	Target     *models.ForwardTarget `json:"target,omitempty"`
+}
+
+// TestRule 按处理邮件时相同的规则解析标题并查找转发目标，不发送邮件也不写入日志
+// mailboxID为 models.DefaultMailboxID 时只匹配适用于所有邮箱的目标
+func (es *EmailService) TestRule(ctx context.Context, mailboxID uint, subject string) (*RuleTestResult, error) {
+	mb := mailbox{id: mailboxID, tenantID: tenantFromContext(ctx)}
+	if mailboxID != models.DefaultMailboxID {
+		var record models.Mailbox
+		if err := database.GetDB().WithContext(ctx).Scopes(tenantScope(ctx)).First(&record, mailboxID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: %d", ErrMailboxNotFound, mailboxID)
+			}
+			return nil, err
+		}
+		mb.tenantID = record.TenantID
+	}
+
+	result := &RuleTestResult{Subject: subject, MailboxID: mailboxID}
+	result.Keyword, result.TargetName = es.parseEmailSubject(subject)
+
+	switch {
+	case result.Keyword == "" || result.TargetName == "":
+		result.Reason = "邮件标题不符合转发规则"
+		return result, nil
+	case !es.allowKeyword(result.Keyword):
+		result.Reason = fmt.Sprintf("关键字 %s 不在全局关键字列表中", result.Keyword)
+		return result, nil
+	}
+
+	target, err := es.findForwardTarget(ctx, mb, result.Keyword, result.TargetName)
+	if err != nil {
+		result.Reason = fmt.Sprintf("查找转发目标失败: %v", err)
+		return result, nil
+	}
+	result.Matched = true
+	result.Target = target
+	return result, nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/utils"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// schedulerStaleRuns 超过多少个检查间隔没有成功运行即视为停滞
+const schedulerStaleRuns = 3
+
+// Scheduler 定时检查邮件的调度器，每个邮箱一个
+type Scheduler struct {
+	mailbox string                          // 邮箱地址，用于日志和指标
+	process func(ctx context.Context) error // 检查一次邮箱
+	done    chan struct{}
+	reset   chan struct{} // 检查间隔变更的通知
+
+	mu          sync.Mutex
+	interval    time.Duration
+	paused      bool // 暂停后到点不再检查，重启服务后恢复
+	startedAt   time.Time
+	lastSuccess time.Time
+	lastSkipped time.Time // 最近一次因Gmail未授权跳过检查的时间
+	lastError   error
+}
+
+// SchedulerStatus 调度器运行状态
+type SchedulerStatus struct {
+	Running     bool       `json:"running"`
+	Interval    string     `json:"interval"`
+	Paused      bool       `json:"paused"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	WaitingAuth bool       `json:"waiting_auth"` // 最近一次检查因Gmail未授权而跳过
+	Stale       bool       `json:"stale"`
+}
+
+// NewScheduler 创建默认邮箱的调度器实例
+func NewScheduler(emailService *EmailService, interval time.Duration) *Scheduler {
+	return newScheduler(emailService.gmailService.UserEmail(), emailService.ProcessEmails, interval)
+}
+
+// newScheduler 创建调度器实例，每个检查间隔调用一次process
+func newScheduler(mailbox string, process func(ctx context.Context) error, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		mailbox:  mailbox,
+		process:  process,
+		interval: interval,
+		done:     make(chan struct{}),
+		reset:    make(chan struct{}, 1),
+	}
+}
+
+// Run 运行定时任务，直到ctx被取消
+func (s *Scheduler) Run(ctx context.Context) {
+	defer close(s.done)
+
+	ctx = utils.ContextWithLogFields(ctx, logrus.Fields{"mailbox": s.mailbox})
+	logger := utils.LoggerFromContext(ctx)
+
+	s.mu.Lock()
+	s.startedAt = time.Now()
+	interval := s.interval
+	s.mu.Unlock()
+	logger.Infof("定时任务已启动，检查间隔: %v", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("定时任务已停止")
+			return
+		case <-s.reset:
+			interval = s.Interval()
+			ticker.Reset(interval)
+			logger.Infof("检查间隔已调整为: %v", interval)
+		case <-ticker.C:
+			if s.Paused() {
+				logger.Debug("定时任务已暂停，跳过本次检查")
+				continue
+			}
+			logger.Info("开始定时检查邮件...")
+
+			err := s.process(ctx)
+			if errors.Is(err, ErrGmailNotAuthorized) {
+				logger.Warnf("跳过本次检查: %v", err)
+				schedulerRuns.WithLabelValues(s.mailbox, "failed").Inc()
+			} else if err != nil {
+				logger.Errorf("定时处理邮件失败: %v", err)
+				schedulerRuns.WithLabelValues(s.mailbox, "failed").Inc()
+			} else {
+				logger.Info("定时邮件检查完成")
+				schedulerRuns.WithLabelValues(s.mailbox, "success").Inc()
+				schedulerLastSuccess.WithLabelValues(s.mailbox).SetToCurrentTime()
+			}
+			s.recordRun(err)
+		}
+	}
+}
+
+// Interval 返回当前的检查间隔
+func (s *Scheduler) Interval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.interval
+}
+
+// SetInterval 调整检查间隔，正在进行的检查不受影响，下一次检查按新间隔计时
+func (s *Scheduler) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	s.interval = interval
+	s.mu.Unlock()
+
+	select {
+	case s.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Paused 返回定时任务是否已暂停
+func (s *Scheduler) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.paused
+}
+
+// SetPaused 暂停或恢复定时任务，暂停期间仍可以手动触发检查
+func (s *Scheduler) SetPaused(paused bool) {
+	s.mu.Lock()
+	s.paused = paused
+	s.mu.Unlock()
+
+	logger := utils.GetLogger().WithField("mailbox", s.mailbox)
+	if paused {
+		logger.Info("定时任务已暂停")
+	} else {
+		logger.Info("定时任务已恢复")
+	}
+}
+
+// Done 返回在定时任务退出后关闭的通道
+func (s *Scheduler) Done() <-chan struct{} {
+	return s.done
+}
+
+// recordRun 记录一次运行的结果，因Gmail未授权跳过的检查不计入停滞时间
+func (s *Scheduler) recordRun(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastError = err
+	switch {
+	case err == nil:
+		s.lastSuccess = time.Now()
+	case errors.Is(err, ErrGmailNotAuthorized):
+		s.lastSkipped = time.Now()
+	}
+}
+
+// Status 返回调度器状态，超过若干个检查间隔没有成功运行时标记为停滞
+func (s *Scheduler) Status() SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := SchedulerStatus{
+		Running:  !s.startedAt.IsZero(),
+		Interval: s.interval.String(),
+		Paused:   s.paused,
+	}
+	select {
+	case <-s.done:
+		status.Running = false
+	default:
+	}
+
+	if s.lastError != nil {
+		status.LastError = s.lastError.Error()
+		status.WaitingAuth = errors.Is(s.lastError, ErrGmailNotAuthorized)
+	}
+
+	// 还没有成功过时，从启动时间开始计算；等待授权期间跳过的检查也视为按时运行，
+	// 否则降级启动的服务会在完成授权前被判定为停滞
+	reference := s.startedAt
+	if !s.lastSuccess.IsZero() {
+		lastSuccess := s.lastSuccess
+		status.LastSuccess = &lastSuccess
+		reference = lastSuccess
+	}
+	if s.lastSkipped.After(reference) {
+		reference = s.lastSkipped
+	}
+	// 暂停期间不检查，不视为停滞
+	status.Stale = !status.Running || (!s.paused && time.Since(reference) > schedulerStaleRuns*s.interval)
+
+	return status
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSchedulerStaleness(t *testing.T) {
+	notAuthorized := fmt.Errorf("获取未读邮件失败: %w", ErrGmailNotAuthorized)
+	tests := []struct {
+		name            string
+		runs            []error
+		wantStale       bool
+		wantWaitingAuth bool
+	}{
+		{"没有运行过", nil, true, false},
+		{"运行失败", []error{errors.New("connection refused")}, true, false},
+		{"运行成功", []error{nil}, false, false},
+		{"等待授权", []error{notAuthorized, notAuthorized}, false, true},
+		{"授权后首次失败", []error{notAuthorized, errors.New("connection refused")}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler("me@example.com", nil, time.Minute)
+			// 启动时间早于停滞阈值，只有最近的运行记录能让调度器保持正常
+			s.startedAt = time.Now().Add(-10 * time.Minute)
+			for _, err := range tt.runs {
+				s.recordRun(err)
+			}
+
+			status := s.Status()
+			if status.Stale != tt.wantStale || status.WaitingAuth != tt.wantWaitingAuth {
+				t.Errorf("stale = %v, waiting_auth = %v, 期望 %v, %v", status.Stale, status.WaitingAuth, tt.wantStale, tt.wantWaitingAuth)
+			}
+		})
+	}
+}
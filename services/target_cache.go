@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// targetCacheTTL 转发目标缓存的有效期，过期后重新加载，用于感知其他实例对数据库的修改
+const targetCacheTTL = time.Minute
+
+// targetCache 启用的转发目标的内存缓存，匹配转发目标时不再逐封查询数据库
+// 缓存的切片只会被整体替换，不会被修改，读取方可以直接使用
+type targetCache struct {
+	mu       sync.RWMutex
+	targets  []models.ForwardTarget
+	loadedAt time.Time
+	// generation 每次失效时递增，加载期间发生过失效的结果不写入缓存
+	generation uint64
+}
+
+// forwardTargets 全局的转发目标缓存，本进程修改转发目标后会立即失效
+var forwardTargets = &targetCache{}
+
+// get 返回缓存的转发目标，缓存失效时从数据库重新加载
+func (c *targetCache) get(ctx context.Context) ([]models.ForwardTarget, error) {
+	c.mu.RLock()
+	targets, loadedAt, generation := c.targets, c.loadedAt, c.generation
+	c.mu.RUnlock()
+
+	if !loadedAt.IsZero() && time.Since(loadedAt) < targetCacheTTL {
+		return targets, nil
+	}
+
+	targets, err := loadActiveTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.set(targets, generation)
+	return targets, nil
+}
+
+// set 替换缓存内容，generation 与当前不一致说明加载期间缓存已失效，丢弃本次结果
+func (c *targetCache) set(targets []models.ForwardTarget, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.targets = targets
+	c.loadedAt = time.Now()
+}
+
+// snapshot 返回当前的generation，配合 set 使用
+func (c *targetCache) snapshot() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.generation
+}
+
+// invalidate 使缓存失效，下次读取时重新加载
+func (c *targetCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loadedAt = time.Time{}
+	c.generation++
+}
+
+// loadActiveTargets 从数据库加载所有启用的转发目标
+func loadActiveTargets(ctx context.Context) ([]models.ForwardTarget, error) {
+	var targets []models.ForwardTarget
+	if err := database.GetDB().WithContext(ctx).Where("is_active = ?", true).Order("id").Find(&targets).Error; err != nil {
+		return nil, fmt.Errorf("加载转发目标失败: %v", err)
+	}
+	return targets, nil
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/database"
+	"email-forwarding/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTenantNotFound 租户不存在
+	ErrTenantNotFound = newError(KindNotFound, "tenant_not_found", "租户不存在", "tenant not found")
+	// ErrTenantInactive 租户已停用
+	ErrTenantInactive = newError(KindForbidden, "tenant_inactive", "租户已停用", "tenant is inactive")
+	// ErrInvalidTenant 租户参数无效
+	ErrInvalidTenant = newError(KindValidation, "invalid_tenant", "租户参数无效", "invalid tenant")
+	// ErrQuotaExceeded 超出租户配额
+	ErrQuotaExceeded = newError(KindQuotaExceeded, "quota_exceeded", "超出租户配额", "tenant quota exceeded")
+)
+
+// tenantFromContext 获取调用方所属的租户，没有调用方或未指定租户时（定时任务、命令行）为默认租户
+func tenantFromContext(ctx context.Context) uint {
+	if principal := PrincipalFromContext(ctx); principal != nil && principal.TenantID != 0 {
+		return principal.TenantID
+	}
+	return models.DefaultTenantID
+}
+
+// tenantScope 按调用方所属租户过滤查询，未指定租户的超级管理员可以看到所有租户的数据
+func tenantScope(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if principal := PrincipalFromContext(ctx); principal != nil && principal.AllTenants {
+			return db
+		}
+		return db.Where("tenant_id = ?", tenantFromContext(ctx))
+	}
+}
+
+// tenantAllowed 判断调用方能否访问属于tenantID的数据
+func tenantAllowed(ctx context.Context, tenantID uint) bool {
+	if principal := PrincipalFromContext(ctx); principal != nil && principal.AllTenants {
+		return true
+	}
+	return tenantFromContext(ctx) == tenantID
+}
+
+// isSuperAdmin 判断调用方是否为超级管理员，没有调用方时（命令行、定时任务）视为超级管理员
+func isSuperAdmin(ctx context.Context) bool {
+	principal := PrincipalFromContext(ctx)
+	return principal == nil || principal.Role == "" || principal.Role == models.RoleSuperAdmin
+}
+
+// loadTenant 在tx中读取租户
+func loadTenant(tx *gorm.DB, id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := tx.First(&tenant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrTenantNotFound, id)
+		}
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// checkQuota 检查租户在model表中的记录数是否已达到limit，limit为0表示不限制
+func checkQuota(tx *gorm.DB, tenantID uint, model interface{}, limit int, what string) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(model).Where("tenant_id = ?", tenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return fmt.Errorf("%w: 租户 %d 最多可创建 %d 个%s", ErrQuotaExceeded, tenantID, limit, what)
+	}
+	return nil
+}
+
+// checkTargetQuota 检查租户的转发目标数量配额
+func checkTargetQuota(tx *gorm.DB, tenantID uint) error {
+	tenant, err := loadTenant(tx, tenantID)
+	if err != nil {
+		return err
+	}
+	return checkQuota(tx, tenantID, &models.ForwardTarget{}, tenant.MaxTargets, "转发目标")
+}
+
+// checkMailboxQuota 检查租户的邮箱数量配额
+func checkMailboxQuota(tx *gorm.DB, tenantID uint) error {
+	tenant, err := loadTenant(tx, tenantID)
+	if err != nil {
+		return err
+	}
+	return checkQuota(tx, tenantID, &models.Mailbox{}, tenant.MaxMailboxes, "邮箱")
+}
+
+// checkDailyForwardQuota 检查租户今天成功转发的邮件数量是否已达到每日配额
+func checkDailyForwardQuota(tx *gorm.DB, tenant *models.Tenant) error {
+	if tenant.MaxDailyForwards <= 0 {
+		return nil
+	}
+
+	var count int64
+	err := tx.Model(&models.EmailLog{}).
+		Where("tenant_id = ? AND forward_status = ? AND processed_at >= ?", tenant.ID, models.StatusSuccess, startOfDay(time.Now())).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count >= int64(tenant.MaxDailyForwards) {
+		return fmt.Errorf("%w: 租户 %d 今天已转发 %d 封邮件", ErrQuotaExceeded, tenant.ID, count)
+	}
+	return nil
+}
+
+// startOfDay 返回t所在日期的零点
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// TenantPatch 租户的部分更新，只有非nil的字段会被写入
+type TenantPatch struct {
+	Name             *string `json:"name"`
+	MaxMailboxes     *int    `json:"max_mailboxes"`
+	MaxTargets       *int    `json:"max_targets"`
+	MaxDailyForwards *int    `json:"max_daily_forwards"`
+	IsActive         *bool   `json:"is_active"`
+}
+
+// TenantStats 单个租户的用量统计
+type TenantStats struct {
+	models.Tenant
+	Mailboxes      int64 `json:"mailboxes"`
+	Targets        int64 `json:"targets"`
+	APIKeys        int64 `json:"api_keys"`
+	TotalEmails    int64 `json:"total_emails"`
+	SuccessEmails  int64 `json:"success_emails"`
+	FailedEmails   int64 `json:"failed_emails"`
+	ForwardedToday int64 `json:"forwarded_today"`
+}
+
+// TenantService 租户管理服务，只供超级管理员使用
+type TenantService struct{}
+
+// NewTenantService 创建租户服务实例
+func NewTenantService() *TenantService {
+	return &TenantService{}
+}
+
+// GetTenants 获取租户列表
+func (ts *TenantService) GetTenants() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+	if err := database.GetDB().Order("id").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// GetTenant 获取单个租户
+func (ts *TenantService) GetTenant(id uint) (*models.Tenant, error) {
+	return loadTenant(database.GetDB(), id)
+}
+
+// CreateTenant 创建租户
+func (ts *TenantService) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
+	if err := normalizeTenant(tenant); err != nil {
+		return err
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, tenant.ID, models.AuditActionCreate, models.EntityTenant, tenant.ID, nil, tenant)
+	})
+}
+
+// PatchTenant 按字段掩码更新租户，默认租户不能停用
+func (ts *TenantService) PatchTenant(ctx context.Context, id uint, patch TenantPatch) (*models.Tenant, error) {
+	var after models.Tenant
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		before, err := loadTenant(tx, id)
+		if err != nil {
+			return err
+		}
+
+		after = *before
+		var columns []string
+		if patch.Name != nil {
+			after.Name = *patch.Name
+			columns = append(columns, "name")
+		}
+		for column, field := range map[string]struct {
+			dst   *int
+			value *int
+		}{
+			"max_mailboxes":      {&after.MaxMailboxes, patch.MaxMailboxes},
+			"max_targets":        {&after.MaxTargets, patch.MaxTargets},
+			"max_daily_forwards": {&after.MaxDailyForwards, patch.MaxDailyForwards},
+		} {
+			if field.value != nil {
+				*field.dst = *field.value
+				columns = append(columns, column)
+			}
+		}
+		if patch.IsActive != nil {
+			after.IsActive = *patch.IsActive
+			columns = append(columns, "is_active")
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+
+		if err := normalizeTenant(&after); err != nil {
+			return err
+		}
+		if after.ID == models.DefaultTenantID && !after.IsActive {
+			return fmt.Errorf("%w: 默认租户不能停用", ErrInvalidTenant)
+		}
+
+		// 用Select指定字段，确保false、0等零值也能写入
+		columns = append(columns, "updated_at")
+		if err := tx.Model(before).Select(columns).Updates(&after).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&after, id).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, id, models.AuditActionUpdate, models.EntityTenant, id, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &after, nil
+}
+
+// GetTenantStats 统计所有租户的用量
+func (ts *TenantService) GetTenantStats() ([]TenantStats, error) {
+	db := database.GetDB()
+
+	tenants, err := ts.GetTenants()
+	if err != nil {
+		return nil, err
+	}
+
+	type row struct {
+		TenantID uint
+		Count    int64
+	}
+	countBy := func(query *gorm.DB) (map[uint]int64, error) {
+		var rows []row
+		if err := query.Select("tenant_id, COUNT(*) AS count").Group("tenant_id").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		counts := make(map[uint]int64, len(rows))
+		for _, r := range rows {
+			counts[r.TenantID] = r.Count
+		}
+		return counts, nil
+	}
+
+	var mailboxes, targets, apiKeys, total, success, failed, today map[uint]int64
+	for _, q := range []struct {
+		dst   *map[uint]int64
+		query *gorm.DB
+	}{
+		{&mailboxes, db.Model(&models.Mailbox{})},
+		{&targets, db.Model(&models.ForwardTarget{})},
+		{&apiKeys, db.Model(&models.APIKey{})},
+		{&total, db.Model(&models.EmailLog{})},
+		{&success, db.Model(&models.EmailLog{}).Where("forward_status = ?", models.StatusSuccess)},
+		{&failed, db.Model(&models.EmailLog{}).Where("forward_status = ?", models.StatusFailed)},
+		{&today, db.Model(&models.EmailLog{}).Where("forward_status = ? AND processed_at >= ?", models.StatusSuccess, startOfDay(time.Now()))},
+	} {
+		counts, err := countBy(q.query)
+		if err != nil {
+			return nil, err
+		}
+		*q.dst = counts
+	}
+
+	stats := make([]TenantStats, 0, len(tenants))
+	for _, tenant := range tenants {
+		stats = append(stats, TenantStats{
+			Tenant:         tenant,
+			Mailboxes:      mailboxes[tenant.ID],
+			Targets:        targets[tenant.ID],
+			APIKeys:        apiKeys[tenant.ID],
+			TotalEmails:    total[tenant.ID],
+			SuccessEmails:  success[tenant.ID],
+			FailedEmails:   failed[tenant.ID],
+			ForwardedToday: today[tenant.ID],
+		})
+	}
+	return stats, nil
+}
+
+// normalizeTenant 校验租户的字段
+func normalizeTenant(tenant *models.Tenant) error {
+	tenant.Name = strings.TrimSpace(tenant.Name)
+	if tenant.Name == "" {
+		return fmt.Errorf("%w: 名称不能为空", ErrInvalidTenant)
+	}
+	if tenant.MaxMailboxes < 0 || tenant.MaxTargets < 0 || tenant.MaxDailyForwards < 0 {
+		return fmt.Errorf("%w: 配额不能为负数", ErrInvalidTenant)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/models"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const (
+	tenantA uint = 2
+	tenantB uint = 3
+)
+
+// tenantAdmin 租户管理员的调用上下文
+func tenantAdmin(tenantID uint) context.Context {
+	return ContextWithPrincipal(context.Background(), &Principal{Name: "admin", Role: models.RoleAdmin, TenantID: tenantID})
+}
+
+func TestCreateTenantInactive(t *testing.T) {
+	mock := useMockDB(t)
+	inserts := captureInserts(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tenants`").WillReturnResult(sqlmock.NewResult(int64(tenantB), 1))
+	mock.ExpectExec("INSERT INTO `audit_logs`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tenant := &models.Tenant{Name: "acme", IsActive: false}
+	if err := NewTenantService().CreateTenant(context.Background(), tenant); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := inserts["tenants"]["is_active"]; !ok || got != false {
+		t.Errorf("写入的 is_active = %v（列存在: %v），期望 false", got, ok)
+	}
+}
+
+func TestTenantAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		tenantID uint
+		want     bool
+	}{
+		{"本租户", tenantAdmin(tenantA), tenantA, true},
+		{"其他租户", tenantAdmin(tenantA), tenantB, false},
+		{"未指定租户按默认租户", tenantAdmin(0), models.DefaultTenantID, true},
+		{"未指定租户不能访问其他租户", tenantAdmin(0), tenantB, false},
+		{"没有调用方按默认租户", context.Background(), tenantB, false},
+		{"超级管理员未指定租户", ContextWithPrincipal(context.Background(), &Principal{Role: models.RoleSuperAdmin, AllTenants: true}), tenantB, true},
+	}
+	for _, tt := range tests {
+		if got := tenantAllowed(tt.ctx, tt.tenantID); got != tt.want {
+			t.Errorf("%s: tenantAllowed = %v, 期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestTenantIsolation 租户A按ID访问租户B的数据时，查询按租户A过滤，结果为不存在
+// sqlmock按参数匹配，只有带上 tenant_id = 租户A 的查询才会命中预期
+func TestTenantIsolation(t *testing.T) {
+	const id = 7 // 属于租户B的记录
+	es := NewEmailService(nil, nil)
+	ms := newTestMailboxService()
+	name := "renamed"
+
+	tests := []struct {
+		name  string
+		table string
+		tx    bool
+		call  func(ctx context.Context) error
+		want  error
+	}{
+		{"读取转发目标", "forward_targets", false, func(ctx context.Context) error {
+			_, err := es.GetForwardTarget(ctx, id)
+			return err
+		}, ErrTargetNotFound},
+		{"修改转发目标", "forward_targets", true, func(ctx context.Context) error {
+			_, err := es.PatchForwardTarget(ctx, id, ForwardTargetPatch{Name: &name})
+			return err
+		}, ErrTargetNotFound},
+		{"删除转发目标", "forward_targets", true, func(ctx context.Context) error {
+			return es.DeleteForwardTarget(ctx, id)
+		}, ErrTargetNotFound},
+		{"读取邮件日志", "email_logs", false, func(ctx context.Context) error {
+			_, err := es.GetEmailLog(ctx, id)
+			return err
+		}, ErrEmailLogNotFound},
+		{"读取邮箱", "mailboxes", false, func(ctx context.Context) error {
+			_, err := ms.GetMailbox(ctx, id)
+			return err
+		}, ErrMailboxNotFound},
+		{"修改邮箱", "mailboxes", true, func(ctx context.Context) error {
+			_, err := ms.PatchMailbox(ctx, id, MailboxPatch{Name: &name})
+			return err
+		}, ErrMailboxNotFound},
+		{"删除邮箱", "mailboxes", true, func(ctx context.Context) error {
+			return ms.DeleteMailbox(ctx, id)
+		}, ErrMailboxNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := useMockDB(t)
+			if tt.tx {
+				mock.ExpectBegin()
+			}
+			mock.ExpectQuery("SELECT \\* FROM `"+tt.table+"` WHERE `"+tt.table+"`.`id` = \\? AND tenant_id = \\?").
+				WithArgs(id, tenantA).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			if tt.tx {
+				mock.ExpectRollback()
+			}
+
+			if err := tt.call(tenantAdmin(tenantA)); !errors.Is(err, tt.want) {
+				t.Errorf("错误 = %v, 期望 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantIsolationEmailLogs(t *testing.T) {
+	mock := useMockDB(t)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `email_logs` WHERE tenant_id = \\?").
+		WithArgs(tenantA).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT \\* FROM `email_logs` WHERE tenant_id = \\?").
+		WithArgs(tenantA).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, _, err := NewEmailService(nil, nil).GetEmailLogs(tenantAdmin(tenantA), EmailLogFilter{}, 1, 20); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfineTenantPath(t *testing.T) {
+	dir := tenantFilesDir(tenantA)
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"token.json", filepath.Join(dir, "token.json"), false},
+		{"keys/sa.json", filepath.Join(dir, "keys", "sa.json"), false},
+		{filepath.Join(dir, "token.json"), filepath.Join(dir, "token.json"), false},
+		{"./token.json", filepath.Join(dir, "token.json"), false},
+		// 其他租户的目录被当作本租户目录下的相对路径
+		{filepath.Join(tenantFilesDir(tenantB), "token.json"), filepath.Join(dir, tenantFilesDir(tenantB), "token.json"), false},
+		{"/etc/google/credentials.json", "", true},
+		{"../credentials.json", "", true},
+		{"keys/../../credentials.json", "", true},
+		{dir + "/../3/token.json", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := confineTenantPath(tenantA, tt.path)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("confineTenantPath(%q) = %q, %v, 期望 %q（出错: %v）", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckMailboxFiles(t *testing.T) {
+	ctx := tenantAdmin(tenantA)
+
+	mb := &models.Mailbox{TenantID: tenantA, AuthMode: GmailAuthOAuth, CredentialsFile: "credentials.json", TokenFile: "/tmp/token.json"}
+	if err := checkMailboxFiles(ctx, nil, mb); !errors.Is(err, ErrInvalidMailbox) {
+		t.Errorf("绝对路径的token文件: %v", err)
+	}
+
+	mb = &models.Mailbox{TenantID: tenantA, AuthMode: GmailAuthOAuth, CredentialsFile: "credentials.json"}
+	if err := checkMailboxFiles(ctx, nil, mb); err != nil || mb.CredentialsFile != filepath.Join(tenantFilesDir(tenantA), "credentials.json") {
+		t.Errorf("credentials_file = %q, %v", mb.CredentialsFile, err)
+	}
+
+	mb = &models.Mailbox{TenantID: tenantA, AuthMode: GmailAuthServiceAccount, Address: "ceo@example.com", IsActive: true}
+	if err := checkMailboxFiles(ctx, nil, mb); !errors.Is(err, ErrForbidden) {
+		t.Errorf("租户管理员使用全局服务账号密钥: %v", err)
+	}
+
+	// 超级管理员不受限制
+	mb = &models.Mailbox{TenantID: tenantA, AuthMode: GmailAuthServiceAccount, TokenFile: "/var/lib/tokens/a.json", IsActive: true}
+	if err := checkMailboxFiles(context.Background(), nil, mb); err != nil || mb.TokenFile != "/var/lib/tokens/a.json" {
+		t.Errorf("超级管理员: %q, %v", mb.TokenFile, err)
+	}
+}
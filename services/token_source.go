@@ -0,0 +1,112 @@
+package services
+
+import (
+	"email-forwarding/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// token刷新结果标签
+const (
+	refreshOK      = "ok"
+	refreshError   = "error"
+	refreshRevoked = "revoked"
+)
+
+// persistingTokenSource 包装oauth2的TokenSource，access token刷新后写回token文件
+// refresh token被撤销或过期时调用 onRevoked，由调用方切换到需要授权的状态
+type persistingTokenSource struct {
+	source    oauth2.TokenSource
+	path      string
+	onRevoked func(error)
+
+	mu   sync.Mutex
+	last string // 最近一次写入文件的access token
+}
+
+// newPersistingTokenSource 创建会持久化刷新结果的TokenSource，tok 为已保存在文件中的token
+func newPersistingTokenSource(source oauth2.TokenSource, path string, tok *oauth2.Token, onRevoked func(error)) *persistingTokenSource {
+	return &persistingTokenSource{
+		source:    source,
+		path:      path,
+		onRevoked: onRevoked,
+		last:      tok.AccessToken,
+	}
+}
+
+// Token 返回有效的token，发生刷新时把新token原子写入文件
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.source.Token()
+	if err != nil {
+		if isTokenRevoked(err) {
+			tokenRefreshes.WithLabelValues(refreshRevoked).Inc()
+			s.onRevoked(err)
+		} else {
+			tokenRefreshes.WithLabelValues(refreshError).Inc()
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tok.AccessToken != s.last {
+		tokenRefreshes.WithLabelValues(refreshOK).Inc()
+		// 写入失败不影响本次请求，下次刷新时会再次尝试
+		if err := saveToken(s.path, tok); err != nil {
+			utils.GetLogger().Errorf("保存刷新后的token失败: %v", err)
+		} else {
+			s.last = tok.AccessToken
+		}
+	}
+	return tok, nil
+}
+
+// isTokenRevoked 判断刷新失败是否因为refresh token已被撤销或过期，这种情况只能重新授权
+func isTokenRevoked(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	return errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant"
+}
+
+// saveToken 原子地保存token到文件：先写入同目录下的临时文件，再重命名覆盖
+// 进程在写入过程中退出也不会留下不完整的token文件
+func saveToken(path string, token *oauth2.Token) error {
+	utils.GetLogger().Infof("保存凭证文件到: %s", path)
+
+	// 租户的token文件放在租户目录中，目录可能还不存在
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("无法缓存oauth token: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("无法缓存oauth token: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("无法缓存oauth token: %v", err)
+	}
+	if err := json.NewEncoder(tmp).Encode(token); err != nil {
+		tmp.Close()
+		return fmt.Errorf("无法缓存oauth token: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("无法缓存oauth token: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("无法缓存oauth token: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("无法缓存oauth token: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer 服务层使用的tracer，通过全局TracerProvider获取，测试时可替换为内存导出器
+var tracer = otel.Tracer("email-forwarding/services")
+
+// endSpan 结束span，有错误时记录错误并标记状态
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// startGmailCall 开始一次Gmail API调用的span，返回的结束函数同时记录耗时指标
+func startGmailCall(ctx context.Context, operation string) (context.Context, func(error)) {
+	ctx, span := tracer.Start(ctx, "gmail."+operation, trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+
+	return ctx, func(err error) {
+		observeGmailCall(operation, start, err)
+		endSpan(span, err)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"email-forwarding/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+var (
+	spanExporterOnce sync.Once
+	spanExporter     *tracetest.InMemoryExporter
+)
+
+// useSpanRecorder 返回写入内存的span导出器，并清空之前测试导出的span
+// 全局TracerProvider只在第一次设置时替换包级tracer的实现，所以所有测试共用同一个导出器
+func useSpanRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	spanExporterOnce.Do(func() {
+		spanExporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+	})
+	spanExporter.Reset()
+	return spanExporter
+}
+
+// newFakeGmail 返回使用httptest模拟Gmail API的服务，邮箱中有一封标题为subject的未读邮件
+func newFakeGmail(t *testing.T, messageID, subject string) *GmailService {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/users/me/messages"):
+			resp = &gmail.ListMessagesResponse{Messages: []*gmail.Message{{Id: messageID}}, ResultSizeEstimate: 1}
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/users/me/messages/"+messageID):
+			resp = &gmail.Message{Id: messageID, Payload: &gmail.MessagePart{
+				MimeType: "text/plain",
+				Headers: []*gmail.MessagePartHeader{
+					{Name: "Subject", Value: subject},
+					{Name: "From", Value: "sender@example.com"},
+				},
+				Body: &gmail.MessagePartBody{},
+			}}
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/users/me/messages/"+messageID+"/modify"):
+			resp = &gmail.Message{Id: messageID}
+		default:
+			t.Errorf("未预期的Gmail请求: %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	service, err := gmail.NewService(context.Background(), option.WithEndpoint(srv.URL), option.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := newGmailService(GmailAuthOAuth, "me@example.com", nil)
+	gs.service = service
+	return gs
+}
+
+// spansByName 按名字索引导出的span，同名的span按结束顺序排列
+func spansByName(exporter *tracetest.InMemoryExporter) map[string][]tracetest.SpanStub {
+	spans := make(map[string][]tracetest.SpanStub)
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = append(spans[s.Name], s)
+	}
+	return spans
+}
+
+func TestProcessMailboxSpans(t *testing.T) {
+	exporter := useSpanRecorder(t)
+	mock := useMockDB(t)
+
+	mock.ExpectQuery("SELECT \\* FROM `tenants`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_active"}).AddRow(models.DefaultTenantID, "default", true))
+	mock.ExpectQuery("SELECT \\* FROM `email_logs` WHERE \\(mailbox_id = \\? AND gmail_message_id = \\?\\)").
+		WithArgs(models.DefaultMailboxID, "msg-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `email_logs`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `email_logs` WHERE \\(tenant_id = \\? AND mailbox_id = \\? AND forward_status = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	gs := newFakeGmail(t, "msg-1", "没有转发关键字的邮件")
+	gs.SetFetchOptions("", 10, 1)
+	es := NewEmailService(gs, nil)
+	if err := es.ProcessEmails(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := spansByName(exporter)
+	for name, want := range map[string]int{"email.process_run": 1, "email.process": 1, "gmail.list": 1, "gmail.get": 1, "gmail.modify": 1} {
+		if got := len(spans[name]); got != want {
+			t.Fatalf("%s span数量 = %d, 期望 %d", name, got, want)
+		}
+	}
+
+	run := spans["email.process_run"][0]
+	if run.Parent.IsValid() {
+		t.Errorf("email.process_run 应为根span，父span为 %s", run.Parent.SpanID())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range run.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["run_id"] == "" || attrs["mailbox.id"] != "0" || attrs["tenant.id"] != "1" || attrs["messages.fetched"] != "1" {
+		t.Errorf("email.process_run 属性 = %v", attrs)
+	}
+
+	for _, name := range []string{"gmail.list", "gmail.get", "email.process"} {
+		if got := spans[name][0].Parent.SpanID(); got != run.SpanContext.SpanID() {
+			t.Errorf("%s 的父span = %s, 期望 email.process_run", name, got)
+		}
+	}
+
+	message := spans["email.process"][0]
+	if got := spans["gmail.modify"][0].Parent.SpanID(); got != message.SpanContext.SpanID() {
+		t.Errorf("gmail.modify 的父span = %s, 期望 email.process", got)
+	}
+	for _, kv := range message.Attributes {
+		if kv.Key == "gmail.message_id" && kv.Value.AsString() != "msg-1" {
+			t.Errorf("gmail.message_id = %s", kv.Value.AsString())
+		}
+	}
+
+	for name, list := range spans {
+		s := list[0]
+		if s.SpanContext.TraceID() != run.SpanContext.TraceID() {
+			t.Errorf("%s 不在同一条链路中", name)
+		}
+		if strings.HasPrefix(name, "gmail.") && s.SpanKind != trace.SpanKindClient {
+			t.Errorf("%s 的SpanKind = %s, 期望 client", name, s.SpanKind)
+		}
+	}
+}
+
+func TestGmailCallSpanRecordsError(t *testing.T) {
+	exporter := useSpanRecorder(t)
+
+	gs := newGmailService(GmailAuthOAuth, "me@example.com", nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":500,"message":"backend error"}}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	service, err := gmail.NewService(context.Background(), option.WithEndpoint(srv.URL), option.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs.service = service
+
+	if err := gs.MarkAsRead(context.Background(), "msg-1"); err == nil {
+		t.Fatal("期望返回错误")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "gmail.modify" {
+		t.Fatalf("spans = %v", spans)
+	}
+	if spans[0].Status.Code != codes.Error || len(spans[0].Events) == 0 {
+		t.Errorf("失败的调用应记录错误，status = %v, events = %v", spans[0].Status, spans[0].Events)
+	}
+}
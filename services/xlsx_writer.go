@@ -0,0 +1,192 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxMaxCellLength Excel单元格最多容纳的字符数
+const xlsxMaxCellLength = 32767
+
+// xlsx工作簿中除工作表以外的固定部分
+const (
+	xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+	xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	// 样式：0 默认，1 粗体（表头），2 日期时间
+	xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="3">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`</cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`
+)
+
+const (
+	xlsxStyleHeader = 1
+	xlsxStyleTime   = 2
+)
+
+// xlsxEpoch Excel日期序列号的起点
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxWriter 逐行写出只有一个工作表的xlsx工作簿
+// 固定部分先写入zip，工作表最后写入并直接流式输出，字符串使用内联字符串，不需要缓存共享字符串表
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+	err   error
+}
+
+// newXLSXWriter 写出工作簿的固定部分和表头，sheetName 为工作表名称
+func newXLSXWriter(w io.Writer, sheetName string, headers []string) (*xlsxWriter, error) {
+	xw := &xlsxWriter{zw: zip.NewWriter(w)}
+
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"` +
+		` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + xmlEscape(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		f, err := xw.zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := xw.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw.sheet = sheet
+
+	// 冻结表头，所有列使用相同的列宽
+	xw.write(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(headers) > 0 {
+		xw.write(`<cols><col min="1" max="` + strconv.Itoa(len(headers)) + `" width="20" customWidth="1"/></cols>`)
+	}
+	xw.write(`<sheetData>`)
+
+	values := make([]interface{}, len(headers))
+	for i, header := range headers {
+		values[i] = header
+	}
+	if err := xw.writeStyledRow(values, xlsxStyleHeader); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+func (xw *xlsxWriter) writeRow(values []interface{}) error {
+	return xw.writeStyledRow(values, 0)
+}
+
+// writeStyledRow 写出一行，style 为0时按值的类型选择样式
+// 支持 string、int64、time.Time、*time.Time，nil 和零值时间写为空单元格
+func (xw *xlsxWriter) writeStyledRow(values []interface{}, style int) error {
+	xw.rows++
+	row := strconv.Itoa(xw.rows)
+	xw.write(`<row r="` + row + `">`)
+	for i, v := range values {
+		ref := xlsxColumn(i) + row
+		switch v := v.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+			xw.write(`<c r="` + ref + `"` + xlsxStyleAttr(style) + ` t="inlineStr"><is><t xml:space="preserve">` +
+				xmlEscape(truncateRunes(v, xlsxMaxCellLength)) + `</t></is></c>`)
+		case int64:
+			xw.write(`<c r="` + ref + `"` + xlsxStyleAttr(style) + `><v>` + strconv.FormatInt(v, 10) + `</v></c>`)
+		case *time.Time:
+			if v != nil {
+				xw.writeTime(ref, *v)
+			}
+		case time.Time:
+			xw.writeTime(ref, v)
+		}
+	}
+	xw.write(`</row>`)
+	return xw.err
+}
+
+// writeTime 以Excel日期序列号写出服务器本地时间
+func (xw *xlsxWriter) writeTime(ref string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	local := t.Local()
+	wall := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+	serial := wall.Sub(xlsxEpoch).Seconds() / 86400
+	xw.write(`<c r="` + ref + `"` + xlsxStyleAttr(xlsxStyleTime) + `><v>` + strconv.FormatFloat(serial, 'f', -1, 64) + `</v></c>`)
+}
+
+// write 写出工作表内容，出错后忽略后续写入，由 writeRow 和 close 返回第一个错误
+func (xw *xlsxWriter) write(s string) {
+	if xw.err == nil {
+		_, xw.err = io.WriteString(xw.sheet, s)
+	}
+}
+
+func (xw *xlsxWriter) close() error {
+	xw.write(`</sheetData></worksheet>`)
+	if xw.err != nil {
+		return xw.err
+	}
+	return xw.zw.Close()
+}
+
+// xlsxStyleAttr 返回单元格的样式属性，默认样式不需要写出
+func xlsxStyleAttr(style int) string {
+	if style == 0 {
+		return ""
+	}
+	return ` s="` + strconv.Itoa(style) + `"`
+}
+
+// xlsxColumn 把从0开始的列序号转换为列字母，例如 0 为 A，26 为 AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xmlEscape 转义XML文本，XML不允许的控制字符替换为U+FFFD
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -1,34 +1,119 @@
 package utils
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
 var Logger *logrus.Logger
 
+// logFieldsKey context中保存日志字段的键
+type logFieldsKey struct{}
+
 // InitLogger 初始化日志
-func InitLogger() {
-	Logger = logrus.New()
+// level 为logrus的日志级别，format 为 text 或 json，output 为 stdout、stderr 或文件路径
+func InitLogger(level, format, output string) error {
+	if Logger == nil {
+		Logger = logrus.New()
+	}
 
 	// 设置日志格式
-	Logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-		TimestampFormat: "2006-01-02 15:04:05",
-	})
+	switch strings.ToLower(format) {
+	case "", "text":
+		Logger.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02 15:04:05",
+		})
+	case "json":
+		Logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+		})
+	default:
+		return fmt.Errorf("不支持的日志格式: %s", format)
+	}
 
 	// 设置日志级别
-	Logger.SetLevel(logrus.InfoLevel)
+	if level == "" {
+		level = "info"
+	}
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("无效的日志级别: %s", level)
+	}
+	Logger.SetLevel(lvl)
 
 	// 设置输出位置
-	Logger.SetOutput(os.Stdout)
+	var out io.Writer
+	switch output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("无法打开日志文件 %s: %v", output, err)
+		}
+		out = f
+	}
+	Logger.SetOutput(out)
+
+	return nil
+}
+
+// SetLogLevel 调整日志级别，用于配置热加载
+func SetLogLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("无效的日志级别: %s", level)
+	}
+	GetLogger().SetLevel(lvl)
+	return nil
 }
 
 // GetLogger 获取日志实例
 func GetLogger() *logrus.Logger {
 	if Logger == nil {
-		InitLogger()
+		InitLogger("info", "text", "stdout")
 	}
 	return Logger
-}
\ No newline at end of file
+}
+
+// ContextWithLogFields 将日志字段附加到context，之后通过 LoggerFromContext 输出的日志都会带上这些字段
+func ContextWithLogFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := logrus.Fields{}
+	if existing, ok := ctx.Value(logFieldsKey{}).(logrus.Fields); ok {
+		for k, v := range existing {
+			merged[k] = v
+		}
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, logFieldsKey{}, merged)
+}
+
+// LoggerFromContext 获取带有context中日志字段（如request_id、run_id、message_id）的日志实例
+func LoggerFromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(GetLogger())
+	if fields, ok := ctx.Value(logFieldsKey{}).(logrus.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+	return entry
+}
+
+// NewID 生成16位十六进制的随机ID，用于请求ID和运行ID
+func NewID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "0000000000000000"
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,892 @@
+// 邮件转发系统管理界面：单页应用，所有数据来自 /api/v1 接口
+(function () {
+  'use strict';
+
+  // 管理界面挂在 /ui/ 下，接口与其同源，反向代理加了路径前缀时一并保留
+  var base = location.pathname.replace(/\/ui(\/.*)?$/, '');
+  var api = base + '/api/v1';
+
+  var STATUSES = ['pending', 'success', 'failed', 'retrying'];
+  var TARGET_TYPES = ['email', 'dingtalk', 'wecom', 'feishu', 'slack', 'webhook'];
+
+  var settings = {
+    get: function (key) { return localStorage.getItem('ef.' + key) || ''; },
+    set: function (key, value) {
+      if (value) {
+        localStorage.setItem('ef.' + key, value);
+      } else {
+        localStorage.removeItem('ef.' + key);
+      }
+    }
+  };
+
+  var lang = settings.get('lang') || (/^zh/i.test(navigator.language) ? 'zh' : 'en');
+
+  function t(key, vars) {
+    var text = (I18N[lang] && I18N[lang][key]) || I18N.zh[key] || key;
+    if (vars) {
+      text = text.replace(/\{(\w+)\}/g, function (_, name) { return vars[name] === undefined ? '' : vars[name]; });
+    }
+    return text;
+  }
+
+  // h 创建DOM元素，文本一律作为文本节点插入，不拼接HTML
+  function h(tag, attrs) {
+    var el = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (name) {
+      var value = attrs[name];
+      if (value === undefined || value === null || value === false) {
+        return;
+      }
+      if (name.indexOf('on') === 0) {
+        el.addEventListener(name.slice(2), value);
+      } else if (name === 'value') {
+        el.value = value;
+      } else if (name === 'checked') {
+        el.checked = true;
+      } else {
+        el.setAttribute(name, value === true ? '' : value);
+      }
+    });
+    for (var i = 2; i < arguments.length; i++) {
+      append(el, arguments[i]);
+    }
+    return el;
+  }
+
+  function append(el, child) {
+    if (child === undefined || child === null || child === false) {
+      return;
+    }
+    if (Array.isArray(child)) {
+      child.forEach(function (c) { append(el, c); });
+      return;
+    }
+    el.appendChild(child instanceof Node ? child : document.createTextNode(String(child)));
+  }
+
+  function render(node) {
+    var app = document.getElementById('app');
+    app.textContent = '';
+    append(app, node);
+  }
+
+  function notify(message, ok) {
+    var el = document.getElementById('notice');
+    el.textContent = message;
+    el.className = ok ? 'notice ok' : 'notice';
+    el.hidden = false;
+    clearTimeout(notify.timer);
+    notify.timer = setTimeout(function () { el.hidden = true; }, ok ? 3000 : 8000);
+  }
+
+  function headers(extra) {
+    // 接口错误说明按界面语言返回
+    var result = Object.assign({ 'Accept-Language': lang }, extra);
+    if (settings.get('apiKey')) {
+      result['X-API-Key'] = settings.get('apiKey');
+    }
+    if (settings.get('tenant')) {
+      result['X-Tenant-ID'] = settings.get('tenant');
+    }
+    return result;
+  }
+
+  // request 调用接口，失败时抛出带接口错误信息的异常
+  function request(method, path, body) {
+    var options = { method: method, headers: headers() };
+    if (body !== undefined) {
+      options.headers['Content-Type'] = 'application/json';
+      options.body = JSON.stringify(body);
+    }
+    return fetch(api + path, options).then(function (resp) {
+      return resp.text().then(function (text) {
+        var data = {};
+        try { data = text ? JSON.parse(text) : {}; } catch (e) { data = { error: text }; }
+        if (!resp.ok) {
+          if (resp.status === 401) {
+            location.hash = '#/settings';
+          }
+          var message = [data.error, data.message].filter(Boolean).join(': ') || resp.statusText;
+          throw new Error('HTTP ' + resp.status + ' ' + message);
+        }
+        return data;
+      });
+    });
+  }
+
+  // download 下载接口返回的文件，文件名取自Content-Disposition
+  function download(path) {
+    return fetch(api + path, { headers: headers() }).then(function (resp) {
+      if (!resp.ok) {
+        return resp.json().catch(function () { return {}; }).then(function (data) {
+          var message = [data.error, data.message].filter(Boolean).join(': ') || resp.statusText;
+          throw new Error('HTTP ' + resp.status + ' ' + message);
+        });
+      }
+      var match = /filename="?([^";]+)"?/.exec(resp.headers.get('Content-Disposition') || '');
+      return resp.blob().then(function (blob) {
+        var link = h('a', { href: URL.createObjectURL(blob), download: match ? match[1] : 'download' });
+        document.body.appendChild(link);
+        link.click();
+        link.remove();
+        setTimeout(function () { URL.revokeObjectURL(link.href); }, 1000);
+      });
+    });
+  }
+
+  function fail(err) {
+    notify(err.message || String(err));
+  }
+
+  function formatTime(value) {
+    if (!value) {
+      return '-';
+    }
+    var d = new Date(value);
+    return isNaN(d) ? value : d.toLocaleString(lang === 'zh' ? 'zh-CN' : 'en-US', { hour12: false });
+  }
+
+  function badge(status) {
+    return h('span', { class: 'badge ' + status }, t('status.' + status));
+  }
+
+  function query(params) {
+    var parts = [];
+    Object.keys(params).forEach(function (key) {
+      if (params[key] !== '' && params[key] !== undefined && params[key] !== null) {
+        parts.push(encodeURIComponent(key) + '=' + encodeURIComponent(params[key]));
+      }
+    });
+    return parts.length ? '?' + parts.join('&') : '';
+  }
+
+  // 邮箱下拉框的选项，默认邮箱的ID为0
+  var mailboxCache = null;
+  function loadMailboxes() {
+    if (mailboxCache) {
+      return Promise.resolve(mailboxCache);
+    }
+    return request('GET', '/mailboxes').then(function (resp) {
+      mailboxCache = [{ id: 0, address: t('mailbox.default') }].concat(resp.data || []);
+      return mailboxCache;
+    }).catch(function () {
+      return [{ id: 0, address: t('mailbox.default') }];
+    });
+  }
+
+  function mailboxSelect(mailboxes, selected, allowAll) {
+    return h('select', { name: 'mailbox_id' },
+      allowAll ? h('option', { value: '' }, t('mailbox.all')) : null,
+      mailboxes.map(function (mb) {
+        return h('option', { value: String(mb.id), selected: String(mb.id) === String(selected) }, mb.name ? mb.name + ' <' + mb.address + '>' : mb.address);
+      }));
+  }
+
+  // ---------- 实时事件流 ----------
+
+  // 浏览器的 EventSource 不能设置请求头，用 fetch 读取SSE，连接断开后按服务端的 retry 重连
+  function streamEvents(params, onEvent) {
+    var controller = new AbortController();
+    var lastID = '';
+    var retry = 3000;
+    var stopped = false;
+
+    function connect() {
+      if (stopped) {
+        return;
+      }
+      var extra = lastID ? { 'Last-Event-ID': lastID } : {};
+      fetch(api + '/events' + query(params), { headers: headers(extra), signal: controller.signal }).then(function (resp) {
+        if (!resp.ok) {
+          throw new Error('HTTP ' + resp.status);
+        }
+        var reader = resp.body.getReader();
+        var decoder = new TextDecoder();
+        var buffer = '';
+        function read() {
+          return reader.read().then(function (chunk) {
+            if (chunk.done) {
+              return;
+            }
+            buffer += decoder.decode(chunk.value, { stream: true });
+            var blocks = buffer.split('\n\n');
+            buffer = blocks.pop();
+            blocks.forEach(function (block) {
+              var message = { event: 'message', data: '' };
+              block.split('\n').forEach(function (line) {
+                var i = line.indexOf(':');
+                if (i <= 0) {
+                  return;
+                }
+                var field = line.slice(0, i);
+                var value = line.slice(i + 1).replace(/^ /, '');
+                if (field === 'data') {
+                  message.data += value;
+                } else if (field === 'id') {
+                  lastID = value;
+                } else if (field === 'retry') {
+                  retry = parseInt(value, 10) || retry;
+                } else {
+                  message[field] = value;
+                }
+              });
+              if (message.data) {
+                onEvent(message.event, JSON.parse(message.data));
+              }
+            });
+            return read();
+          });
+        }
+        return read();
+      }).catch(function () {}).then(function () {
+        if (!stopped) {
+          setTimeout(connect, retry);
+        }
+      });
+    }
+
+    connect();
+    return function () {
+      stopped = true;
+      controller.abort();
+    };
+  }
+
+  // 切换页面时关闭上一个页面的事件流
+  var cleanup = null;
+
+  // ---------- 邮件日志 ----------
+
+  function logsPage(params) {
+    var state = {
+      page: parseInt(params.page, 10) || 1,
+      status: params.status || '',
+      mailbox_id: params.mailbox_id || '',
+      search: params.search || '',
+      live: params.live === '1'
+    };
+
+    function go(changes) {
+      var next = Object.assign({}, state, changes);
+      location.hash = '#/logs' + query({
+        page: next.page > 1 ? next.page : '',
+        status: next.status,
+        mailbox_id: next.mailbox_id,
+        search: next.search,
+        live: next.live ? '1' : ''
+      });
+    }
+
+    var tbody = h('tbody');
+    var pager = h('div', { class: 'pager' });
+    var rows = {};
+
+    function row(log, flash) {
+      var tr = h('tr', { class: 'clickable' + (flash ? ' flash' : ''), onclick: function () { location.hash = '#/logs/' + log.id; } },
+        h('td', { class: 'nowrap' }, formatTime(log.created_at)),
+        h('td', null, log.subject),
+        h('td', null, log.from_email),
+        h('td', null, log.forward_target || '-', log.target_type ? h('div', { class: 'muted' }, log.target_type) : null),
+        h('td', null, badge(log.forward_status)),
+        h('td', null, String(log.attempts || 0)),
+        h('td', { class: 'muted' }, log.error_message || ''));
+      rows[log.id] = tr;
+      return tr;
+    }
+
+    function matches(log) {
+      if (state.status && log.forward_status !== state.status) {
+        return false;
+      }
+      if (state.mailbox_id !== '' && String(log.mailbox_id) !== state.mailbox_id) {
+        return false;
+      }
+      var search = state.search.toLowerCase();
+      return !search || [log.subject, log.from_email, log.forward_target].some(function (v) {
+        return (v || '').toLowerCase().indexOf(search) >= 0;
+      });
+    }
+
+    var searchInput = h('input', { type: 'search', value: state.search, placeholder: t('logs.search') });
+    var statusSelect = h('select', { onchange: function () { go({ status: this.value, page: 1 }); } },
+      h('option', { value: '' }, t('status.all')),
+      STATUSES.map(function (s) { return h('option', { value: s, selected: s === state.status }, t('status.' + s)); }));
+    var mailboxHolder = h('span');
+
+    render([
+      h('h2', null, t('nav.logs')),
+      h('div', { class: 'toolbar' },
+        h('form', { onsubmit: function (e) { e.preventDefault(); go({ search: searchInput.value.trim(), page: 1 }); } }, searchInput),
+        statusSelect,
+        mailboxHolder,
+        h('span', { class: 'spacer' }),
+        ['csv', 'xlsx'].map(function (format) {
+          return h('button', { type: 'button', onclick: function () { exportLogs(format); } }, t('logs.export.' + format));
+        }),
+        h('label', null, h('input', { type: 'checkbox', checked: state.live, onchange: function () { go({ live: this.checked }); } }), ' ', t('logs.live'))),
+      h('table', null,
+        h('thead', null, h('tr', null,
+          h('th', null, t('logs.time')), h('th', null, t('logs.subject')), h('th', null, t('logs.from')),
+          h('th', null, t('logs.target')), h('th', null, t('logs.status')), h('th', null, t('logs.attempts')),
+          h('th', null, t('logs.error')))),
+        tbody),
+      pager
+    ]);
+
+    // exportLogs 按当前筛选条件导出日志
+    function exportLogs(format) {
+      download('/emails/logs/export' + query({ format: format, status: state.status, mailbox_id: state.mailbox_id, search: state.search }))
+        .catch(fail);
+    }
+
+    loadMailboxes().then(function (mailboxes) {
+      var select = mailboxSelect(mailboxes, state.mailbox_id, true);
+      select.addEventListener('change', function () { go({ mailbox_id: this.value, page: 1 }); });
+      append(mailboxHolder, select);
+    });
+
+    request('GET', '/emails/logs' + query({ page: state.page, page_size: 20, status: state.status, mailbox_id: state.mailbox_id, search: state.search }))
+      .then(function (resp) {
+        var logs = resp.data || [];
+        if (!logs.length) {
+          append(tbody, h('tr', null, h('td', { colspan: 7, class: 'muted' }, t('common.empty'))));
+        }
+        logs.forEach(function (log) { append(tbody, row(log)); });
+
+        var p = resp.pagination || {};
+        append(pager, [
+          h('button', { disabled: state.page <= 1, onclick: function () { go({ page: state.page - 1 }); } }, t('common.prev')),
+          h('span', null, t('common.page', { page: p.page, total: p.total_page || 1, count: p.total })),
+          h('button', { disabled: state.page >= (p.total_page || 1), onclick: function () { go({ page: state.page + 1 }); } }, t('common.next'))
+        ]);
+      })
+      .catch(fail);
+
+    if (state.live) {
+      var stream = { type: 'log.created,log.updated' };
+      if (state.status) {
+        stream.status = state.status;
+      }
+      cleanup = streamEvents(stream, function (type, e) {
+        if (type === 'dropped') {
+          notify(t('logs.dropped', { count: e.dropped }));
+          return;
+        }
+        var log = e.data && e.data.log;
+        if (!log || !matches(log)) {
+          return;
+        }
+        var existing = rows[log.id];
+        var tr = row(log, true);
+        if (existing) {
+          tbody.replaceChild(tr, existing);
+        } else if (state.page === 1) {
+          tbody.insertBefore(tr, tbody.firstChild);
+        }
+      });
+    }
+  }
+
+  function logDetailPage(id) {
+    render(h('p', { class: 'muted' }, t('common.loading')));
+
+    request('GET', '/emails/logs/' + encodeURIComponent(id)).then(function (resp) {
+      var log = resp.data;
+      var meta = [
+        ['logs.subject', log.subject],
+        ['logs.from', log.from_email],
+        ['logs.to', log.to_email],
+        ['logs.mailbox', log.mailbox],
+        ['logs.keyword', log.keyword || '-'],
+        ['logs.target', log.forward_target ? log.forward_target + (log.target_type ? ' (' + log.target_type + ')' : '') : '-'],
+        ['logs.forwardEmail', log.forward_email || '-'],
+        ['logs.status', badge(log.forward_status)],
+        ['logs.attempts', String(log.attempts || 0)],
+        ['logs.nextRetry', formatTime(log.next_retry_at)],
+        ['logs.error', log.error_message || '-'],
+        ['logs.time', formatTime(log.created_at)],
+        ['logs.processed', formatTime(log.processed_at)],
+        ['logs.messageID', log.gmail_message_id],
+        ['logs.traceID', log.trace_id || '-']
+      ];
+
+      render([
+        h('div', { class: 'toolbar' },
+          h('button', { onclick: function () { history.back(); } }, '← ' + t('common.back')),
+          h('h2', { style: 'margin:0' }, t('logs.detail') + ' #' + log.id)),
+        h('div', { class: 'panel' },
+          h('dl', { class: 'meta' }, meta.map(function (m) { return [h('dt', null, t(m[0])), h('dd', null, m[1])]; }))),
+        h('div', { class: 'columns' },
+          h('div', { class: 'panel' }, h('h3', { style: 'margin-top:0' }, t('logs.original')), htmlPreview(log.content)),
+          h('div', { class: 'panel' }, h('h3', { style: 'margin-top:0' }, t('logs.forwarded')), forwardedPreview(log.forwarded)))
+      ]);
+    }).catch(fail);
+  }
+
+  // htmlPreview 在禁用脚本的iframe中渲染邮件HTML
+  function htmlPreview(content) {
+    if (!content) {
+      return h('p', { class: 'muted' }, t('common.empty'));
+    }
+    return h('iframe', { class: 'preview', sandbox: '', referrerpolicy: 'no-referrer', srcdoc: content });
+  }
+
+  function forwardedPreview(preview) {
+    if (!preview) {
+      return h('p', { class: 'muted' }, t('logs.noForward'));
+    }
+    return [
+      h('p', null, h('span', { class: 'badge' }, preview.type), ' ', preview.subject || ''),
+      preview.format === 'html' ? htmlPreview(preview.body) : h('pre', { class: 'preview' }, preview.body)
+    ];
+  }
+
+  // ---------- 转发目标 ----------
+
+  function targetsPage(params) {
+    var search = params.search || '';
+    var tbody = h('tbody');
+    var searchInput = h('input', { type: 'search', value: search, placeholder: t('targets.search') });
+
+    render([
+      h('h2', null, t('nav.targets')),
+      h('div', { class: 'toolbar' },
+        h('form', { onsubmit: function (e) { e.preventDefault(); location.hash = '#/targets' + query({ search: searchInput.value.trim() }); } }, searchInput),
+        h('span', { class: 'spacer' }),
+        h('button', { class: 'primary', onclick: function () { location.hash = '#/targets/new'; } }, t('targets.create'))),
+      h('table', null,
+        h('thead', null, h('tr', null,
+          h('th', null, 'ID'), h('th', null, t('targets.name')), h('th', null, t('targets.type')),
+          h('th', null, t('targets.destination')), h('th', null, t('targets.keywords')), h('th', null, t('targets.mailbox')),
+          h('th', null, t('targets.active')), h('th', null, ''))),
+        tbody)
+    ]);
+
+    function action(method, path, message) {
+      return function (e) {
+        e.stopPropagation();
+        if (method === 'DELETE' && !confirm(t('targets.confirmDelete'))) {
+          return;
+        }
+        request(method, path).then(function () {
+          notify(message, true);
+          targetsPage(params);
+        }).catch(fail);
+      };
+    }
+
+    request('GET', '/targets' + query({ include_inactive: 'true', search: search })).then(function (resp) {
+      var targets = resp.data || [];
+      if (!targets.length) {
+        append(tbody, h('tr', null, h('td', { colspan: 8, class: 'muted' }, t('common.empty'))));
+      }
+      targets.forEach(function (target) {
+        append(tbody, h('tr', null,
+          h('td', null, String(target.id)),
+          h('td', null, target.name),
+          h('td', null, target.type),
+          h('td', null, target.type === 'email' ? target.email : (target.webhook_url || '')),
+          h('td', null, target.keywords),
+          h('td', null, target.mailbox_id ? String(target.mailbox_id) : t('mailbox.any')),
+          h('td', null, target.is_active ? badge('success') : h('span', { class: 'badge' }, t('targets.inactive'))),
+          h('td', null, h('div', { class: 'actions' },
+            h('button', { onclick: function () { location.hash = '#/targets/' + target.id; } }, t('common.edit')),
+            target.is_active
+              ? h('button', { onclick: action('POST', '/targets/' + target.id + '/deactivate', t('targets.deactivated')) }, t('targets.deactivate'))
+              : h('button', { onclick: action('POST', '/targets/' + target.id + '/activate', t('targets.activated')) }, t('targets.activate')),
+            h('button', { class: 'danger', onclick: action('DELETE', '/targets/' + target.id, t('common.deleted')) }, t('common.delete'))))));
+      });
+    }).catch(fail);
+  }
+
+  function targetFormPage(id) {
+    var isNew = id === 'new';
+    var load = isNew
+      ? Promise.resolve({ data: { type: 'email', is_active: true, mailbox_id: 0, attachment_mode: 'url' } })
+      : request('GET', '/targets/' + encodeURIComponent(id));
+
+    render(h('p', { class: 'muted' }, t('common.loading')));
+
+    Promise.all([load, loadMailboxes()]).then(function (results) {
+      var target = results[0].data;
+      var mailboxes = results[1];
+
+      var fields = {
+        name: h('input', { name: 'name', value: target.name || '', required: true }),
+        type: h('select', { name: 'type' }, TARGET_TYPES.map(function (type) {
+          return h('option', { value: type, selected: type === target.type }, t('type.' + type));
+        })),
+        email: h('input', { name: 'email', type: 'email', value: target.email || '' }),
+        webhook_url: h('input', { name: 'webhook_url', type: 'url', value: target.webhook_url || '' }),
+        secret: h('input', { name: 'secret', type: 'password', autocomplete: 'new-password', placeholder: isNew ? '' : t('targets.secretKeep') }),
+        attachment_mode: h('select', { name: 'attachment_mode' },
+          ['url', 'base64'].map(function (mode) { return h('option', { value: mode, selected: mode === target.attachment_mode }, mode); })),
+        keywords: h('input', { name: 'keywords', value: target.keywords || '', placeholder: t('targets.keywordsHint') }),
+        mailbox_id: mailboxSelect([{ id: 0, address: t('mailbox.any') }].concat(mailboxes.slice(1)), target.mailbox_id, false),
+        is_active: h('input', { name: 'is_active', type: 'checkbox', checked: target.is_active })
+      };
+
+      var rows = {};
+      function field(name, label) {
+        rows[name] = [h('label', null, t(label)), fields[name]];
+        return rows[name];
+      }
+
+      // 不同投递方式需要的字段不同
+      function toggle() {
+        var type = fields.type.value;
+        var visible = {
+          email: type === 'email',
+          webhook_url: type !== 'email',
+          secret: type !== 'email',
+          attachment_mode: type === 'webhook'
+        };
+        Object.keys(visible).forEach(function (name) {
+          rows[name].forEach(function (el) { el.style.display = visible[name] ? '' : 'none'; });
+        });
+      }
+      fields.type.addEventListener('change', toggle);
+
+      function submit(e) {
+        e.preventDefault();
+        var body = {
+          name: fields.name.value.trim(),
+          type: fields.type.value,
+          email: fields.email.value.trim(),
+          webhook_url: fields.webhook_url.value.trim(),
+          attachment_mode: fields.attachment_mode.value,
+          keywords: fields.keywords.value.trim(),
+          mailbox_id: parseInt(fields.mailbox_id.value, 10) || 0,
+          is_active: fields.is_active.checked
+        };
+        // 编辑时密钥留空表示不修改
+        if (fields.secret.value || isNew) {
+          body.secret = fields.secret.value;
+        }
+        var req = isNew ? request('POST', '/targets', body) : request('PATCH', '/targets/' + target.id, body);
+        req.then(function () {
+          notify(t('common.saved'), true);
+          location.hash = '#/targets';
+        }).catch(fail);
+      }
+
+      render([
+        h('div', { class: 'toolbar' },
+          h('button', { onclick: function () { location.hash = '#/targets'; } }, '← ' + t('common.back')),
+          h('h2', { style: 'margin:0' }, isNew ? t('targets.create') : t('targets.edit') + ' #' + target.id)),
+        h('div', { class: 'panel' },
+          h('form', { class: 'grid', onsubmit: submit },
+            field('name', 'targets.name'),
+            field('type', 'targets.type'),
+            field('email', 'targets.email'),
+            field('webhook_url', 'targets.webhookURL'),
+            field('secret', 'targets.secret'),
+            field('attachment_mode', 'targets.attachmentMode'),
+            field('keywords', 'targets.keywords'),
+            field('mailbox_id', 'targets.mailbox'),
+            field('is_active', 'targets.active'),
+            h('div', { class: 'full actions' },
+              h('button', { class: 'primary', type: 'submit' }, t('common.save')))))
+      ]);
+      toggle();
+    }).catch(fail);
+  }
+
+  // ---------- 规则测试 ----------
+
+  function sandboxPage() {
+    var subject = h('input', { name: 'subject', style: 'width:100%', placeholder: t('sandbox.placeholder'), required: true });
+    var mailboxHolder = h('span');
+    var result = h('div');
+    var select = null;
+
+    function submit(e) {
+      e.preventDefault();
+      request('POST', '/rules/test', {
+        subject: subject.value,
+        mailbox_id: select ? parseInt(select.value, 10) || 0 : 0
+      }).then(function (resp) {
+        var r = resp.data;
+        result.textContent = '';
+        append(result, h('div', { class: 'panel' },
+          h('p', null, r.matched ? badge('success') : badge('failed'), ' ', r.matched ? t('sandbox.matched') : r.reason),
+          h('dl', { class: 'meta' },
+            h('dt', null, t('logs.keyword')), h('dd', null, r.keyword || '-'),
+            h('dt', null, t('sandbox.targetName')), h('dd', null, r.target_name || '-'),
+            r.target ? [
+              h('dt', null, t('logs.target')),
+              h('dd', null, h('a', { href: '#/targets/' + r.target.id }, '#' + r.target.id + ' ' + r.target.name), ' (' + r.target.type + ')'),
+              h('dt', null, t('targets.destination')),
+              h('dd', null, r.target.type === 'email' ? r.target.email : r.target.webhook_url),
+              h('dt', null, t('targets.keywords')),
+              h('dd', null, r.target.keywords)
+            ] : null)));
+      }).catch(fail);
+    }
+
+    render([
+      h('h2', null, t('nav.sandbox')),
+      h('p', { class: 'muted' }, t('sandbox.help')),
+      h('div', { class: 'panel' },
+        h('form', { class: 'grid', onsubmit: submit },
+          h('label', null, t('logs.subject')), subject,
+          h('label', null, t('logs.mailbox')), mailboxHolder,
+          h('div', { class: 'full' }, h('button', { class: 'primary', type: 'submit' }, t('sandbox.test'))))),
+      result
+    ]);
+
+    loadMailboxes().then(function (mailboxes) {
+      select = mailboxSelect(mailboxes, 0, false);
+      append(mailboxHolder, select);
+    });
+  }
+
+  // ---------- 统计 ----------
+
+  var CHART_COLORS = { success: '#0e9f6e', failed: '#e02424', retrying: '#ff8a4c' };
+
+  function statsPage(params) {
+    var days = params.days || '14';
+
+    render([
+      h('h2', null, t('nav.stats')),
+      h('div', { class: 'toolbar' },
+        h('select', { onchange: function () { location.hash = '#/stats?days=' + this.value; } },
+          ['7', '14', '30', '90'].map(function (d) { return h('option', { value: d, selected: d === days }, t('stats.days', { days: d })); }))),
+      h('p', { class: 'muted' }, t('common.loading'))
+    ]);
+
+    request('GET', '/stats?days=' + encodeURIComponent(days)).then(function (resp) {
+      var s = resp.data;
+      var by = s.by_status || {};
+      var cards = [
+        ['stats.total', s.total],
+        ['status.success', by.success || 0],
+        ['status.failed', by.failed || 0],
+        ['status.retrying', by.retrying || 0],
+        ['stats.today', s.forwarded_today]
+      ];
+      var max = (s.top_targets || []).reduce(function (m, x) { return Math.max(m, x.count); }, 0);
+
+      render([
+        h('h2', null, t('nav.stats')),
+        h('div', { class: 'toolbar' },
+          h('select', { onchange: function () { location.hash = '#/stats?days=' + this.value; } },
+            ['7', '14', '30', '90'].map(function (d) { return h('option', { value: d, selected: d === days }, t('stats.days', { days: d })); }))),
+        h('div', { class: 'cards' }, cards.map(function (c) {
+          return h('div', { class: 'card' }, h('div', { class: 'muted' }, t(c[0])), h('div', { class: 'value' }, String(c[1])));
+        })),
+        h('h3', null, t('stats.daily')),
+        h('div', { class: 'panel chart' }, dailyChart(s.daily || []),
+          h('div', { class: 'legend' }, ['success', 'failed', 'retrying'].map(function (k) {
+            return h('span', { style: '--color:' + CHART_COLORS[k] }, t('status.' + k));
+          }))),
+        h('h3', null, t('stats.topTargets')),
+        h('div', { class: 'panel' }, (s.top_targets || []).length ? s.top_targets.map(function (x) {
+          return h('div', { class: 'bar-row' },
+            h('span', null, x.target || '-'),
+            h('div', null, h('div', { class: 'bar', style: 'width:' + (max ? x.count / max * 100 : 0) + '%' })),
+            h('span', null, String(x.count)));
+        }) : h('p', { class: 'muted' }, t('common.empty')))
+      ]);
+    }).catch(fail);
+  }
+
+  // dailyChart 每天一根堆叠柱：成功、失败、等待重试
+  function dailyChart(daily) {
+    var ns = 'http://www.w3.org/2000/svg';
+    var width = 900;
+    var height = 240;
+    var pad = { left: 40, right: 10, top: 10, bottom: 30 };
+    var max = daily.reduce(function (m, d) { return Math.max(m, d.success + d.failed + d.retrying); }, 0) || 1;
+    var slot = (width - pad.left - pad.right) / Math.max(daily.length, 1);
+    var scale = (height - pad.top - pad.bottom) / max;
+
+    function el(tag, attrs, text) {
+      var node = document.createElementNS(ns, tag);
+      Object.keys(attrs).forEach(function (k) { node.setAttribute(k, attrs[k]); });
+      if (text !== undefined) {
+        node.textContent = text;
+      }
+      return node;
+    }
+
+    var svg = el('svg', { viewBox: '0 0 ' + width + ' ' + height, role: 'img' });
+    [0, 0.5, 1].forEach(function (f) {
+      var y = height - pad.bottom - f * max * scale;
+      svg.appendChild(el('line', { x1: pad.left, x2: width - pad.right, y1: y, y2: y, stroke: '#e4e7eb' }));
+      svg.appendChild(el('text', { x: pad.left - 6, y: y + 4, 'text-anchor': 'end', 'font-size': 11, fill: '#7b8794' }, String(Math.round(f * max))));
+    });
+
+    var labelEvery = Math.ceil(daily.length / 15);
+    daily.forEach(function (d, i) {
+      var x = pad.left + i * slot + slot * 0.15;
+      var y = height - pad.bottom;
+      ['success', 'failed', 'retrying'].forEach(function (k) {
+        var hgt = d[k] * scale;
+        if (hgt > 0) {
+          y -= hgt;
+          var rect = el('rect', { x: x, y: y, width: slot * 0.7, height: hgt, fill: CHART_COLORS[k] });
+          rect.appendChild(el('title', {}, d.date + ' ' + t('status.' + k) + ': ' + d[k]));
+          svg.appendChild(rect);
+        }
+      });
+      if (i % labelEvery === 0) {
+        svg.appendChild(el('text', { x: x + slot * 0.35, y: height - pad.bottom + 16, 'text-anchor': 'middle', 'font-size': 11, fill: '#7b8794' }, d.date.slice(5)));
+      }
+    });
+    return svg;
+  }
+
+  // ---------- 定时任务 ----------
+
+  function schedulersPage() {
+    var tbody = h('tbody');
+
+    render([
+      h('h2', null, t('nav.schedulers')),
+      h('p', { class: 'muted' }, t('schedulers.help')),
+      h('table', null,
+        h('thead', null, h('tr', null,
+          h('th', null, t('logs.mailbox')), h('th', null, t('schedulers.state')), h('th', null, t('schedulers.interval')),
+          h('th', null, t('schedulers.lastSuccess')), h('th', null, t('schedulers.lastError')), h('th', null, ''))),
+        tbody)
+    ]);
+
+    function run(path, message, button) {
+      button.disabled = true;
+      request('POST', path).then(function (resp) {
+        notify(resp.message || message, true);
+        schedulersPage();
+      }).catch(function (err) {
+        button.disabled = false;
+        fail(err);
+      });
+    }
+
+    request('GET', '/schedulers').then(function (resp) {
+      var list = resp.data || [];
+      if (!list.length) {
+        append(tbody, h('tr', null, h('td', { colspan: 6, class: 'muted' }, t('common.empty'))));
+      }
+      list.forEach(function (s) {
+        var state = !s.running ? h('span', { class: 'badge failed' }, t('schedulers.stopped'))
+          : s.paused ? h('span', { class: 'badge paused' }, t('schedulers.paused'))
+            : s.stale ? h('span', { class: 'badge retrying' }, t('schedulers.stale'))
+              : s.waiting_auth ? h('span', { class: 'badge paused' }, t('schedulers.waitingAuth'))
+                : h('span', { class: 'badge success' }, t('schedulers.running'));
+        var processPath = s.mailbox_id ? '/mailboxes/' + s.mailbox_id + '/process' : '/emails/process';
+        var toggle = h('button', null, s.paused ? t('schedulers.resume') : t('schedulers.pause'));
+        toggle.addEventListener('click', function () {
+          run('/schedulers/' + s.mailbox_id + (s.paused ? '/resume' : '/pause'), '', toggle);
+        });
+        var runNow = h('button', { class: 'primary' }, t('schedulers.run'));
+        runNow.addEventListener('click', function () { run(processPath, t('schedulers.done'), runNow); });
+
+        append(tbody, h('tr', null,
+          h('td', null, s.mailbox, s.mailbox_id ? null : h('div', { class: 'muted' }, t('mailbox.default'))),
+          h('td', null, state),
+          h('td', null, s.interval),
+          h('td', null, formatTime(s.last_success)),
+          h('td', { class: 'muted' }, s.last_error || ''),
+          h('td', null, h('div', { class: 'actions' }, toggle, runNow))));
+      });
+    }).catch(fail);
+  }
+
+  // ---------- 设置 ----------
+
+  function settingsPage() {
+    var apiKey = h('input', { type: 'password', value: settings.get('apiKey'), autocomplete: 'off', style: 'width:100%' });
+    var tenant = h('input', { type: 'number', min: 1, value: settings.get('tenant') });
+
+    render([
+      h('h2', null, t('nav.settings')),
+      h('div', { class: 'panel' },
+        h('form', {
+          class: 'grid', onsubmit: function (e) {
+            e.preventDefault();
+            settings.set('apiKey', apiKey.value.trim());
+            settings.set('tenant', tenant.value.trim());
+            mailboxCache = null;
+            notify(t('common.saved'), true);
+          }
+        },
+        h('label', null, t('settings.apiKey')), apiKey,
+        h('label', null, t('settings.tenant')), tenant,
+        h('p', { class: 'full muted' }, t('settings.help')),
+        h('div', { class: 'full' }, h('button', { class: 'primary', type: 'submit' }, t('common.save')))))
+    ]);
+  }
+
+  // ---------- 路由 ----------
+
+  function parseHash() {
+    var hash = location.hash.replace(/^#\/?/, '');
+    var i = hash.indexOf('?');
+    var path = (i >= 0 ? hash.slice(0, i) : hash).split('/').filter(Boolean);
+    var params = {};
+    if (i >= 0) {
+      hash.slice(i + 1).split('&').forEach(function (pair) {
+        var kv = pair.split('=');
+        if (kv[0]) {
+          params[decodeURIComponent(kv[0])] = decodeURIComponent(kv[1] || '');
+        }
+      });
+    }
+    return { path: path, params: params };
+  }
+
+  function route() {
+    if (cleanup) {
+      cleanup();
+      cleanup = null;
+    }
+
+    var r = parseHash();
+    var section = r.path[0] || 'logs';
+    document.querySelectorAll('#nav a').forEach(function (a) {
+      a.classList.toggle('active', a.getAttribute('href') === '#/' + section);
+    });
+
+    switch (section) {
+      case 'logs':
+        return r.path[1] ? logDetailPage(r.path[1]) : logsPage(r.params);
+      case 'targets':
+        return r.path[1] ? targetFormPage(r.path[1]) : targetsPage(r.params);
+      case 'sandbox':
+        return sandboxPage();
+      case 'stats':
+        return statsPage(r.params);
+      case 'schedulers':
+        return schedulersPage();
+      case 'settings':
+        return settingsPage();
+      default:
+        location.hash = '#/logs';
+    }
+  }
+
+  function applyLanguage() {
+    document.documentElement.lang = lang === 'zh' ? 'zh-CN' : 'en';
+    document.title = t('app.title');
+    document.querySelectorAll('[data-i18n]').forEach(function (el) {
+      el.textContent = t(el.getAttribute('data-i18n'));
+    });
+  }
+
+  var langSelect = document.getElementById('lang');
+  langSelect.value = lang;
+  langSelect.addEventListener('change', function () {
+    lang = this.value;
+    settings.set('lang', lang);
+    mailboxCache = null;
+    applyLanguage();
+    route();
+  });
+
+  window.addEventListener('hashchange', route);
+  applyLanguage();
+  route();
+})();
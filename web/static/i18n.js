@@ -0,0 +1,238 @@
+// 管理界面的中英文文案，缺少的英文文案回退到中文
+var I18N = {
+  zh: {
+    'app.title': '邮件转发系统',
+    'nav.logs': '邮件日志',
+    'nav.targets': '转发目标',
+    'nav.sandbox': '规则测试',
+    'nav.stats': '统计',
+    'nav.schedulers': '定时任务',
+    'nav.settings': '设置',
+    'nav.docs': 'API文档',
+
+    'common.loading': '加载中…',
+    'common.empty': '暂无数据',
+    'common.prev': '上一页',
+    'common.next': '下一页',
+    'common.page': '第 {page} / {total} 页，共 {count} 条',
+    'common.back': '返回',
+    'common.edit': '编辑',
+    'common.delete': '删除',
+    'common.deleted': '已删除',
+    'common.save': '保存',
+    'common.saved': '已保存',
+
+    'status.all': '全部状态',
+    'status.pending': '待处理',
+    'status.success': '成功',
+    'status.failed': '失败',
+    'status.retrying': '等待重试',
+
+    'mailbox.default': '默认邮箱',
+    'mailbox.all': '全部邮箱',
+    'mailbox.any': '所有邮箱',
+
+    'type.email': '邮箱',
+    'type.dingtalk': '钉钉',
+    'type.wecom': '企业微信',
+    'type.feishu': '飞书',
+    'type.slack': 'Slack',
+    'type.webhook': '通用webhook',
+
+    'logs.search': '搜索主题、发件人、目标',
+    'logs.live': '实时更新',
+    'logs.export.csv': '导出CSV',
+    'logs.export.xlsx': '导出Excel',
+    'logs.dropped': '实时更新丢失了 {count} 条事件，请刷新页面',
+    'logs.time': '时间',
+    'logs.subject': '主题',
+    'logs.from': '发件人',
+    'logs.to': '收件人',
+    'logs.mailbox': '邮箱',
+    'logs.keyword': '关键字',
+    'logs.target': '转发目标',
+    'logs.forwardEmail': '目标邮箱',
+    'logs.status': '状态',
+    'logs.attempts': '尝试次数',
+    'logs.nextRetry': '下次重试',
+    'logs.error': '错误信息',
+    'logs.processed': '处理时间',
+    'logs.messageID': 'Gmail消息ID',
+    'logs.traceID': '链路追踪ID',
+    'logs.detail': '邮件详情',
+    'logs.original': '原邮件',
+    'logs.forwarded': '转发内容',
+    'logs.noForward': '没有转发内容（未匹配到转发目标，或通用webhook推送完整的原邮件）',
+
+    'targets.search': '搜索名称、邮箱、关键字',
+    'targets.create': '新建转发目标',
+    'targets.edit': '编辑转发目标',
+    'targets.name': '名称',
+    'targets.type': '投递方式',
+    'targets.destination': '投递地址',
+    'targets.email': '目标邮箱',
+    'targets.webhookURL': 'Webhook地址',
+    'targets.secret': '签名密钥',
+    'targets.secretKeep': '留空表示不修改',
+    'targets.attachmentMode': '附件传递方式',
+    'targets.keywords': '关键字',
+    'targets.keywordsHint': '多个关键字用逗号分隔',
+    'targets.mailbox': '适用邮箱',
+    'targets.active': '启用',
+    'targets.inactive': '已停用',
+    'targets.activate': '启用',
+    'targets.deactivate': '停用',
+    'targets.activated': '已启用',
+    'targets.deactivated': '已停用',
+    'targets.confirmDelete': '确定删除该转发目标吗？',
+
+    'sandbox.help': '输入邮件标题，按处理邮件时相同的规则解析关键字并查找转发目标，不会发送邮件也不会写入日志。',
+    'sandbox.placeholder': '例如：投诉 - 张三',
+    'sandbox.test': '测试',
+    'sandbox.matched': '匹配成功',
+    'sandbox.targetName': '目标名字',
+
+    'stats.days': '最近 {days} 天',
+    'stats.total': '处理邮件',
+    'stats.today': '今日已转发',
+    'stats.daily': '每日处理量',
+    'stats.topTargets': '转发最多的目标',
+
+    'schedulers.help': '暂停只在本次运行期间有效，服务重启或邮箱被修改后自动恢复；暂停期间仍可以立即检查。',
+    'schedulers.state': '状态',
+    'schedulers.interval': '检查间隔',
+    'schedulers.lastSuccess': '最后成功',
+    'schedulers.lastError': '最近错误',
+    'schedulers.running': '运行中',
+    'schedulers.paused': '已暂停',
+    'schedulers.stale': '停滞',
+    'schedulers.waitingAuth': '等待授权',
+    'schedulers.stopped': '已停止',
+    'schedulers.pause': '暂停',
+    'schedulers.resume': '恢复',
+    'schedulers.run': '立即检查',
+    'schedulers.done': '邮件处理完成',
+
+    'settings.apiKey': 'API密钥',
+    'settings.tenant': '租户ID',
+    'settings.help': 'API密钥和租户ID只保存在本浏览器中。未开启认证时可以留空；租户ID只对超级管理员有效，留空表示所有租户。'
+  },
+
+  en: {
+    'app.title': 'Email Forwarding',
+    'nav.logs': 'Logs',
+    'nav.targets': 'Targets',
+    'nav.sandbox': 'Rule sandbox',
+    'nav.stats': 'Stats',
+    'nav.schedulers': 'Schedulers',
+    'nav.settings': 'Settings',
+    'nav.docs': 'API docs',
+
+    'common.loading': 'Loading…',
+    'common.empty': 'No data',
+    'common.prev': 'Previous',
+    'common.next': 'Next',
+    'common.page': 'Page {page} of {total}, {count} total',
+    'common.back': 'Back',
+    'common.edit': 'Edit',
+    'common.delete': 'Delete',
+    'common.deleted': 'Deleted',
+    'common.save': 'Save',
+    'common.saved': 'Saved',
+
+    'status.all': 'All statuses',
+    'status.pending': 'Pending',
+    'status.success': 'Success',
+    'status.failed': 'Failed',
+    'status.retrying': 'Retrying',
+
+    'mailbox.default': 'Default mailbox',
+    'mailbox.all': 'All mailboxes',
+    'mailbox.any': 'Any mailbox',
+
+    'type.email': 'Email',
+    'type.dingtalk': 'DingTalk',
+    'type.wecom': 'WeCom',
+    'type.feishu': 'Feishu',
+    'type.slack': 'Slack',
+    'type.webhook': 'Webhook',
+
+    'logs.search': 'Search subject, sender, target',
+    'logs.live': 'Live updates',
+    'logs.export.csv': 'Export CSV',
+    'logs.export.xlsx': 'Export Excel',
+    'logs.dropped': 'Live updates missed {count} events, please reload',
+    'logs.time': 'Time',
+    'logs.subject': 'Subject',
+    'logs.from': 'From',
+    'logs.to': 'To',
+    'logs.mailbox': 'Mailbox',
+    'logs.keyword': 'Keyword',
+    'logs.target': 'Target',
+    'logs.forwardEmail': 'Target email',
+    'logs.status': 'Status',
+    'logs.attempts': 'Attempts',
+    'logs.nextRetry': 'Next retry',
+    'logs.error': 'Error',
+    'logs.processed': 'Processed at',
+    'logs.messageID': 'Gmail message ID',
+    'logs.traceID': 'Trace ID',
+    'logs.detail': 'Message',
+    'logs.original': 'Original message',
+    'logs.forwarded': 'Forwarded content',
+    'logs.noForward': 'Nothing was forwarded (no matching target, or a webhook target that receives the full original message)',
+
+    'targets.search': 'Search name, email, keywords',
+    'targets.create': 'New target',
+    'targets.edit': 'Edit target',
+    'targets.name': 'Name',
+    'targets.type': 'Delivery',
+    'targets.destination': 'Destination',
+    'targets.email': 'Email',
+    'targets.webhookURL': 'Webhook URL',
+    'targets.secret': 'Signing secret',
+    'targets.secretKeep': 'Leave empty to keep the current secret',
+    'targets.attachmentMode': 'Attachments',
+    'targets.keywords': 'Keywords',
+    'targets.keywordsHint': 'Comma-separated',
+    'targets.mailbox': 'Mailbox',
+    'targets.active': 'Active',
+    'targets.inactive': 'Inactive',
+    'targets.activate': 'Activate',
+    'targets.deactivate': 'Deactivate',
+    'targets.activated': 'Activated',
+    'targets.deactivated': 'Deactivated',
+    'targets.confirmDelete': 'Delete this target?',
+
+    'sandbox.help': 'Enter a subject to see which keyword and target it resolves to, using the same rules as message processing. Nothing is sent or logged.',
+    'sandbox.placeholder': 'e.g. Complaint - Alice',
+    'sandbox.test': 'Test',
+    'sandbox.matched': 'Matched',
+    'sandbox.targetName': 'Target name',
+
+    'stats.days': 'Last {days} days',
+    'stats.total': 'Processed',
+    'stats.today': 'Forwarded today',
+    'stats.daily': 'Daily volume',
+    'stats.topTargets': 'Top targets',
+
+    'schedulers.help': 'Pausing lasts until the service restarts or the mailbox is edited. A paused mailbox can still be checked manually.',
+    'schedulers.state': 'State',
+    'schedulers.interval': 'Interval',
+    'schedulers.lastSuccess': 'Last success',
+    'schedulers.lastError': 'Last error',
+    'schedulers.running': 'Running',
+    'schedulers.paused': 'Paused',
+    'schedulers.stale': 'Stale',
+    'schedulers.waitingAuth': 'Awaiting authorization',
+    'schedulers.stopped': 'Stopped',
+    'schedulers.pause': 'Pause',
+    'schedulers.resume': 'Resume',
+    'schedulers.run': 'Check now',
+    'schedulers.done': 'Mailbox checked',
+
+    'settings.apiKey': 'API key',
+    'settings.tenant': 'Tenant ID',
+    'settings.help': 'The API key and tenant ID are stored in this browser only. Leave the key empty when authentication is disabled; the tenant ID only applies to superadmins.'
+  }
+};
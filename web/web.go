@@ -0,0 +1,20 @@
+// Package web 内嵌的管理界面，由gin路由在 /ui/ 下提供
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed static
+var static embed.FS
+
+// FS 返回管理界面的静态文件，根目录为 static
+func FS() fs.FS {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		// static 目录在编译时已嵌入，不会出错
+		panic(err)
+	}
+	return sub
+}